@@ -0,0 +1,170 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/marcokz/movie-final/internal/buildinfo"
+	"github.com/marcokz/movie-final/internal/entity"
+	"github.com/marcokz/movie-final/internal/handler"
+	"github.com/marcokz/movie-final/internal/openapi"
+)
+
+// apiDocument описание всех маршрутов из run. При добавлении маршрута сюда
+// добавляется его операция, иначе сервис не стартует (см. Document.Check).
+func apiDocument() (*openapi.Document, error) {
+	admin := []string{entity.RoleAdmin}
+	users := []string{entity.RoleAdmin, entity.RoleModerator, entity.RoleUser}
+
+	message := openapi.Object("message")
+	imageBody := []string{"image/jpeg", "image/png", "image/gif", "multipart/form-data"}
+	lang := openapi.Param{Name: "lang", Description: "response language, overrides Accept-Language"}
+	limit := openapi.Param{Name: "limit", Type: "integer"}
+	offset := openapi.Param{Name: "offset", Type: "integer"}
+
+	// Вход без второго фактора ставит cookie auth_token и отвечает без тела или
+	// сообщением об отмене удаления аккаунта
+	login := openapi.Object("message", "mfatoken")
+	login.Description = "mfatoken is returned instead of a session when 2FA is enabled, pass it to POST /user/auth/2fa"
+
+	search := []openapi.Param{
+		{Name: "minage", Type: "integer"},
+		{Name: "maxage", Type: "integer"},
+		{Name: "sex", Description: "male, female or other"},
+		{Name: "country", Description: "ISO code or name"},
+		{Name: "city", Type: "integer", Description: "city id"},
+		{Name: "movieid", Type: "integer"},
+		{Name: "minrating", Type: "integer", Description: "1 to 10, requires movieid"},
+		{Name: "maxrating", Type: "integer", Description: "1 to 10, requires movieid"},
+		{Name: "minsimilarity", Type: "number", Description: "taste similarity from 0 to 1"},
+		{Name: "radius_km", Type: "number"},
+		{Name: "sort", Description: "id, name, age, rating, similarity or distance, prefix - for descending order"},
+		limit, offset,
+	}
+
+	ops := []openapi.Operation{
+		{Pattern: "POST /movies", Summary: "Create a movie", Roles: admin, Body: handler.MovieResponse{}, Status: http.StatusCreated},
+		{Pattern: "GET /movies", Summary: "List movies", Response: []handler.Movie{}},
+		{Pattern: "GET /movies/{id}", Summary: "Get a movie", Response: handler.Movie{}},
+		{Pattern: "PUT /movies/{id}", Summary: "Update a movie", Roles: admin, Body: handler.MovieResponse{}, Response: message},
+		{Pattern: "DELETE /movies/{id}", Summary: "Delete a movie", Roles: admin, Response: message},
+		{Pattern: "PUT /movies/{id}/poster", Summary: "Upload a movie poster", Roles: admin, Media: imageBody, Response: handler.ImageURLs{}},
+		{Pattern: "DELETE /movies/{id}/poster", Summary: "Delete a movie poster", Roles: admin, Response: message},
+
+		{Pattern: "GET /images/{name}", Summary: "Get an uploaded image or its thumbnail", Response: &openapi.Schema{Type: "string", Format: "binary"}, ResponseType: "image/*"},
+
+		{Pattern: "POST /user/create", Summary: "Register", Body: handler.RegisterRequest{}, Status: http.StatusCreated, Response: message},
+		{Pattern: "POST /user/auth", Summary: "Log in with email and password", Body: handler.LoginRequest{}, Response: login},
+		{Pattern: "POST /user/auth/2fa", Summary: "Finish login with a TOTP or recovery code", Body: handler.LoginTOTPRequest{}, Response: login},
+		{Pattern: "POST /user/logout", Summary: "Log out and revoke the session", Response: &openapi.Schema{Type: "string"}, ResponseType: "text/plain"},
+		{Pattern: "GET /user/age", Summary: "Users by age range", Roles: users, Body: handler.Age{}, Response: []handler.User{}},
+		{Pattern: "GET /user/country", Summary: "Users by country", Roles: users, Body: handler.GetByCountry{}, Response: []handler.User{}},
+		{Pattern: "GET /user/city", Summary: "Users by city", Roles: users, Body: handler.GetCity{}, Response: []handler.User{}},
+		{Pattern: "GET /user/sex", Summary: "Users by sex", Roles: users, Body: handler.UserBySex{}, Response: []handler.User{}},
+		{Pattern: "GET /users/search", Summary: "Search users", Roles: users, Params: search, Response: handler.UserSearchResponse{}},
+		{
+			Pattern: "GET /users/nearby", Summary: "Users near a city", Roles: users,
+			Description: "Sorted by distance unless sort is given.",
+			Params:      append([]openapi.Param{{Name: "near", Type: "integer", Description: "city id, defaults to the caller's city"}}, search...),
+			Response:    handler.UserSearchResponse{},
+		},
+		{
+			Pattern: "GET /users/{id}", Summary: "User profile", Roles: users,
+			Params:   []openapi.Param{{Name: "id", In: "path", Description: "user id or @handle; an old handle redirects with 302"}},
+			Response: handler.ProfileResponse{},
+		},
+		{Pattern: "GET /user/me", Summary: "Current user", Roles: users, Response: handler.MeResponse{}},
+		{Pattern: "PATCH /user/me", Summary: "Update the current user", Roles: users, Body: handler.UserPatchRequest{}, Response: message},
+		{Pattern: "PUT /user/me/handle", Summary: "Set the handle", Roles: users, Body: handler.HandleRequest{}, Response: openapi.Object("handle")},
+		{
+			Pattern: "DELETE /user/me", Summary: "Delete the account", Roles: users,
+			Description: "The account is removed after a grace period, logging in cancels the deletion.",
+			Body:        handler.DeleteAccountRequest{}, Status: http.StatusAccepted, Response: message,
+		},
+		{Pattern: "PUT /user/me/avatar", Summary: "Upload an avatar", Roles: users, Media: imageBody, Response: handler.ImageURLs{}},
+		{Pattern: "DELETE /user/me/avatar", Summary: "Delete the avatar", Roles: users, Response: message},
+		{Pattern: "GET /user/me/export", Summary: "Export personal data as a ZIP archive", Roles: users, Response: &openapi.Schema{Type: "string", Format: "binary"}, ResponseType: "application/zip"},
+		{Pattern: "GET /user/privacy", Summary: "Privacy settings", Roles: users, Response: handler.PrivacySettings{}},
+		{Pattern: "PUT /user/privacy", Summary: "Update privacy settings", Roles: users, Body: handler.PrivacySettings{}, Response: message},
+		{Pattern: "PUT /user/update", Summary: "Replace profile fields", Roles: users, Body: handler.UserUpdateRequest{}, Response: message},
+		{Pattern: "PUT /user/password", Summary: "Change the password", Roles: users, Body: handler.ChangePasswordRequest{}, Response: message},
+		{Pattern: "POST /user/password/reset", Summary: "Set a new password with a reset token", Body: handler.ResetPasswordRequest{}, Response: message},
+		{Pattern: "POST /user/password/reset/request", Summary: "Send a new reset token", Description: "Only for accounts that must change their password and whose previous token has expired. The response is the same for any email.", Body: handler.PasswordResetRequest{}, Response: message},
+		{Pattern: "PUT /user/email", Summary: "Request an email change", Roles: users, Body: handler.ChangeEmailRequest{}, Status: http.StatusAccepted, Response: message},
+		{Pattern: "POST /user/email/confirm", Summary: "Confirm an email change", Roles: users, Body: handler.ConfirmEmailRequest{}, Response: message},
+		{Pattern: "POST /user/2fa/enroll", Summary: "Start TOTP enrollment", Roles: users, Response: handler.EnrollTOTPResponse{}},
+		{
+			Pattern: "POST /user/2fa/verify", Summary: "Confirm TOTP enrollment", Roles: users, Body: handler.TOTPCodeRequest{},
+			Response: struct {
+				RecoveryCodes []string `json:"recoverycodes"`
+			}{},
+		},
+		{Pattern: "POST /user/2fa/disable", Summary: "Disable TOTP", Roles: users, Body: handler.TOTPCodeRequest{}, Response: message},
+		{Pattern: "GET /user/sessions", Summary: "Active sessions", Roles: users, Response: []handler.SessionResponse{}},
+		{Pattern: "DELETE /user/sessions/{id}", Summary: "Revoke a session", Roles: users, Response: message},
+
+		{Pattern: "POST /users/{id}/follow", Summary: "Follow a user", Roles: users, Response: message},
+		{Pattern: "DELETE /users/{id}/follow", Summary: "Unfollow a user", Roles: users, Response: message},
+
+		{Pattern: "POST /users/{id}/block", Summary: "Block a user", Roles: users, Response: message},
+		{Pattern: "DELETE /users/{id}/block", Summary: "Unblock a user", Roles: users, Response: message},
+		{Pattern: "POST /users/{id}/mute", Summary: "Mute a user", Roles: users, Response: message},
+		{Pattern: "DELETE /users/{id}/mute", Summary: "Unmute a user", Roles: users, Response: message},
+		{Pattern: "GET /user/blocks", Summary: "Blocked users", Roles: users, Response: []handler.User{}},
+		{Pattern: "GET /user/mutes", Summary: "Muted users", Roles: users, Response: []handler.User{}},
+
+		{Pattern: "GET /user/oidc", Summary: "Configured identity providers", Response: []string{}},
+		{Pattern: "GET /user/oidc/{provider}/login", Summary: "Redirect to the identity provider", Status: http.StatusFound},
+		{
+			Pattern: "GET /user/oidc/{provider}/callback", Summary: "Finish login with the identity provider",
+			Params: []openapi.Param{
+				{Name: "state", Required: true, Description: "must match the oidc_state cookie set by the login redirect"},
+				{Name: "code"},
+				{Name: "error", Description: "set by the provider when the login failed"},
+			},
+			Response: login,
+		},
+
+		{Pattern: "GET /locations/countries", Summary: "Country suggestions", Params: []openapi.Param{{Name: "q", Description: "name prefix or code"}, limit, lang}, Response: []handler.CountryResponse{}},
+		{
+			Pattern: "GET /locations/cities", Summary: "City suggestions",
+			Params: []openapi.Param{
+				{Name: "q", Required: true, Description: "name prefix"},
+				{Name: "country", Description: "ISO code or name"},
+				limit, lang,
+			},
+			Response: []handler.CityResponse{},
+		},
+
+		{
+			Pattern: "GET /admin/users", Summary: "List accounts", Roles: admin,
+			Params: []openapi.Param{
+				{Name: "q", Description: "email, name or surname"},
+				{Name: "status", Description: "active, suspended or banned"},
+				limit, offset,
+			},
+			Response: struct {
+				Users  []handler.AccountResponse `json:"users"`
+				Total  int                       `json:"total"`
+				Limit  int                       `json:"limit"`
+				Offset int                       `json:"offset"`
+			}{},
+		},
+		{Pattern: "POST /admin/users/{id}/suspend", Summary: "Suspend an account", Roles: admin, Body: handler.SanctionRequest{}, Response: message},
+		{Pattern: "POST /admin/users/{id}/ban", Summary: "Ban an account", Roles: admin, Body: handler.SanctionRequest{}, Response: message},
+		{Pattern: "POST /admin/users/{id}/reinstate", Summary: "Lift a suspension or ban", Roles: admin, Body: handler.SanctionRequest{}, Response: message},
+		{Pattern: "POST /admin/users/{id}/password-reset", Summary: "Force a password reset", Roles: admin, Response: message},
+		{Pattern: "GET /admin/users/{id}/activity", Summary: "Account activity", Roles: admin, Response: handler.ActivityResponse{}},
+
+		{Pattern: "GET /ratings/movies/rating", Summary: "Movies rated by a user", Roles: users, Body: handler.GetMovieByRating{}, Response: []entity.MovieWithRating{}},
+		{Pattern: "GET /ratings/users/rating", Summary: "Users who rated a movie", Roles: users, Body: handler.GetUserByRatingOgMovie{}, Response: []handler.UsersWithRating{}},
+		{Pattern: "PUT /ratings/update", Summary: "Rate a movie", Roles: users, Body: handler.UpdateRating{}, Response: message},
+
+		{Pattern: "GET /healthz", Summary: "Liveness probe", Response: openapi.Object("status")},
+		{Pattern: "GET /readyz", Summary: "Readiness probe", Description: "Responds 503 problem+json with code service_unavailable and the database, schema_version and expected_schema fields while not ready.", Response: handler.ReadyResponse{}},
+		{Pattern: "GET /version", Summary: "Build information", Response: buildinfo.Info{}},
+		{Pattern: "GET /openapi.json", Summary: "This document", Response: &openapi.Schema{Type: "object"}},
+		{Pattern: "GET /docs/", Summary: "API documentation page", Response: &openapi.Schema{Type: "string"}, ResponseType: "text/html"},
+	}
+
+	return openapi.New(openapi.Info{Title: "movie-final", Version: buildinfo.Get().Version}, ops)
+}
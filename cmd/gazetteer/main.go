@@ -0,0 +1,183 @@
+// gazetteer загружает справочник стран и городов из выгрузки GeoNames
+// (https://download.geonames.org/export/dump/) и сопоставляет с ним города,
+// которые пользователи вводили текстом до появления справочника.
+//
+// Пример:
+//
+//	MOVIE_DB_URL=postgres://... go run ./cmd/gazetteer -countries countryInfo.txt -cities cities15000.txt \
+//		-names alternateNamesV2.txt -langs ru,kk,en
+//
+// Загрузку можно повторять: существующие записи обновляются.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/marcokz/movie-final/internal/entity"
+	"github.com/marcokz/movie-final/internal/geonames"
+	"github.com/marcokz/movie-final/internal/postgresdb"
+)
+
+// Сколько городов копим в памяти перед отправкой в базу
+const citiesChunk = 5000
+
+func main() {
+	dsn := flag.String("db", os.Getenv("MOVIE_DB_URL"), "postgres connection string, env MOVIE_DB_URL")
+	countriesFile := flag.String("countries", "", "countryInfo.txt")
+	citiesFile := flag.String("cities", "", "cities15000.txt, cities500.txt or allCountries.txt")
+	namesFile := flag.String("names", "", "alternateNamesV2.txt, translations for -countries and -cities")
+	langsFlag := flag.String("langs", "ru,kk,en", "comma separated languages to import from -names")
+	flag.Parse()
+	if *dsn == "" {
+		log.Fatal("-db or MOVIE_DB_URL is required")
+	}
+
+	pool, err := postgresdb.Connect(postgresdb.PoolConfig{URL: *dsn})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer pool.Close()
+
+	ctx := context.Background()
+	repo := postgresdb.NewLocationsRepo(pool)
+
+	// geonameid страны -> код, чтобы узнать переводы стран в alternateNames
+	countryIDs := map[int64]string{}
+	if *countriesFile != "" {
+		f, err := os.Open(*countriesFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		countries, err := geonames.ReadCountries(f)
+		f.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		list := make([]entity.Country, 0, len(countries))
+		for _, c := range countries {
+			list = append(list, c.Country)
+			countryIDs[c.GeonameID] = c.Code
+		}
+		if err := repo.ImportCountries(ctx, list); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("countries: %d", len(list))
+	}
+
+	cityIDs := map[int64]bool{}
+	if *citiesFile != "" {
+		f, err := os.Open(*citiesFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		var chunk []entity.City
+		flush := func() error {
+			err := repo.ImportCities(ctx, chunk)
+			chunk = chunk[:0]
+			return err
+		}
+
+		err = geonames.ReadCities(f, func(c entity.City) error {
+			cityIDs[c.ID] = true
+			chunk = append(chunk, c)
+			if len(chunk) < citiesChunk {
+				return nil
+			}
+			return flush()
+		})
+		if err == nil {
+			err = flush()
+		}
+		f.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("cities: %d", len(cityIDs))
+	}
+
+	if *namesFile != "" {
+		langs := map[string]bool{}
+		for _, l := range strings.Split(*langsFlag, ",") {
+			if l = strings.TrimSpace(l); l != "" {
+				langs[l] = true
+			}
+		}
+
+		countryNames, cityNames, err := readNames(*namesFile, langs, countryIDs, cityIDs)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := repo.ImportCountryNames(ctx, countryNames); err != nil {
+			log.Fatal(err)
+		}
+		if err := repo.ImportCityNames(ctx, cityNames); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("names: %d countries, %d cities", len(countryNames), len(cityNames))
+	}
+
+	matched, err := repo.MatchLegacyCities(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("users matched to cities: %d", matched)
+}
+
+// readNames выбирает по одному названию на место и язык: предпочтительное,
+// иначе полное, иначе первое попавшееся. Берутся только страны из -countries
+// и города из -cities этого же запуска.
+func readNames(path string, langs map[string]bool, countryIDs map[int64]string, cityIDs map[int64]bool) ([]entity.PlaceName, []entity.PlaceName, error) {
+	type key struct {
+		id   int64
+		lang string
+	}
+	best := map[key]geonames.AlternateName{}
+	score := func(n geonames.AlternateName) int {
+		s := 0
+		if n.Preferred {
+			s += 2
+		}
+		if !n.Short {
+			s++
+		}
+		return s
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	err = geonames.ReadAlternateNames(f, langs, func(n geonames.AlternateName) error {
+		_, isCountry := countryIDs[n.GeonameID]
+		if !isCountry && !cityIDs[n.GeonameID] {
+			return nil
+		}
+		k := key{n.GeonameID, n.Lang}
+		if old, ok := best[k]; !ok || score(n) > score(old) {
+			best[k] = n
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var countryNames, cityNames []entity.PlaceName
+	for k, n := range best {
+		if code, ok := countryIDs[k.id]; ok {
+			countryNames = append(countryNames, entity.PlaceName{Code: code, Lang: n.Lang, Name: n.Name})
+			continue
+		}
+		cityNames = append(cityNames, entity.PlaceName{ID: k.id, Lang: n.Lang, Name: n.Name})
+	}
+
+	return countryNames, cityNames, nil
+}
@@ -0,0 +1,30 @@
+// mockoidc минимальный OpenID Connect провайдер для локальной проверки входа через
+// внешних провайдеров. Любой запрос на /authorize сразу одобряется, пользователь
+// берётся из параметров email и sub (по умолчанию dev@example.com).
+//
+// Пример oidc.json для основного сервиса:
+//
+//	[{"name": "mock", "issuer": "http://127.0.0.1:9090", "clientid": "movie-final",
+//	  "clientsecret": "secret", "redirecturl": "http://127.0.0.1:8080/user/oidc/mock/callback"}]
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/marcokz/movie-final/internal/oidc/oidctest"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:9090", "listen address")
+	flag.Parse()
+
+	p, err := oidctest.New("http://" + *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Println("mock oidc provider on http://" + *addr)
+	log.Fatal(http.ListenAndServe(*addr, p.Handler()))
+}
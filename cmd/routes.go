@@ -0,0 +1,128 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/marcokz/movie-final/internal/handler"
+	"github.com/marcokz/movie-final/internal/metrics"
+	"github.com/marcokz/movie-final/internal/middleware"
+	"github.com/marcokz/movie-final/internal/openapi"
+	"github.com/marcokz/movie-final/internal/tracing"
+)
+
+// apiHandlers обработчики, из которых собирается таблица маршрутов
+type apiHandlers struct {
+	authz     *middleware.Auth
+	movies    *handler.MovieHandler
+	images    *handler.ImageUploader
+	users     *handler.UserHandler
+	follows   *handler.FollowsHandler
+	blocks    *handler.BlocksHandler
+	oidc      *handler.OIDCHandler
+	locations *handler.LocationsHandler
+	admin     *handler.AdminHandler
+	ratings   *handler.RatingsHandler
+	health    *handler.Health
+	apiDoc    *openapi.Document
+	docs      http.Handler
+}
+
+// routes регистрирует маршруты API в mux и возвращает их шаблоны. Таблица не
+// зависит от базы, поэтому тест сверяет её с описанием API (cmd/api.go).
+func routes(mux *http.ServeMux, h apiHandlers) []string {
+	var patterns []string
+	// route регистрирует обработчик вместе с метриками и трассировкой по его шаблону
+	route := func(pattern string, h http.HandlerFunc) {
+		patterns = append(patterns, pattern)
+		mux.HandleFunc(pattern, metrics.Instrument(pattern, tracing.Instrument(pattern, h)))
+	}
+
+	adminOnly := h.authz.Authorize("admin")
+	userAndAdmin := h.authz.Authorize("admin", "moderator", "user")
+
+	m := h.movies
+	route("POST /movies", adminOnly(m.CreateMovie))
+	route("GET /movies", m.GetMovies)
+	route("GET /movies/{id}", m.GetMoviesByID)
+	route("PUT /movies/{id}", adminOnly(m.UpdateMovieByID))
+	route("DELETE /movies/{id}", adminOnly(m.DeleteMovieByID))
+	route("PUT /movies/{id}/poster", adminOnly(m.UploadPoster))
+	route("DELETE /movies/{id}/poster", adminOnly(m.DeletePoster))
+
+	route("GET /images/{name}", h.images.ServeImage)
+
+	u := h.users
+	route("POST /user/create", u.CreateUser)
+	route("POST /user/auth", u.Login)
+	route("POST /user/auth/2fa", u.LoginTOTP)
+	route("POST /user/logout", u.Logout)
+	route("GET /user/age", userAndAdmin(u.GetUserByAge))
+	route("GET /user/country", userAndAdmin(u.GetUserByCountry))
+	route("GET /user/city", userAndAdmin(u.GetUserByCity))
+	route("GET /user/sex", userAndAdmin(u.GetUserBySex))
+	route("GET /users/search", userAndAdmin(u.SearchUsers))
+	route("GET /users/nearby", userAndAdmin(u.NearbyUsers))
+	route("GET /users/{id}", userAndAdmin(u.GetProfile)) // и /users/@{handle}
+	route("GET /user/me", userAndAdmin(u.GetMe))
+	route("PATCH /user/me", userAndAdmin(u.PatchMe))
+	route("PUT /user/me/handle", userAndAdmin(u.SetHandle))
+	route("DELETE /user/me", userAndAdmin(u.DeleteMe))
+	route("PUT /user/me/avatar", userAndAdmin(u.UploadAvatar))
+	route("DELETE /user/me/avatar", userAndAdmin(u.DeleteAvatar))
+	route("GET /user/me/export", userAndAdmin(u.ExportMe))
+	route("GET /user/privacy", userAndAdmin(u.GetPrivacy))
+	route("PUT /user/privacy", userAndAdmin(u.UpdatePrivacy))
+	route("PUT /user/update", userAndAdmin(u.UpdateUserInfo))
+	route("PUT /user/password", userAndAdmin(u.ChangePassword))
+	route("POST /user/password/reset", u.ResetPassword)
+	route("POST /user/password/reset/request", u.RequestPasswordReset)
+	route("PUT /user/email", userAndAdmin(u.ChangeEmail))
+	route("POST /user/email/confirm", userAndAdmin(u.ConfirmEmail))
+	route("POST /user/2fa/enroll", userAndAdmin(u.EnrollTOTP))
+	route("POST /user/2fa/verify", userAndAdmin(u.VerifyTOTP))
+	route("POST /user/2fa/disable", userAndAdmin(u.DisableTOTP))
+	route("GET /user/sessions", userAndAdmin(u.GetSessions))
+	route("DELETE /user/sessions/{id}", userAndAdmin(u.DeleteSession))
+
+	f := h.follows
+	route("POST /users/{id}/follow", userAndAdmin(f.Follow))
+	route("DELETE /users/{id}/follow", userAndAdmin(f.Unfollow))
+
+	b := h.blocks
+	route("POST /users/{id}/block", userAndAdmin(b.Block))
+	route("DELETE /users/{id}/block", userAndAdmin(b.Unblock))
+	route("POST /users/{id}/mute", userAndAdmin(b.Mute))
+	route("DELETE /users/{id}/mute", userAndAdmin(b.Unmute))
+	route("GET /user/blocks", userAndAdmin(b.GetBlocks))
+	route("GET /user/mutes", userAndAdmin(b.GetMutes))
+
+	o := h.oidc
+	route("GET /user/oidc", o.Providers)
+	route("GET /user/oidc/{provider}/login", o.Login)
+	route("GET /user/oidc/{provider}/callback", o.Callback)
+
+	l := h.locations
+	route("GET /locations/countries", l.Countries)
+	route("GET /locations/cities", l.Cities)
+
+	a := h.admin
+	route("GET /admin/users", adminOnly(a.ListUsers))
+	route("POST /admin/users/{id}/suspend", adminOnly(a.SuspendUser))
+	route("POST /admin/users/{id}/ban", adminOnly(a.BanUser))
+	route("POST /admin/users/{id}/reinstate", adminOnly(a.ReinstateUser))
+	route("POST /admin/users/{id}/password-reset", adminOnly(a.ForcePasswordReset))
+	route("GET /admin/users/{id}/activity", adminOnly(a.GetUserActivity))
+
+	r := h.ratings
+	route("GET /ratings/movies/rating", userAndAdmin(r.GetMoviesWithRatingFromUser))
+	route("GET /ratings/users/rating", userAndAdmin(r.GetUsersByRatingOfMovie))
+	route("PUT /ratings/update", userAndAdmin(r.UpdateRating))
+
+	route("GET /healthz", h.health.Alive)
+	route("GET /readyz", h.health.Ready)
+	route("GET /version", h.health.Version)
+	route("GET /openapi.json", h.apiDoc.ServeHTTP)
+	route("GET /docs/", h.docs.ServeHTTP)
+
+	return patterns
+}
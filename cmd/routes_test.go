@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/marcokz/movie-final/internal/handler"
+	"github.com/marcokz/movie-final/internal/middleware"
+	"github.com/marcokz/movie-final/internal/openapi"
+)
+
+// TestRoutesDescribed маршрут без описания или описание без маршрута: документ врёт клиентам
+func TestRoutesDescribed(t *testing.T) {
+	doc, err := apiDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs, err := openapi.Docs("/docs/", "movie-final API", "/openapi.json", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Обработчики не вызываются, репозитории им не нужны
+	users := handler.NewUserHandler(nil, nil, nil, nil, nil, nil, nil)
+	patterns := routes(http.NewServeMux(), apiHandlers{
+		authz:     middleware.NewAuth(nil),
+		movies:    handler.NewMovieHandler(nil, nil),
+		images:    handler.NewImageUploader(nil, nil),
+		users:     users,
+		follows:   handler.NewFollowsHandler(nil),
+		blocks:    handler.NewBlocksHandler(nil),
+		oidc:      handler.NewOIDCHandler(nil, nil, users),
+		locations: handler.NewLocationsHandler(nil),
+		admin:     handler.NewAdminHandler(nil, nil, nil),
+		ratings:   handler.NewRatingsHandler(nil),
+		health:    handler.NewHealth(nil, 0),
+		apiDoc:    doc,
+		docs:      docs,
+	})
+
+	if err := doc.Check(patterns); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -7,21 +7,43 @@ import (
 	"github.com/golang-jwt/jwt"
 )
 
-// Секретный ключ для подписи токена
-var jwtKey = []byte("your_secret_key")
+// Секретный ключ для подписи токена, задаётся при старте через SetJWTKey
+var jwtKey []byte
+
+var errNoJWTKey = errors.New("jwt key is not set")
+
+// Короче ключа HS256 быть не должно
+const minJWTKeyBytes = 32
+
+func SetJWTKey(key []byte) error {
+	if len(key) < minJWTKeyBytes {
+		return errors.New("jwt key is too short")
+	}
+	jwtKey = key
+	return nil
+}
+
+func signingKey(*jwt.Token) (interface{}, error) {
+	if jwtKey == nil {
+		return nil, errNoJWTKey
+	}
+	return jwtKey, nil
+}
 
 type Claims struct {
-	ID   int64  `json:"id"`
-	Role string `json:"role"`
+	ID        int64  `json:"id"`
+	SessionID int64  `json:"sid"`
+	Role      string `json:"role"`
 	jwt.StandardClaims
 }
 
-func GenerateJWT(id int64, email string, role string) (string, error) {
+func GenerateJWT(id, sessionID int64, email string, role string) (string, error) {
 	// Устанавливаем время жизни токена
 	expirationTime := time.Now().Add(time.Hour * 24)
 	claims := &Claims{
-		ID:   id,
-		Role: role,
+		ID:        id,
+		SessionID: sessionID,
+		Role:      role,
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: expirationTime.Unix(),
 		},
@@ -29,7 +51,11 @@ func GenerateJWT(id int64, email string, role string) (string, error) {
 	// Создаём токен
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-	tokenString, err := token.SignedString(jwtKey)
+	key, err := signingKey(token)
+	if err != nil {
+		return "", err
+	}
+	tokenString, err := token.SignedString(key)
 	if err != nil {
 		return "", err
 	}
@@ -38,14 +64,44 @@ func GenerateJWT(id int64, email string, role string) (string, error) {
 
 func ValidationJWT(tokenStr string) (*Claims, error) {
 	claims := &Claims{}
-	token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
-		return jwtKey, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenStr, claims, signingKey)
 	if err != nil {
 		return &Claims{}, err
 	}
-	if !token.Valid {
+	if !token.Valid || claims.Audience == mfaAudience {
 		return &Claims{}, errors.New("invalid token")
 	}
 	return claims, nil
 }
+
+// Токен промежуточного шага входа: пароль проверен, ждём код второго фактора.
+// Им нельзя авторизоваться, он годится только для POST /user/auth/2fa.
+const mfaAudience = "mfa"
+
+func GenerateMFAToken(id int64) (string, error) {
+	claims := &Claims{
+		ID: id,
+		StandardClaims: jwt.StandardClaims{
+			Audience:  mfaAudience,
+			ExpiresAt: time.Now().Add(time.Minute * 5).Unix(),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	key, err := signingKey(token)
+	if err != nil {
+		return "", err
+	}
+	return token.SignedString(key)
+}
+
+func ValidationMFAToken(tokenStr string) (int64, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, signingKey)
+	if err != nil {
+		return 0, err
+	}
+	if !token.Valid || claims.Audience != mfaAudience {
+		return 0, errors.New("invalid token")
+	}
+	return claims.ID, nil
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrPasswordTooShort = errors.New("password is too short")
+	ErrPasswordTooLong  = errors.New("password is too long")
+	ErrPasswordBreached = errors.New("password is in the list of leaked passwords")
+)
+
+// bcrypt учитывает только первые 72 байта
+const maxPasswordBytes = 72
+
+type PasswordPolicy struct {
+	MinLength int
+	breached  map[string]struct{}
+}
+
+// NewPasswordPolicy создаёт политику паролей. breachedFile необязателен: текстовый файл
+// с утёкшими паролями, по одному на строку.
+func NewPasswordPolicy(minLength int, breachedFile string) (*PasswordPolicy, error) {
+	p := &PasswordPolicy{MinLength: minLength, breached: map[string]struct{}{}}
+	if breachedFile == "" {
+		return p, nil
+	}
+
+	f, err := os.Open(breachedFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" {
+			p.breached[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *PasswordPolicy) Validate(password string) error {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return ErrPasswordTooShort
+	}
+	if len(password) > maxPasswordBytes {
+		return ErrPasswordTooLong
+	}
+	if _, ok := p.breached[strings.ToLower(password)]; ok {
+		return ErrPasswordBreached
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Параметры TOTP по RFC 6238, которые понимают все приложения-аутентификаторы
+const (
+	totpPeriod = 30
+	totpDigits = 6
+	totpSkew   = 1 // сколько соседних интервалов принимаем из-за рассинхрона часов
+
+	totpIssuer = "movie-final"
+)
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func NewTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return b32.EncodeToString(b), nil
+}
+
+// TOTPProvisioningURI возвращает otpauth:// ссылку, которую клиент превращает в QR код.
+func TOTPProvisioningURI(secret, account string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", totpIssuer)
+	v.Set("period", fmt.Sprint(totpPeriod))
+	v.Set("digits", fmt.Sprint(totpDigits))
+	return "otpauth://totp/" + url.PathEscape(totpIssuer+":"+account) + "?" + v.Encode()
+}
+
+// ValidateTOTP проверяет код и возвращает номер интервала, на котором он совпал.
+// Номер нужен, чтобы не принимать один и тот же код дважды.
+func ValidateTOTP(secret, code string, now time.Time) (int64, bool) {
+	key, err := b32.DecodeString(secret)
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+
+	step := now.Unix() / totpPeriod
+	for i := int64(-totpSkew); i <= totpSkew; i++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step+i)), []byte(code)) == 1 {
+			return step + i, true
+		}
+	}
+	return 0, false
+}
+
+func totpCode(key []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// NewRecoveryCodes генерирует одноразовые коды восстановления и их хеши.
+func NewRecoveryCodes(n int) ([]string, []string, error) {
+	codes := make([]string, 0, n)
+	hashes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := b32.EncodeToString(b)
+		codes = append(codes, code)
+		hashes = append(hashes, HashToken(code))
+	}
+	return codes, hashes, nil
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// Ключ из приложения B RFC 6238 для SHA-1
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	// В RFC коды из 8 цифр, наши из 6: это последние 6 цифр того же числа
+	tests := []struct {
+		unix int64
+		rfc  string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, tt := range tests {
+		want := tt.rfc[2:]
+		if got := totpCode([]byte("12345678901234567890"), tt.unix/totpPeriod); got != want {
+			t.Errorf("totpCode(T=%d) = %s, want %s", tt.unix, got, want)
+		}
+
+		step, ok := ValidateTOTP(rfcSecret, want, time.Unix(tt.unix, 0))
+		if !ok || step != tt.unix/totpPeriod {
+			t.Errorf("ValidateTOTP(T=%d) = %d, %v, want step %d", tt.unix, step, ok, tt.unix/totpPeriod)
+		}
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	key := []byte("12345678901234567890")
+	now := time.Unix(1111111111, 0)
+	step := now.Unix() / totpPeriod
+
+	tests := []struct {
+		name   string
+		code   string
+		ok     bool
+		atStep int64
+	}{
+		{"current", totpCode(key, step), true, step},
+		{"previous interval", totpCode(key, step-1), true, step - 1},
+		{"next interval", totpCode(key, step+1), true, step + 1},
+		{"two intervals ago", totpCode(key, step-2), false, 0},
+		{"two intervals ahead", totpCode(key, step+2), false, 0},
+		{"short", "12345", false, 0},
+		{"letters", "abcdef", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ValidateTOTP(rfcSecret, tt.code, now)
+			if ok != tt.ok || got != tt.atStep {
+				t.Errorf("ValidateTOTP() = %d, %v, want %d, %v", got, ok, tt.atStep, tt.ok)
+			}
+		})
+	}
+
+	if _, ok := ValidateTOTP("not base32!", totpCode(key, step), now); ok {
+		t.Error("ValidateTOTP() accepted a broken secret")
+	}
+}
+
+// TestValidateTOTPReplay один код в пределах окна совпадает с одним и тем же интервалом,
+// поэтому повтор отсекается по номеру интервала (UseTOTPStep)
+func TestValidateTOTPReplay(t *testing.T) {
+	key := []byte("12345678901234567890")
+	issued := time.Unix(1111111111, 0)
+	code := totpCode(key, issued.Unix()/totpPeriod)
+
+	first, ok := ValidateTOTP(rfcSecret, code, issued)
+	if !ok {
+		t.Fatal("code rejected at issue time")
+	}
+	replay, ok := ValidateTOTP(rfcSecret, code, issued.Add(totpPeriod*time.Second))
+	if !ok {
+		t.Fatal("code rejected in the next interval")
+	}
+	if replay != first {
+		t.Errorf("replayed code matched step %d, first use %d", replay, first)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := NewRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != 10 || len(hashes) != 10 {
+		t.Fatalf("got %d codes, %d hashes", len(codes), len(hashes))
+	}
+	seen := map[string]bool{}
+	for i, c := range codes {
+		if seen[c] {
+			t.Errorf("duplicate code %s", c)
+		}
+		seen[c] = true
+		if hashes[i] != HashToken(c) {
+			t.Errorf("hash %d does not match its code", i)
+		}
+	}
+}
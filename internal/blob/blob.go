@@ -0,0 +1,39 @@
+// Package blob хранилище файлов по ключу. Сейчас есть только реализация на
+// локальном диске, интерфейс рассчитан и на S3-совместимые хранилища.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// Store ключи вида "ab/abcdef...", только строчные латинские буквы, цифры, "_" и "/".
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Exists(ctx context.Context, key string) (bool, error)
+	Delete(ctx context.Context, key string) error
+}
+
+var ErrNotFound error = errors.New("blob not found")
+
+var ErrInvalidKey error = errors.New("invalid blob key")
+
+func validKey(key string) bool {
+	if key == "" || key[0] == '/' || key[len(key)-1] == '/' {
+		return false
+	}
+	prev := byte(0)
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '_':
+		case c == '/' && prev != '/':
+		default:
+			return false
+		}
+		prev = c
+	}
+	return true
+}
@@ -0,0 +1,46 @@
+// Package buildinfo сведения о сборке. Значения подставляются при линковке:
+//
+//	go build -ldflags "-X github.com/marcokz/movie-final/internal/buildinfo.Version=v1.2.0 \
+//		-X github.com/marcokz/movie-final/internal/buildinfo.Commit=$(git rev-parse HEAD) \
+//		-X github.com/marcokz/movie-final/internal/buildinfo.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get без -ldflags берёт коммит и время из сведений о VCS, которые пишет go build
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit, Date: Date, GoVersion: runtime.Version()}
+	if info.Commit != "" {
+		return info
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				info.Commit = s.Value
+			case "vcs.time":
+				if info.Date == "" {
+					info.Date = s.Value
+				}
+			}
+		}
+	}
+	return info
+}
@@ -0,0 +1,353 @@
+// Package config настройки сервиса. Источники по возрастанию приоритета: значения
+// по умолчанию, файл (-config или MOVIE_CONFIG), переменные окружения MOVIE_*, флаги.
+//
+// Ключ http.addr задаётся в файле как addr в секции [http], в окружении как
+// MOVIE_HTTP_ADDR, флагом как -http.addr. Секреты можно читать из файла: ключ с
+// суффиксом _file (db.url_file, MOVIE_AUTH_JWT_SECRET_FILE) содержит путь к нему.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	HTTP     HTTP
+	Database Database
+	Auth     Auth
+	Images   Images
+	Log      Log
+	Tracing  Tracing
+	Metrics  Metrics
+	Docs     Docs
+}
+
+type HTTP struct {
+	Addr              string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+
+	// При остановке сервис сначала DrainDelay отвечает not ready, чтобы балансировщик
+	// перестал слать запросы, затем до ShutdownTimeout дожидается начатых запросов.
+	DrainDelay      time.Duration
+	ShutdownTimeout time.Duration
+
+	// Адреса балансировщиков, которым верим X-Forwarded-For. Без них адрес клиента
+	// берётся из соединения, и за балансировщиком все клиенты выглядят одним.
+	TrustedProxies []netip.Prefix
+}
+
+// Database нулевые значения оставляют настройки пула pgx по умолчанию
+type Database struct {
+	URL               string
+	MaxConns          int
+	MinConns          int
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+	ConnectTimeout    time.Duration
+
+	// Применять миграции при старте. Без этого сервис не стартует, пока
+	// схема не совпадёт с ожидаемой.
+	AutoMigrate bool
+}
+
+type Auth struct {
+	JWTSecret             string
+	MinPasswordLength     int
+	BreachedPasswordsFile string // необязателен, утёкшие пароли по одному на строку
+	OIDCProvidersFile     string // JSON со списком провайдеров, пустой выключает вход через них
+}
+
+type Images struct {
+	Dir string
+}
+
+type Log struct {
+	Level  string // debug, info, warn, error
+	Format string // text или json
+}
+
+type Tracing struct {
+	Exporter    string // none, stdout или otlp
+	Endpoint    string // OTLP/HTTP коллектора
+	ServiceName string
+	SampleRatio float64 // доля записываемых трасс, решение из traceparent важнее
+}
+
+// Metrics метрики отдаются на отдельном адресе, недоступном снаружи
+type Metrics struct {
+	Addr string // пустой выключает эндпоинт
+}
+
+type Docs struct {
+	SwaggerUIDir string // каталог пакета swagger-ui-dist вместо встроенного, например другой версии
+}
+
+// Минимальная длина ключа подписи JWT (HS256)
+const minJWTSecretBytes = 32
+
+func Default() Config {
+	return Config{
+		HTTP: HTTP{
+			Addr:              ":8080",
+			ReadTimeout:       30 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      60 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			DrainDelay:        5 * time.Second,
+			ShutdownTimeout:   20 * time.Second,
+		},
+		Database: Database{
+			ConnectTimeout: 3 * time.Second,
+		},
+		Auth: Auth{
+			MinPasswordLength: 8,
+		},
+		Images: Images{Dir: "data/images"},
+		Log:    Log{Level: "info", Format: "text"},
+		Tracing: Tracing{
+			Exporter:    "none",
+			Endpoint:    "http://localhost:4318/v1/traces",
+			ServiceName: "movie-final",
+			SampleRatio: 0.1,
+		},
+		Metrics: Metrics{Addr: "127.0.0.1:9091"},
+	}
+}
+
+// setting одна настройка. Значение меняется через flag.Value, поэтому разбор строк
+// одинаков для файла, окружения и флагов.
+type setting struct {
+	key    string
+	usage  string
+	secret bool
+	value  flag.Value
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{key: "http.addr", usage: "listen address", value: (*stringValue)(&c.HTTP.Addr)},
+		{key: "http.read_timeout", usage: "max time to read a request including body", value: (*durationValue)(&c.HTTP.ReadTimeout)},
+		{key: "http.read_header_timeout", usage: "max time to read request headers", value: (*durationValue)(&c.HTTP.ReadHeaderTimeout)},
+		{key: "http.write_timeout", usage: "max time to write a response", value: (*durationValue)(&c.HTTP.WriteTimeout)},
+		{key: "http.idle_timeout", usage: "keep-alive connections idle timeout", value: (*durationValue)(&c.HTTP.IdleTimeout)},
+		{key: "http.drain_delay", usage: "how long to report not ready before shutdown", value: (*durationValue)(&c.HTTP.DrainDelay)},
+		{key: "http.shutdown_timeout", usage: "how long to wait for in-flight requests on shutdown", value: (*durationValue)(&c.HTTP.ShutdownTimeout)},
+		{key: "http.trusted_proxies", usage: "comma-separated proxy addresses or CIDRs whose X-Forwarded-For is trusted", value: (*prefixListValue)(&c.HTTP.TrustedProxies)},
+
+		{key: "db.url", usage: "postgres connection string", secret: true, value: (*stringValue)(&c.Database.URL)},
+		{key: "db.max_conns", usage: "max pool connections, 0 for pgx default", value: (*intValue)(&c.Database.MaxConns)},
+		{key: "db.min_conns", usage: "min idle pool connections", value: (*intValue)(&c.Database.MinConns)},
+		{key: "db.max_conn_lifetime", usage: "close connections older than this", value: (*durationValue)(&c.Database.MaxConnLifetime)},
+		{key: "db.max_conn_idle_time", usage: "close connections idle longer than this", value: (*durationValue)(&c.Database.MaxConnIdleTime)},
+		{key: "db.health_check_period", usage: "how often idle connections are checked", value: (*durationValue)(&c.Database.HealthCheckPeriod)},
+		{key: "db.connect_timeout", usage: "timeout for the initial connection", value: (*durationValue)(&c.Database.ConnectTimeout)},
+		{key: "db.auto_migrate", usage: "apply pending migrations on start", value: (*boolValue)(&c.Database.AutoMigrate)},
+
+		{key: "auth.jwt_secret", usage: "HS256 key for access tokens, at least 32 bytes", secret: true, value: (*stringValue)(&c.Auth.JWTSecret)},
+		{key: "auth.min_password_length", usage: "minimum password length", value: (*intValue)(&c.Auth.MinPasswordLength)},
+		{key: "auth.breached_passwords_file", usage: "file with leaked passwords, one per line", value: (*stringValue)(&c.Auth.BreachedPasswordsFile)},
+		{key: "auth.oidc_providers_file", usage: "JSON file with OpenID Connect providers", value: (*stringValue)(&c.Auth.OIDCProvidersFile)},
+
+		{key: "images.dir", usage: "directory for uploaded images", value: (*stringValue)(&c.Images.Dir)},
+
+		{key: "log.level", usage: "debug, info, warn or error", value: (*stringValue)(&c.Log.Level)},
+		{key: "log.format", usage: "text or json", value: (*stringValue)(&c.Log.Format)},
+
+		{key: "tracing.exporter", usage: "none, stdout or otlp", value: (*stringValue)(&c.Tracing.Exporter)},
+		{key: "tracing.endpoint", usage: "OTLP/HTTP traces endpoint of the collector", value: (*stringValue)(&c.Tracing.Endpoint)},
+		{key: "tracing.service_name", usage: "service.name reported with spans", value: (*stringValue)(&c.Tracing.ServiceName)},
+		{key: "tracing.sample_ratio", usage: "share of new traces to record, from 0 to 1", value: (*floatValue)(&c.Tracing.SampleRatio)},
+
+		{key: "metrics.addr", usage: "listen address for GET /metrics, empty to disable", value: (*stringValue)(&c.Metrics.Addr)},
+
+		{key: "docs.swagger_ui_dir", usage: "directory with swagger-ui-dist files served at /docs/ instead of the embedded ones", value: (*stringValue)(&c.Docs.SwaggerUIDir)},
+	}
+}
+
+// Validate проверяет настройки для подкоманды command ("" запуск сервера) и возвращает
+// все ошибки сразу. База нужна серверу и migrate, ключ JWT только серверу, а openapi
+// не нужно ни то, ни другое.
+func (c *Config) Validate(command string) error {
+	var errs []error
+
+	needDatabase := command == "" || command == "migrate"
+	needAuth := command == ""
+
+	if _, _, err := net.SplitHostPort(c.HTTP.Addr); err != nil {
+		errs = append(errs, fmt.Errorf("http.addr: %w", err))
+	}
+	if c.HTTP.ReadTimeout < 0 || c.HTTP.ReadHeaderTimeout < 0 || c.HTTP.WriteTimeout < 0 || c.HTTP.IdleTimeout < 0 ||
+		c.HTTP.DrainDelay < 0 {
+		errs = append(errs, errors.New("http durations must not be negative"))
+	}
+	if c.HTTP.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("http.shutdown_timeout must be positive"))
+	}
+
+	if c.Database.URL == "" {
+		if needDatabase {
+			errs = append(errs, errors.New("db.url or db.url_file is required"))
+		}
+	} else if _, err := url.Parse(c.Database.URL); err != nil {
+		errs = append(errs, errors.New("db.url is not a valid URL"))
+	}
+	if c.Database.MaxConns < 0 || c.Database.MinConns < 0 {
+		errs = append(errs, errors.New("db.max_conns and db.min_conns must not be negative"))
+	}
+	if c.Database.MaxConns > 0 && c.Database.MinConns > c.Database.MaxConns {
+		errs = append(errs, errors.New("db.min_conns must not exceed db.max_conns"))
+	}
+	if c.Database.MaxConnLifetime < 0 || c.Database.MaxConnIdleTime < 0 ||
+		c.Database.HealthCheckPeriod < 0 || c.Database.ConnectTimeout < 0 {
+		errs = append(errs, errors.New("db durations must not be negative"))
+	}
+
+	if needAuth && len(c.Auth.JWTSecret) < minJWTSecretBytes {
+		errs = append(errs, fmt.Errorf("auth.jwt_secret must be at least %d bytes", minJWTSecretBytes))
+	}
+	if c.Auth.MinPasswordLength < 1 {
+		errs = append(errs, errors.New("auth.min_password_length must be positive"))
+	}
+
+	if c.Images.Dir == "" {
+		errs = append(errs, errors.New("images.dir is required"))
+	}
+
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, errors.New("log.level must be debug, info, warn or error"))
+	}
+	if c.Log.Format != "text" && c.Log.Format != "json" {
+		errs = append(errs, errors.New("log.format must be text or json"))
+	}
+
+	if c.Metrics.Addr != "" {
+		if _, _, err := net.SplitHostPort(c.Metrics.Addr); err != nil {
+			errs = append(errs, fmt.Errorf("metrics.addr: %w", err))
+		} else if c.Metrics.Addr == c.HTTP.Addr {
+			errs = append(errs, errors.New("metrics.addr must differ from http.addr"))
+		}
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if u, err := url.Parse(c.Tracing.Endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, errors.New("tracing.endpoint must be an absolute URL"))
+		}
+	default:
+		errs = append(errs, errors.New("tracing.exporter must be none, stdout or otlp"))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, errors.New("tracing.sample_ratio must be between 0 and 1"))
+	}
+
+	return errors.Join(errs...)
+}
+
+type stringValue string
+
+func (v *stringValue) Set(s string) error {
+	*v = stringValue(s)
+	return nil
+}
+
+func (v *stringValue) String() string { return string(*v) }
+
+type intValue int
+
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return errors.New("not an integer")
+	}
+	*v = intValue(n)
+	return nil
+}
+
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+
+type floatValue float64
+
+func (v *floatValue) Set(s string) error {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return errors.New("not a number")
+	}
+	*v = floatValue(f)
+	return nil
+}
+
+func (v *floatValue) String() string { return strconv.FormatFloat(float64(*v), 'g', -1, 64) }
+
+type boolValue bool
+
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return errors.New("not a boolean")
+	}
+	*v = boolValue(b)
+	return nil
+}
+
+func (v *boolValue) String() string { return strconv.FormatBool(bool(*v)) }
+
+func (v *boolValue) IsBoolFlag() bool { return true }
+
+type durationValue time.Duration
+
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return errors.New("not a duration, expected e.g. 30s or 5m")
+	}
+	*v = durationValue(d)
+	return nil
+}
+
+func (v *durationValue) String() string { return time.Duration(*v).String() }
+
+// prefixListValue адреса и подсети через запятую; адрес без маски значит ровно его
+type prefixListValue []netip.Prefix
+
+func (v *prefixListValue) Set(s string) error {
+	var list []netip.Prefix
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if addr, err := netip.ParseAddr(part); err == nil {
+			list = append(list, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(part)
+		if err != nil {
+			return fmt.Errorf("%q is not an IP address or CIDR", part)
+		}
+		list = append(list, p.Masked())
+	}
+	*v = list
+	return nil
+}
+
+func (v *prefixListValue) String() string {
+	parts := make([]string, len(*v))
+	for i, p := range *v {
+		parts[i] = p.String()
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func TestLoadRequiredByCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr []string
+	}{
+		{"server without db and secret", nil, []string{"db.url or db.url_file is required", "auth.jwt_secret"}},
+		{"server", []string{"-db.url", "postgres://db/movies", "-auth.jwt_secret", testSecret}, nil},
+		{"server with short secret", []string{"-db.url", "postgres://db/movies", "-auth.jwt_secret", "short"}, []string{"auth.jwt_secret"}},
+		{"migrate without db", []string{"migrate", "up"}, []string{"db.url or db.url_file is required"}},
+		{"migrate without secret", []string{"-db.url", "postgres://db/movies", "migrate", "up"}, nil},
+		{"openapi", []string{"openapi"}, nil},
+		{"metrics on the API address", []string{"-metrics.addr", ":8080", "openapi"}, []string{"metrics.addr must differ from http.addr"}},
+		{"metrics disabled", []string{"-metrics.addr", "", "openapi"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Load("movie", tt.args)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("Load() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Load() error = nil")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Load() error = %q, want it to mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadDatabaseURLFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db_url")
+	if err := os.WriteFile(path, []byte("postgres://db/movies\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, _, err := Load("movie", []string{"-db.url_file", path, "-auth.jwt_secret", testSecret})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if c.Database.URL != "postgres://db/movies" {
+		t.Errorf("Database.URL = %q", c.Database.URL)
+	}
+}
+
+func TestLoadTrustedProxies(t *testing.T) {
+	c, _, err := Load("movie", []string{"-http.trusted_proxies", "10.0.0.0/8, 192.168.1.7,fd00::/8", "openapi"})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := (*prefixListValue)(&c.HTTP.TrustedProxies).String(); got != "10.0.0.0/8,192.168.1.7/32,fd00::/8" {
+		t.Errorf("TrustedProxies = %s", got)
+	}
+
+	if _, _, err := Load("movie", []string{"-http.trusted_proxies", "10.0.0.0/8,lb.internal", "openapi"}); err == nil {
+		t.Error("Load() accepted a host name as a trusted proxy")
+	}
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const envPrefix = "MOVIE_"
+
+// Load собирает настройки из всех источников и проверяет их. args аргументы
+// командной строки без имени программы; на -h возвращается flag.ErrHelp.
+// Вторым значением возвращаются аргументы после флагов, например подкоманда;
+// от неё зависит, какие настройки обязательны.
+func Load(name string, args []string) (Config, []string, error) {
+	c := Default()
+	settings := c.settings()
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv(envPrefix+"CONFIG"), "config file (TOML), env "+envPrefix+"CONFIG")
+
+	flags := map[string]string{}
+	for _, s := range settings {
+		_, isBool := s.value.(*boolValue)
+		fs.Var(&collectValue{key: s.key, to: flags, def: s.value.String(), isBool: isBool}, s.key, s.usage+", env "+envName(s.key))
+		if s.secret {
+			fs.Var(&collectValue{key: s.key + "_file", to: flags}, s.key+"_file", "read "+s.key+" from this file")
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, nil, err
+	}
+
+	if *configFile != "" {
+		file, err := readFile(*configFile)
+		if err != nil {
+			return Config{}, nil, err
+		}
+		if err := apply(settings, file, *configFile); err != nil {
+			return Config{}, nil, err
+		}
+	}
+
+	env := map[string]string{}
+	for _, s := range settings {
+		keys := []string{s.key}
+		if s.secret {
+			keys = append(keys, s.key+"_file")
+		}
+		for _, key := range keys {
+			if v, ok := os.LookupEnv(envName(key)); ok {
+				env[key] = v
+			}
+		}
+	}
+	if err := apply(settings, env, "environment"); err != nil {
+		return Config{}, nil, err
+	}
+
+	if err := apply(settings, flags, "flags"); err != nil {
+		return Config{}, nil, err
+	}
+
+	if err := c.Validate(fs.Arg(0)); err != nil {
+		return Config{}, nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return c, fs.Args(), nil
+}
+
+func envName(key string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// apply переносит значения одного источника. Неизвестные ключи считаются ошибкой,
+// чтобы опечатка в файле не оставляла молча значение по умолчанию.
+func apply(settings []setting, values map[string]string, source string) error {
+	known := map[string]bool{}
+	for _, s := range settings {
+		known[s.key] = true
+
+		v, ok := values[s.key]
+		path, fromFile := values[s.key+"_file"]
+		if s.secret {
+			known[s.key+"_file"] = true
+		} else {
+			fromFile = false
+		}
+
+		if ok && fromFile {
+			return fmt.Errorf("%s: both %s and %s_file are set", source, s.key, s.key)
+		}
+		if fromFile {
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("%s: %s_file: %w", source, s.key, err)
+			}
+			v, ok = strings.TrimRight(string(b), "\r\n"), true
+		}
+		if !ok {
+			continue
+		}
+
+		if err := s.value.Set(v); err != nil {
+			return fmt.Errorf("%s: %s: %w", source, s.key, err)
+		}
+	}
+
+	for key := range values {
+		if !known[key] {
+			return fmt.Errorf("%s: unknown setting %s", source, key)
+		}
+	}
+
+	return nil
+}
+
+// collectValue флаг, который только запоминает явно переданное значение:
+// применяются флаги последними, после файла и окружения.
+type collectValue struct {
+	key    string
+	to     map[string]string
+	def    string
+	isBool bool
+}
+
+func (v *collectValue) Set(s string) error {
+	v.to[v.key] = s
+	return nil
+}
+
+func (v *collectValue) IsBoolFlag() bool { return v.isBool }
+
+func (v *collectValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return v.def
+}
+
+func readFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values, err := parseTOML(f)
+	if err != nil {
+		var lineErr *lineError
+		if errors.As(err, &lineErr) {
+			return nil, fmt.Errorf("%s:%d: %s", path, lineErr.line, lineErr.msg)
+		}
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return values, nil
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type lineError struct {
+	line int
+	msg  string
+}
+
+func (e *lineError) Error() string {
+	return "line " + strconv.Itoa(e.line) + ": " + e.msg
+}
+
+// parseTOML разбирает подмножество TOML, которого хватает для настроек: секции
+// [name], пары key = value, строки в "..." или '...', числа и true/false, комментарии #.
+// Ключи возвращаются с именем секции: [db] max_conns = 10 даёт db.max_conns.
+func parseTOML(r io.Reader) (map[string]string, error) {
+	values := map[string]string{}
+	var section string
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			name, ok := strings.CutSuffix(stripComment(line), "]")
+			name = strings.TrimSpace(strings.TrimPrefix(name, "["))
+			if !ok || name == "" || !validKey(name) {
+				return nil, &lineError{line: n, msg: "invalid section header"}
+			}
+			section = name + "."
+			continue
+		}
+
+		key, raw, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !validKey(key) {
+			return nil, &lineError{line: n, msg: "expected key = value"}
+		}
+
+		value, err := parseValue(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, &lineError{line: n, msg: err.Error()}
+		}
+
+		if _, dup := values[section+key]; dup {
+			return nil, &lineError{line: n, msg: "duplicate key " + section + key}
+		}
+		values[section+key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+func parseValue(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		end := closingQuote(s)
+		if end < 0 {
+			return "", errors.New("unterminated string")
+		}
+		if rest := strings.TrimSpace(s[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+			return "", errors.New("unexpected text after string")
+		}
+		return strconv.Unquote(s[:end+1])
+	case strings.HasPrefix(s, "'"):
+		end := strings.Index(s[1:], "'")
+		if end < 0 {
+			return "", errors.New("unterminated string")
+		}
+		if rest := strings.TrimSpace(s[end+2:]); rest != "" && !strings.HasPrefix(rest, "#") {
+			return "", errors.New("unexpected text after string")
+		}
+		return s[1 : end+1], nil
+	}
+
+	s = stripComment(s)
+	if s == "" || strings.ContainsAny(s, " \t[]{}") {
+		return "", errors.New("expected a string, number or boolean")
+	}
+	return s, nil
+}
+
+// closingQuote индекс закрывающей кавычки строки в "...", с учётом \"
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+func stripComment(s string) string {
+	if i := strings.Index(s, "#"); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+func validKey(s string) bool {
+	for _, c := range s {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '.' || c == '-') {
+			return false
+		}
+	}
+	return s != ""
+}
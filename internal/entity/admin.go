@@ -0,0 +1,60 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	StatusActive    = "active"
+	StatusSuspended = "suspended"
+	StatusBanned    = "banned"
+)
+
+const (
+	AuditLogin              = "login"
+	AuditUserSuspended      = "user_suspended"
+	AuditUserBanned         = "user_banned"
+	AuditUserReinstated     = "user_reinstated"
+	AuditPasswordResetForce = "password_reset_forced"
+	AuditPasswordReset      = "password_reset"
+)
+
+// Sanction блокировка пользователя администратором. Until == nil значит бессрочно.
+type Sanction struct {
+	Status  string
+	Reason  string
+	Until   *time.Time
+	AdminID int64
+}
+
+// AccountFilter параметры списка пользователей для администратора
+type AccountFilter struct {
+	Query  string
+	Status string
+	Limit  int
+	Offset int
+}
+
+// Account пользователь со служебной информацией, видной только администратору
+type Account struct {
+	Users               User
+	LastSeenAt          *time.Time
+	DeletionRequestedAt *time.Time
+}
+
+type UserActivity struct {
+	Sessions []Session
+	Events   []AuditEvent
+	Ratings  []MovieWithRating
+}
+
+type PasswordReset struct {
+	UserID    int64
+	TokenHash string
+	ExpiresAt time.Time
+}
+
+var ErrAccountSuspended error = errors.New("account suspended")
+
+var ErrPasswordResetRequired error = errors.New("password reset required")
@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+const (
+	AuditAccountLocked = "account_locked"
+	AuditIPLocked      = "ip_locked"
+)
+
+type AuditEvent struct {
+	ID        int64
+	UserID    int64 // 0 если пользователь неизвестен
+	IP        string
+	Event     string
+	Details   string
+	CreatedAt time.Time
+}
@@ -0,0 +1,7 @@
+package entity
+
+import "errors"
+
+var ErrCannotBlockSelf error = errors.New("cannot block or mute yourself")
+
+var ErrBlocked error = errors.New("user is blocked")
@@ -0,0 +1,19 @@
+package entity
+
+import "time"
+
+// UserExport все персональные данные пользователя для выгрузки по GDPR
+type UserExport struct {
+	Users      User
+	Privacy    Privacy
+	Ratings    []MovieWithRating
+	Following  []int64
+	Followers  []int64
+	Blocked    []int64
+	Muted      []int64
+	Sessions   []Session
+	Identities []string
+	Handles    []string // прежние ники
+	Audit      []AuditEvent
+	ExportedAt time.Time
+}
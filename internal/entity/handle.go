@@ -0,0 +1,50 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	MinHandleLength = 3
+	MaxHandleLength = 30
+)
+
+var ErrInvalidHandle error = errors.New("handle must be 3-30 latin letters, digits or underscores and start with a letter")
+
+var ErrHandleReserved error = errors.New("handle is reserved")
+
+var ErrHandleTaken error = errors.New("handle already in use")
+
+// Ники, которые совпадают с путями API или выдают себя за администрацию
+var reservedHandles = map[string]bool{
+	"admin": true, "administrator": true, "root": true, "system": true, "moderator": true,
+	"staff": true, "support": true, "help": true, "official": true, "security": true,
+	"me": true, "user": true, "users": true, "movie": true, "movies": true, "ratings": true,
+	"search": true, "nearby": true, "images": true, "locations": true, "api": true,
+	"auth": true, "login": true, "logout": true, "settings": true, "null": true, "undefined": true,
+}
+
+// NormalizeHandle проверяет ник. Ведущий @ отбрасывается, регистр сохраняется:
+// сравниваются ники без учёта регистра.
+func NormalizeHandle(s string) (string, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "@")
+	if len(s) < MinHandleLength || len(s) > MaxHandleLength {
+		return "", ErrInvalidHandle
+	}
+
+	for i, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case i > 0 && (c >= '0' && c <= '9' || c == '_'):
+		default:
+			return "", ErrInvalidHandle
+		}
+	}
+
+	if reservedHandles[strings.ToLower(s)] {
+		return "", ErrHandleReserved
+	}
+
+	return s, nil
+}
@@ -0,0 +1,46 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeHandle(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		err  error
+	}{
+		{"kino_fan", "kino_fan", nil},
+		{"@KinoFan", "KinoFan", nil},
+		{"  @neo  ", "neo", nil},
+		{"abc", "abc", nil},
+		{"a1_", "a1_", nil},
+		{strings.Repeat("a", MaxHandleLength), strings.Repeat("a", MaxHandleLength), nil},
+
+		{"ab", "", ErrInvalidHandle},
+		{"@ab", "", ErrInvalidHandle},
+		{strings.Repeat("a", MaxHandleLength+1), "", ErrInvalidHandle},
+		{"1abc", "", ErrInvalidHandle},
+		{"_abc", "", ErrInvalidHandle},
+		{"@@abc", "", ErrInvalidHandle},
+		{"ab@c", "", ErrInvalidHandle},
+		{"ab-c", "", ErrInvalidHandle},
+		{"ab c", "", ErrInvalidHandle},
+		{"кино", "", ErrInvalidHandle},
+		{"", "", ErrInvalidHandle},
+
+		{"admin", "", ErrHandleReserved},
+		{"@Admin", "", ErrHandleReserved},
+		{"USERS", "", ErrHandleReserved},
+		{"movies", "", ErrHandleReserved},
+		{"undefined", "", ErrHandleReserved},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeHandle(tt.in)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("NormalizeHandle(%q) = %q, %v, want %q, %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
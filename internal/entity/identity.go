@@ -0,0 +1,17 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+// OIDCLogin начатый вход через внешнего провайдера, живёт до возврата на callback
+type OIDCLogin struct {
+	State     string
+	Provider  string
+	Nonce     string
+	Verifier  string
+	ExpiresAt time.Time
+}
+
+var ErrEmailNotVerified error = errors.New("email is not verified by the provider")
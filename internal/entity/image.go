@@ -0,0 +1,16 @@
+package entity
+
+import "errors"
+
+// Image загруженная картинка, Hash sha256 оригинала в hex
+type Image struct {
+	Hash        string
+	ContentType string
+	Width       int
+	Height      int
+	Size        int64
+}
+
+var ErrUnsupportedImage error = errors.New("unsupported image format, use JPEG, PNG or GIF")
+
+var ErrImageTooLarge error = errors.New("image is too large")
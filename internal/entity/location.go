@@ -0,0 +1,44 @@
+package entity
+
+import "errors"
+
+// Country страна по ISO 3166-1 alpha-2, Name на языке запроса
+type Country struct {
+	Code string
+	Name string
+}
+
+// City город из справочника GeoNames, ID совпадает с geonameid
+type City struct {
+	ID          int64
+	Name        string
+	ASCIIName   string
+	Country     string
+	CountryName string
+	Latitude    float64
+	Longitude   float64
+	Population  int64
+}
+
+func (c City) Point() GeoPoint {
+	return GeoPoint{Latitude: c.Latitude, Longitude: c.Longitude}
+}
+
+type GeoPoint struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// PlaceName перевод названия страны или города для справочника
+type PlaceName struct {
+	ID   int64 // geonameid города; для стран пусто
+	Code string
+	Lang string
+	Name string
+}
+
+var ErrUnknownCountry error = errors.New("unknown country")
+
+var ErrCityNotFound error = errors.New("city not found")
+
+var ErrCityCountryMismatch error = errors.New("city is not in the given country")
@@ -7,6 +7,10 @@ type Movie struct {
 	Name        string
 	Year        int
 	Description string
+	Poster      string `json:"-"` // hash картинки, наружу отдаются ссылки
+	// Агрегаты по всем оценкам, включая обезличенные оценки удалённых аккаунтов
+	RatingsCount  int64   `json:"-"`
+	AverageRating float64 `json:"-"`
 }
 
 type MovieWithRating struct {
@@ -15,3 +19,4 @@ type MovieWithRating struct {
 }
 
 var ErrMovieNotFound error = errors.New("movie not found")
+var ErrMovieExists error = errors.New("movie with this title and year already exists")
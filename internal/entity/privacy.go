@@ -0,0 +1,20 @@
+package entity
+
+const (
+	VisibilityPublic    = "public"
+	VisibilityFollowers = "followers"
+	VisibilityPrivate   = "private"
+)
+
+func ValidVisibility(v string) bool {
+	return v == VisibilityPublic || v == VisibilityFollowers || v == VisibilityPrivate
+}
+
+// Privacy кому видны поля профиля и список оценок пользователя
+type Privacy struct {
+	Sex         string
+	DateOfBirth string
+	Country     string
+	City        string
+	Ratings     string
+}
@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+// Profile публичный профиль пользователя со статистикой
+type Profile struct {
+	Users         User
+	RatingsHidden bool
+	RatingsCount  int64
+	AverageRating float64
+	Followers     int64
+	Following     int64
+	TopMovies     []MovieWithRating
+}
+
+// UserPatch частичное обновление профиля, nil поля не меняются
+type UserPatch struct {
+	Name        *string
+	Surname     *string
+	Sex         *string
+	DateOfBirth *time.Time
+	Country     *string
+	CityID      *int64
+}
+
+var ErrCannotFollowSelf error = errors.New("cannot follow yourself")
@@ -0,0 +1,38 @@
+package entity
+
+// UserFilter параметры поиска пользователей. Пустые поля не участвуют в фильтрации.
+type UserFilter struct {
+	// Кто ищет: от него зависят видимые поля и схожесть вкусов
+	Viewer int64
+
+	MinAge  *int64
+	MaxAge  *int64
+	Sex     string
+	Country string
+	CityID  int64
+
+	// Оценка фильма MovieID в диапазоне MinRating..MaxRating
+	MovieID   int64
+	MinRating int64
+	MaxRating int64
+
+	// Схожесть вкусов с Viewer, от 0 до 1
+	WithSimilarity bool
+	MinSimilarity  *float64
+
+	// Пользователи, чей город не дальше RadiusKm от Near
+	Near     *GeoPoint
+	RadiusKm float64
+
+	Sort   string
+	Desc   bool
+	Limit  int
+	Offset int
+}
+
+type UserSearchResult struct {
+	Users      User
+	Rating     *int64
+	Similarity *float64
+	DistanceKm *float64
+}
@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+type Session struct {
+	ID         int64
+	UserID     int64
+	UserAgent  string
+	IP         string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	RevokedAt  *time.Time
+}
+
+type EmailChange struct {
+	UserID    int64
+	NewEmail  string
+	TokenHash string
+	ExpiresAt time.Time
+}
+
+var ErrSessionNotFound error = errors.New("session not found")
+
+var ErrInvalidToken error = errors.New("invalid or expired token")
+
+var ErrTOTPAlreadyEnabled error = errors.New("two-factor authentication already enabled")
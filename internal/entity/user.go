@@ -7,14 +7,46 @@ import (
 
 type User struct {
 	ID          int64
+	Handle      string // уникальный без учёта регистра, пустой если не выбран
 	Email       string
 	Password    string
 	Name        string
 	Surname     string
 	Sex         string
 	DateOfBirth time.Time
-	Country     string
+	AgeRange    string // видят другие пользователи вместо даты рождения
+	Country     string // ISO 3166-1 alpha-2
+	CountryName string
+	CityID      int64 // geonameid
 	City        string
+	Avatar      string // hash картинки
+	Role        string
+	TOTPSecret  string
+	TOTPEnabled bool
+
+	Status                string
+	StatusReason          string
+	StatusUntil           *time.Time
+	PasswordResetRequired bool
+}
+
+// Blocked заблокирован ли пользователь на момент now
+func (u User) Blocked(now time.Time) bool {
+	if u.Status == "" || u.Status == StatusActive {
+		return false
+	}
+	return u.StatusUntil == nil || u.StatusUntil.After(now)
+}
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Для этих ролей вход без второго фактора не даёт их привилегий
+func RoleRequiresMFA(role string) bool {
+	return role == RoleAdmin || role == RoleModerator
 }
 
 type UserWithRating struct {
@@ -24,6 +56,8 @@ type UserWithRating struct {
 
 var ErrUserNotFound error = errors.New("user not found")
 
+var ErrEmailTaken error = errors.New("email already in use")
+
 //  FOR TEST:
 //  "email": "test@gmail.com",
 //  "password": "test"
@@ -0,0 +1,130 @@
+// Package geonames читает выгрузки https://download.geonames.org/export/dump/:
+// countryInfo.txt, cities*.txt (или allCountries.txt) и alternateNamesV2.txt.
+// Все файлы в UTF-8, поля разделены табуляцией, строки с # комментарии.
+package geonames
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/marcokz/movie-final/internal/entity"
+)
+
+// Country строка countryInfo.txt. GeonameID нужен, чтобы найти переводы страны в alternateNames.
+type Country struct {
+	entity.Country
+	GeonameID int64
+}
+
+// AlternateName строка alternateNamesV2.txt
+type AlternateName struct {
+	GeonameID int64
+	Lang      string
+	Name      string
+	Preferred bool
+	Short     bool
+}
+
+// Строки бывают очень длинными (alternatenames у крупных городов)
+const maxLineSize = 1 << 20
+
+func scan(r io.Reader, minFields int, fn func(fields []string) error) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	line := 0
+	for s.Scan() {
+		line++
+		text := s.Text()
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, "\t")
+		if len(fields) < minFields {
+			return fmt.Errorf("geonames: line %d: expected at least %d fields, got %d", line, minFields, len(fields))
+		}
+		if err := fn(fields); err != nil {
+			return fmt.Errorf("geonames: line %d: %w", line, err)
+		}
+	}
+	return s.Err()
+}
+
+// ReadCountries читает countryInfo.txt.
+func ReadCountries(r io.Reader) ([]Country, error) {
+	var countries []Country
+
+	err := scan(r, 17, func(f []string) error {
+		id, err := strconv.ParseInt(f[16], 10, 64)
+		if err != nil {
+			return err
+		}
+		countries = append(countries, Country{
+			Country:   entity.Country{Code: f[0], Name: f[4]},
+			GeonameID: id,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return countries, nil
+}
+
+// ReadCities читает населённые пункты (feature class P) из таблицы geoname
+// и отдаёт их по одному в fn, чтобы не держать весь файл в памяти.
+func ReadCities(r io.Reader, fn func(entity.City) error) error {
+	return scan(r, 19, func(f []string) error {
+		if f[6] != "P" {
+			return nil
+		}
+
+		var c entity.City
+		var err error
+		if c.ID, err = strconv.ParseInt(f[0], 10, 64); err != nil {
+			return err
+		}
+		if c.Latitude, err = strconv.ParseFloat(f[4], 64); err != nil {
+			return err
+		}
+		if c.Longitude, err = strconv.ParseFloat(f[5], 64); err != nil {
+			return err
+		}
+		if f[14] != "" {
+			if c.Population, err = strconv.ParseInt(f[14], 10, 64); err != nil {
+				return err
+			}
+		}
+		c.Name, c.ASCIIName, c.Country = f[1], f[2], f[8]
+
+		return fn(c)
+	})
+}
+
+// ReadAlternateNames читает alternateNamesV2.txt, оставляя только языки из langs.
+// Разговорные и исторические названия пропускаются.
+func ReadAlternateNames(r io.Reader, langs map[string]bool, fn func(AlternateName) error) error {
+	return scan(r, 8, func(f []string) error {
+		if !langs[f[2]] || f[6] == "1" || f[7] == "1" {
+			return nil
+		}
+
+		id, err := strconv.ParseInt(f[1], 10, 64)
+		if err != nil {
+			return err
+		}
+
+		return fn(AlternateName{
+			GeonameID: id,
+			Lang:      f[2],
+			Name:      f[3],
+			Preferred: f[4] == "1",
+			Short:     f[5] == "1",
+		})
+	})
+}
@@ -0,0 +1,122 @@
+package geonames
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/marcokz/movie-final/internal/entity"
+)
+
+// row склеивает поля строки выгрузки через табуляцию
+func row(fields ...string) string {
+	return strings.Join(fields, "\t") + "\n"
+}
+
+func TestReadCities(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []entity.City
+		wantErr bool
+	}{
+		{
+			name: "cities only",
+			input: "# geonames dump\n\n" +
+				row("524901", "Москва", "Moscow", "MSK,Moskva", "55.75222", "37.61556", "P", "PPLC", "RU", "", "48", "", "", "", "10381222", "", "144", "Europe/Moscow", "2022-12-10") +
+				row("2017370", "Russia", "Russia", "", "60", "100", "A", "PCLI", "RU", "", "00", "", "", "", "140702000", "", "125", "Europe/Moscow", "2022-08-17") +
+				row("8133876", "Хутор", "Khutor", "", "-1.5", "-70.25", "P", "PPL", "RU", "", "", "", "", "", "", "", "10", "", "2012-01-18"),
+			want: []entity.City{
+				{ID: 524901, Name: "Москва", ASCIIName: "Moscow", Country: "RU", Latitude: 55.75222, Longitude: 37.61556, Population: 10381222},
+				{ID: 8133876, Name: "Хутор", ASCIIName: "Khutor", Country: "RU", Latitude: -1.5, Longitude: -70.25},
+			},
+		},
+		{
+			name:    "too few fields",
+			input:   row("524901", "Moscow", "Moscow"),
+			wantErr: true,
+		},
+		{
+			name:    "bad latitude",
+			input:   row("524901", "Moscow", "Moscow", "", "north", "37.6", "P", "PPLC", "RU", "", "", "", "", "", "1", "", "", "", ""),
+			wantErr: true,
+		},
+		{
+			name:    "bad population",
+			input:   row("524901", "Moscow", "Moscow", "", "55.7", "37.6", "P", "PPLC", "RU", "", "", "", "", "", "many", "", "", "", ""),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		var got []entity.City
+		err := ReadCities(strings.NewReader(tt.input), func(c entity.City) error {
+			got = append(got, c)
+			return nil
+		})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: cities = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReadCitiesCallbackError(t *testing.T) {
+	input := row("524901", "Moscow", "Moscow", "", "55.7", "37.6", "P", "PPLC", "RU", "", "", "", "", "", "1", "", "", "", "") +
+		row("498817", "Saint Petersburg", "Saint Petersburg", "", "59.9", "30.3", "P", "PPLA", "RU", "", "", "", "", "", "1", "", "", "", "")
+	stop := errors.New("stop")
+
+	calls := 0
+	err := ReadCities(strings.NewReader(input), func(entity.City) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("err = %v after %d calls, want stop after 1", err, calls)
+	}
+	if err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("err = %v, want the line number", err)
+	}
+}
+
+func TestReadAlternateNames(t *testing.T) {
+	input := "# alternateNamesV2\n" +
+		row("1", "524901", "ru", "Москва", "1", "", "", "", "", "") +
+		row("2", "524901", "en", "Moscow", "1", "", "", "", "", "") +
+		row("3", "524901", "de", "Moskau", "", "", "", "", "", "") +
+		row("4", "524901", "ru", "Белокаменная", "", "", "1", "", "", "") +
+		row("5", "524901", "ru", "Московь", "", "", "", "1", "", "") +
+		row("6", "2017370", "ru", "РФ", "", "1", "", "", "", "") +
+		row("7", "2017370", "link", "https://ru.wikipedia.org/wiki/Россия", "", "", "", "", "", "")
+
+	var got []AlternateName
+	err := ReadAlternateNames(strings.NewReader(input), map[string]bool{"ru": true, "en": true}, func(n AlternateName) error {
+		got = append(got, n)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Немецкое название и служебный link отброшены языком, разговорное и историческое флагами
+	want := []AlternateName{
+		{GeonameID: 524901, Lang: "ru", Name: "Москва", Preferred: true},
+		{GeonameID: 524901, Lang: "en", Name: "Moscow", Preferred: true},
+		{GeonameID: 2017370, Lang: "ru", Name: "РФ", Short: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("names = %+v, want %+v", got, want)
+	}
+
+	err = ReadAlternateNames(strings.NewReader(row("1", "x", "ru", "Москва", "", "", "", "")), map[string]bool{"ru": true}, func(AlternateName) error { return nil })
+	if err == nil {
+		t.Error("bad geonameid must fail")
+	}
+	err = ReadAlternateNames(strings.NewReader(row("1", "524901", "ru", "Москва")), map[string]bool{"ru": true}, func(AlternateName) error { return nil })
+	if err == nil {
+		t.Error("short line must fail")
+	}
+}
@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/marcokz/movie-final/internal/auth"
+	"github.com/marcokz/movie-final/internal/middleware"
+	"github.com/marcokz/movie-final/internal/problem"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// AccountDeletionGrace сколько ждём перед окончательным удалением аккаунта.
+// Вход в аккаунт в этот срок отменяет удаление.
+const AccountDeletionGrace = time.Hour * 24 * 30
+
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+func (h *UserHandler) DeleteMe(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, r, problem.Unauthenticated)
+		return
+	}
+
+	var req DeleteAccountRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	u, err := h.userRepo.GetUserByID(r.Context(), claims.ID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	// У пользователей, вошедших через внешнего провайдера, пароля нет
+	if u.Password != "" {
+		err = bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(req.Password))
+		if err != nil {
+			problem.Write(w, r, problem.New(http.StatusUnauthorized, "invalid_credentials", "invalid password"))
+			return
+		}
+	}
+
+	err = h.userRepo.RequestDeletion(r.Context(), claims.ID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	err = h.sessionsRepo.RevokeAllSessions(r.Context(), claims.ID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:    "auth_token",
+		Expires: time.Now().Add(-time.Hour),
+		Path:    "/",
+	})
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{
+		"message":    "account scheduled for deletion, log in again to cancel",
+		"deletionat": time.Now().Add(AccountDeletionGrace).Format(time.RFC3339),
+	})
+}
+
+// ExportMe отдаёт zip архив со всеми персональными данными пользователя (JSON и CSV).
+func (h *UserHandler) ExportMe(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, r, problem.Unauthenticated)
+		return
+	}
+
+	e, err := h.userRepo.ExportUserData(r.Context(), claims.ID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="movie-final-export-%d.zip"`, claims.ID))
+	w.WriteHeader(http.StatusOK)
+
+	// Заголовки уже отправлены, при ошибке остаётся только оборвать архив
+	zw := zip.NewWriter(w)
+	defer zw.Close()
+
+	profile := MeResponse{
+		User:        userResponse(e.Users),
+		Email:       e.Users.Email,
+		Role:        e.Users.Role,
+		TOTPEnabled: e.Users.TOTPEnabled,
+	}
+
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", profile},
+		{"privacy.json", PrivacySettings(e.Privacy)},
+		{"ratings.json", e.Ratings},
+		{"follows.json", map[string][]int64{"following": e.Following, "followers": e.Followers}},
+		{"blocks.json", map[string][]int64{"blocked": e.Blocked, "muted": e.Muted}},
+		{"sessions.json", e.Sessions},
+		{"identities.json", e.Identities},
+		{"handles.json", e.Handles},
+		{"security_events.json", e.Audit},
+	}
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			return
+		}
+	}
+
+	ratings := [][]string{{"movie_id", "movie_name", "year", "rating"}}
+	for _, m := range e.Ratings {
+		ratings = append(ratings, []string{
+			strconv.FormatInt(m.Movies.ID, 10), m.Movies.Name, strconv.Itoa(m.Movies.Year), strconv.FormatInt(m.Rating, 10),
+		})
+	}
+
+	sessions := [][]string{{"id", "user_agent", "ip", "created_at", "last_seen_at", "revoked_at"}}
+	for _, s := range e.Sessions {
+		revoked := ""
+		if s.RevokedAt != nil {
+			revoked = s.RevokedAt.Format(time.RFC3339)
+		}
+		sessions = append(sessions, []string{
+			strconv.FormatInt(s.ID, 10), s.UserAgent, s.IP, s.CreatedAt.Format(time.RFC3339), s.LastSeenAt.Format(time.RFC3339), revoked,
+		})
+	}
+
+	for name, records := range map[string][][]string{"ratings.csv": ratings, "sessions.csv": sessions} {
+		fw, err := zw.Create(name)
+		if err != nil {
+			return
+		}
+		if err := csv.NewWriter(fw).WriteAll(records); err != nil {
+			return
+		}
+	}
+}
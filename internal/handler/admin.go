@@ -0,0 +1,310 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/marcokz/movie-final/internal/auth"
+	"github.com/marcokz/movie-final/internal/entity"
+	"github.com/marcokz/movie-final/internal/middleware"
+	"github.com/marcokz/movie-final/internal/problem"
+)
+
+type AdminRepo interface {
+	ListAccounts(ctx context.Context, f entity.AccountFilter) ([]entity.Account, int, error)
+	SetStatus(ctx context.Context, userid int64, s entity.Sanction) error
+	RequirePasswordReset(ctx context.Context, r entity.PasswordReset) (string, error)
+	GetUserActivity(ctx context.Context, userid int64, limit int) (entity.UserActivity, error)
+}
+
+const (
+	passwordResetTTL  = time.Hour * 24
+	userActivityLimit = 50
+	defaultAdminLimit = 50
+	maxAdminListLimit = 200
+)
+
+type AdminHandler struct {
+	adminRepo AdminRepo
+	auditRepo AuditRepo
+	mailer    Mailer
+}
+
+func NewAdminHandler(a AdminRepo, au AuditRepo, m Mailer) *AdminHandler {
+	return &AdminHandler{adminRepo: a, auditRepo: au, mailer: m}
+}
+
+type AccountResponse struct {
+	ID                    int64
+	Email                 string
+	Name                  string
+	Surname               string
+	Role                  string
+	Status                string
+	StatusReason          string `json:",omitempty"`
+	StatusUntil           string `json:",omitempty"`
+	PasswordResetRequired bool
+	TOTPEnabled           bool
+	LastSeenAt            string `json:",omitempty"`
+	DeletionRequestedAt   string `json:",omitempty"`
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// ListUsers параметры: q (email, имя или фамилия), status, limit, offset.
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	_, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, r, problem.Unauthenticated)
+		return
+	}
+
+	q := r.URL.Query()
+	f := entity.AccountFilter{
+		Query:  q.Get("q"),
+		Status: q.Get("status"),
+		Limit:  defaultAdminLimit,
+	}
+	switch f.Status {
+	case "", entity.StatusActive, entity.StatusSuspended, entity.StatusBanned:
+	default:
+		problem.Write(w, r, problem.Validation(problem.Invalid("status", "status must be active, suspended or banned")))
+		return
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxAdminListLimit {
+			problem.Write(w, r, problem.Validation(problem.Invalid("limit", "limit from 1 to "+strconv.Itoa(maxAdminListLimit))))
+			return
+		}
+		f.Limit = n
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			problem.Write(w, r, problem.Validation(problem.Invalid("offset", "offset must be a non-negative integer")))
+			return
+		}
+		f.Offset = n
+	}
+
+	accounts, total, err := h.adminRepo.ListAccounts(r.Context(), f)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	resp := make([]AccountResponse, 0, len(accounts))
+	for _, a := range accounts {
+		status := a.Users.Status
+		if !a.Users.Blocked(time.Now()) {
+			status = entity.StatusActive
+		}
+		resp = append(resp, AccountResponse{
+			ID:                    a.Users.ID,
+			Email:                 a.Users.Email,
+			Name:                  a.Users.Name,
+			Surname:               a.Users.Surname,
+			Role:                  a.Users.Role,
+			Status:                status,
+			StatusReason:          a.Users.StatusReason,
+			StatusUntil:           formatTime(a.Users.StatusUntil),
+			PasswordResetRequired: a.Users.PasswordResetRequired,
+			TOTPEnabled:           a.Users.TOTPEnabled,
+			LastSeenAt:            formatTime(a.LastSeenAt),
+			DeletionRequestedAt:   formatTime(a.DeletionRequestedAt),
+		})
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{"users": resp, "total": total, "limit": f.Limit, "offset": f.Offset})
+}
+
+type SanctionRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+	Until  string `json:"until" validate:"datetime"` // RFC 3339, пусто значит бессрочно
+}
+
+func (h *AdminHandler) SuspendUser(w http.ResponseWriter, r *http.Request) {
+	h.sanction(w, r, entity.StatusSuspended)
+}
+
+func (h *AdminHandler) BanUser(w http.ResponseWriter, r *http.Request) {
+	h.sanction(w, r, entity.StatusBanned)
+}
+
+func (h *AdminHandler) ReinstateUser(w http.ResponseWriter, r *http.Request) {
+	h.sanction(w, r, entity.StatusActive)
+}
+
+func (h *AdminHandler) sanction(w http.ResponseWriter, r *http.Request, status string) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, r, problem.Unauthenticated)
+		return
+	}
+
+	pathValue := r.PathValue("id")
+	id, err := strconv.ParseInt(pathValue, 10, 64)
+	if err != nil {
+		problem.Write(w, r, problem.Validation(problem.Invalid("id", "id must be an integer")))
+		return
+	}
+
+	if id == claims.ID {
+		problem.Write(w, r, problem.BadRequest("cannot change your own status"))
+		return
+	}
+
+	s := entity.Sanction{Status: status, AdminID: claims.ID}
+
+	if status != entity.StatusActive {
+		var req SanctionRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+		s.Reason = req.Reason
+
+		if req.Until != "" {
+			until, err := time.Parse(time.RFC3339, req.Until)
+			if err != nil || !until.After(time.Now()) {
+				problem.Write(w, r, problem.Validation(problem.Invalid("until", "until must be a future RFC 3339 time")))
+				return
+			}
+			s.Until = &until
+		}
+		// Отстранение всегда временное, бессрочным бывает только бан
+		if status == entity.StatusSuspended && s.Until == nil {
+			problem.Write(w, r, problem.Validation(problem.FieldError{Field: "until", Code: "required", Message: "until is required for suspension"}))
+			return
+		}
+	}
+
+	err = h.adminRepo.SetStatus(r.Context(), id, s)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	event := map[string]string{
+		entity.StatusSuspended: entity.AuditUserSuspended,
+		entity.StatusBanned:    entity.AuditUserBanned,
+		entity.StatusActive:    entity.AuditUserReinstated,
+	}[status]
+	h.audit(r, id, event, fmt.Sprintf("admin=%d reason=%q until=%s", claims.ID, s.Reason, formatTime(s.Until)))
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "user status updated"})
+}
+
+func (h *AdminHandler) ForcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, r, problem.Unauthenticated)
+		return
+	}
+
+	pathValue := r.PathValue("id")
+	id, err := strconv.ParseInt(pathValue, 10, 64)
+	if err != nil {
+		problem.Write(w, r, problem.Validation(problem.Invalid("id", "id must be an integer")))
+		return
+	}
+
+	token, tokenHash, err := auth.NewToken()
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	email, err := h.adminRepo.RequirePasswordReset(r.Context(), entity.PasswordReset{
+		UserID:    id,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+	})
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	err = h.mailer.Send(r.Context(), email, "Password reset required", "Your password reset code: "+token)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	h.audit(r, id, entity.AuditPasswordResetForce, fmt.Sprintf("admin=%d", claims.ID))
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "password reset required, email sent"})
+}
+
+type ActivityResponse struct {
+	Sessions []SessionResponse
+	Events   []entity.AuditEvent
+	Ratings  []ProfileMovie
+}
+
+func (h *AdminHandler) GetUserActivity(w http.ResponseWriter, r *http.Request) {
+	_, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, r, problem.Unauthenticated)
+		return
+	}
+
+	pathValue := r.PathValue("id")
+	id, err := strconv.ParseInt(pathValue, 10, 64)
+	if err != nil {
+		problem.Write(w, r, problem.Validation(problem.Invalid("id", "id must be an integer")))
+		return
+	}
+
+	a, err := h.adminRepo.GetUserActivity(r.Context(), id, userActivityLimit)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	resp := ActivityResponse{
+		Sessions: make([]SessionResponse, 0, len(a.Sessions)),
+		Events:   a.Events,
+		Ratings:  make([]ProfileMovie, 0, len(a.Ratings)),
+	}
+	for _, s := range a.Sessions {
+		resp.Sessions = append(resp.Sessions, SessionResponse{
+			ID:         s.ID,
+			UserAgent:  s.UserAgent,
+			IP:         s.IP,
+			CreatedAt:  s.CreatedAt.Format(time.RFC3339),
+			LastSeenAt: s.LastSeenAt.Format(time.RFC3339),
+		})
+	}
+	for _, m := range a.Ratings {
+		resp.Ratings = append(resp.Ratings, ProfileMovie{ID: m.Movies.ID, Name: m.Movies.Name, Year: m.Movies.Year, Rating: m.Rating})
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *AdminHandler) audit(r *http.Request, userid int64, event, details string) {
+	err := h.auditRepo.RecordEvent(r.Context(), entity.AuditEvent{
+		UserID:  userid,
+		IP:      clientIP(r),
+		Event:   event,
+		Details: details,
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "record audit event", "err", err)
+	}
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/marcokz/movie-final/internal/auth"
+	"github.com/marcokz/movie-final/internal/entity"
+	"github.com/marcokz/movie-final/internal/middleware"
+	"github.com/marcokz/movie-final/internal/problem"
+)
+
+type BlocksRepo interface {
+	Block(ctx context.Context, blockerid, blockedid int64) error
+	Unblock(ctx context.Context, blockerid, blockedid int64) error
+	Mute(ctx context.Context, muterid, mutedid int64) error
+	Unmute(ctx context.Context, muterid, mutedid int64) error
+	GetBlocked(ctx context.Context, userid int64) ([]entity.User, error)
+	GetMuted(ctx context.Context, userid int64) ([]entity.User, error)
+}
+
+// BlocksHandler блокировка и заглушение пользователей. Блокировка взаимная и
+// снимает подписки, заглушение скрывает пользователя только от того, кто заглушил.
+type BlocksHandler struct {
+	blocksRepo BlocksRepo
+}
+
+func NewBlocksHandler(b BlocksRepo) *BlocksHandler {
+	return &BlocksHandler{blocksRepo: b}
+}
+
+func (h *BlocksHandler) Block(w http.ResponseWriter, r *http.Request) {
+	h.change(w, r, h.blocksRepo.Block, "blocked")
+}
+
+func (h *BlocksHandler) Unblock(w http.ResponseWriter, r *http.Request) {
+	h.change(w, r, h.blocksRepo.Unblock, "unblocked")
+}
+
+func (h *BlocksHandler) Mute(w http.ResponseWriter, r *http.Request) {
+	h.change(w, r, h.blocksRepo.Mute, "muted")
+}
+
+func (h *BlocksHandler) Unmute(w http.ResponseWriter, r *http.Request) {
+	h.change(w, r, h.blocksRepo.Unmute, "unmuted")
+}
+
+func (h *BlocksHandler) GetBlocks(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, h.blocksRepo.GetBlocked)
+}
+
+func (h *BlocksHandler) GetMutes(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, h.blocksRepo.GetMuted)
+}
+
+func (h *BlocksHandler) change(w http.ResponseWriter, r *http.Request, apply func(ctx context.Context, userid, targetid int64) error, message string) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, r, problem.Unauthenticated)
+		return
+	}
+
+	pathValue := r.PathValue("id")
+	id, err := strconv.ParseInt(pathValue, 10, 64)
+	if err != nil {
+		problem.Write(w, r, problem.Validation(problem.Invalid("id", "id must be an integer")))
+		return
+	}
+
+	err = apply(r.Context(), claims.ID, id)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+func (h *BlocksHandler) list(w http.ResponseWriter, r *http.Request, get func(ctx context.Context, userid int64) ([]entity.User, error)) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, r, problem.Unauthenticated)
+		return
+	}
+
+	users, err := get(r.Context(), claims.ID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	resp := make([]User, 0, len(users))
+	for _, u := range users {
+		resp = append(resp, userResponse(u))
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
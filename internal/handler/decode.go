@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/marcokz/movie-final/internal/problem"
+	"github.com/marcokz/movie-final/internal/validate"
+)
+
+// decodeJSON читает тело запроса в v и проверяет его по тегам validate.
+// При ошибке ответ уже записан и возвращается false.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		problem.Write(w, r, problem.InvalidBody(err))
+		return false
+	}
+	if err := validate.Struct(v); err != nil {
+		problem.Write(w, r, err)
+		return false
+	}
+	return true
+}
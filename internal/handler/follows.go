@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/marcokz/movie-final/internal/auth"
+	"github.com/marcokz/movie-final/internal/middleware"
+	"github.com/marcokz/movie-final/internal/problem"
+)
+
+type FollowsRepo interface {
+	Follow(ctx context.Context, followerid, followeeid int64) error
+	Unfollow(ctx context.Context, followerid, followeeid int64) error
+}
+
+type FollowsHandler struct {
+	followsRepo FollowsRepo
+}
+
+func NewFollowsHandler(f FollowsRepo) *FollowsHandler {
+	return &FollowsHandler{followsRepo: f}
+}
+
+func (h *FollowsHandler) Follow(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, r, problem.Unauthenticated)
+		return
+	}
+
+	pathValue := r.PathValue("id")
+	id, err := strconv.ParseInt(pathValue, 10, 64)
+	if err != nil {
+		problem.Write(w, r, problem.Validation(problem.Invalid("id", "id must be an integer")))
+		return
+	}
+
+	err = h.followsRepo.Follow(r.Context(), claims.ID, id)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "followed"})
+}
+
+func (h *FollowsHandler) Unfollow(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, r, problem.Unauthenticated)
+		return
+	}
+
+	pathValue := r.PathValue("id")
+	id, err := strconv.ParseInt(pathValue, 10, 64)
+	if err != nil {
+		problem.Write(w, r, problem.Validation(problem.Invalid("id", "id must be an integer")))
+		return
+	}
+
+	err = h.followsRepo.Unfollow(r.Context(), claims.ID, id)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "unfollowed"})
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/marcokz/movie-final/internal/auth"
+	"github.com/marcokz/movie-final/internal/entity"
+	"github.com/marcokz/movie-final/internal/middleware"
+	"github.com/marcokz/movie-final/internal/problem"
+)
+
+type HandleRequest struct {
+	Handle string `json:"handle" validate:"required"`
+}
+
+// SetHandle PUT /user/me/handle. Прежний ник продолжает вести на профиль.
+func (h *UserHandler) SetHandle(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, r, problem.Unauthenticated)
+		return
+	}
+
+	var req HandleRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	handle, err := entity.NormalizeHandle(req.Handle)
+	if err != nil {
+		problem.Write(w, r, problem.InvalidField("handle", err))
+		return
+	}
+
+	err = h.userRepo.SetHandle(r.Context(), claims.ID, handle)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"handle": handle})
+}
+
+// resolveHandle id пользователя по нику из /users/@{handle}. Для прежнего ника
+// возвращает и путь к профилю по текущему, туда нужно перенаправить.
+func (h *UserHandler) resolveHandle(r *http.Request, handle string) (id int64, redirect string, err error) {
+	id, current, err := h.userRepo.ResolveHandle(r.Context(), handle)
+	if err != nil {
+		return 0, "", err
+	}
+	if !strings.EqualFold(current, handle) {
+		return id, "/users/@" + url.PathEscape(current), nil
+	}
+	return id, "", nil
+}
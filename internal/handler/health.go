@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/marcokz/movie-final/internal/buildinfo"
+	"github.com/marcokz/movie-final/internal/problem"
+)
+
+type HealthRepo interface {
+	Ping(ctx context.Context) error
+	SchemaVersion(ctx context.Context) (int64, error)
+}
+
+// Сколько ждём базу в проверке готовности
+const readyCheckTimeout = 2 * time.Second
+
+// Health пробы для оркестратора. Перед остановкой сервис перестаёт быть
+// готовым, но продолжает обслуживать начатые запросы.
+type Health struct {
+	healthRepo    HealthRepo
+	schemaVersion int64
+	ready         atomic.Bool
+}
+
+// NewHealth schemaVersion версия миграций, под которую собран код
+func NewHealth(r HealthRepo, schemaVersion int64) *Health {
+	return &Health{healthRepo: r, schemaVersion: schemaVersion}
+}
+
+func (h *Health) SetReady(ready bool) {
+	h.ready.Store(ready)
+}
+
+// Alive GET /healthz: процесс жив, база не проверяется
+func (h *Health) Alive(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// ReadyResponse ответ готового сервиса. Неготовый отвечает 503 problem+json
+// с кодом service_unavailable и теми же полями database, schema_version, expected_schema.
+type ReadyResponse struct {
+	Status         string `json:"status"`
+	Database       string `json:"database,omitempty"`
+	SchemaVersion  int64  `json:"schema_version,omitempty"`
+	ExpectedSchema int64  `json:"expected_schema,omitempty"`
+}
+
+// Ready GET /readyz: сервис не останавливается, база отвечает и схема нужной версии
+func (h *Health) Ready(w http.ResponseWriter, r *http.Request) {
+	if !h.ready.Load() {
+		problem.Write(w, r, problem.Unavailable("service is shutting down"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readyCheckTimeout)
+	defer cancel()
+
+	notReady := func(detail string) *problem.Problem {
+		return problem.Unavailable(detail).With("expected_schema", h.schemaVersion)
+	}
+
+	if err := h.healthRepo.Ping(ctx); err != nil {
+		problem.Write(w, r, notReady("database is unavailable").With("database", "unavailable"))
+		return
+	}
+
+	version, err := h.healthRepo.SchemaVersion(ctx)
+	if err != nil {
+		problem.Write(w, r, notReady("schema version is unknown").With("database", "schema version unknown"))
+		return
+	}
+	if version != h.schemaVersion {
+		problem.Write(w, r, notReady("schema version does not match the code, run migrate up").With("database", "ok").With("schema_version", version))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ReadyResponse{Status: "ready", Database: "ok", SchemaVersion: version, ExpectedSchema: h.schemaVersion})
+}
+
+// Version GET /version
+func (h *Health) Version(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(buildinfo.Get())
+}
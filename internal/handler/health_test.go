@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/marcokz/movie-final/internal/problem"
+)
+
+type fakeHealthRepo struct {
+	pingErr    error
+	version    int64
+	versionErr error
+}
+
+func (f fakeHealthRepo) Ping(context.Context) error { return f.pingErr }
+
+func (f fakeHealthRepo) SchemaVersion(context.Context) (int64, error) {
+	return f.version, f.versionErr
+}
+
+func TestReady(t *testing.T) {
+	const expected = 20250125100000
+
+	tests := []struct {
+		name     string
+		repo     fakeHealthRepo
+		stopping bool
+		status   int
+		want     map[string]any
+	}{
+		{
+			name:   "ready",
+			repo:   fakeHealthRepo{version: expected},
+			status: http.StatusOK,
+			want:   map[string]any{"status": "ready", "database": "ok", "schema_version": float64(expected)},
+		},
+		{
+			name:     "shutting down",
+			repo:     fakeHealthRepo{version: expected},
+			stopping: true,
+			status:   http.StatusServiceUnavailable,
+			want:     map[string]any{"code": problem.CodeUnavailable, "detail": "service is shutting down"},
+		},
+		{
+			name:   "database unavailable",
+			repo:   fakeHealthRepo{pingErr: errors.New("connection refused")},
+			status: http.StatusServiceUnavailable,
+			want:   map[string]any{"code": problem.CodeUnavailable, "database": "unavailable", "expected_schema": float64(expected)},
+		},
+		{
+			name:   "schema version unknown",
+			repo:   fakeHealthRepo{versionErr: errors.New("no table")},
+			status: http.StatusServiceUnavailable,
+			want:   map[string]any{"code": problem.CodeUnavailable, "database": "schema version unknown"},
+		},
+		{
+			name:   "schema behind",
+			repo:   fakeHealthRepo{version: expected - 1},
+			status: http.StatusServiceUnavailable,
+			want:   map[string]any{"code": problem.CodeUnavailable, "database": "ok", "schema_version": float64(expected - 1)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHealth(tt.repo, expected)
+			h.SetReady(!tt.stopping)
+
+			rec := httptest.NewRecorder()
+			h.Ready(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.status != http.StatusOK && rec.Header().Get("Content-Type") != problem.ContentType {
+				t.Errorf("Content-Type = %q, want %q", rec.Header().Get("Content-Type"), problem.ContentType)
+			}
+			var body map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode %q: %v", rec.Body, err)
+			}
+			for k, v := range tt.want {
+				if body[k] != v {
+					t.Errorf("%s = %v, want %v", k, body[k], v)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/marcokz/movie-final/internal/blob"
+	"github.com/marcokz/movie-final/internal/entity"
+	"github.com/marcokz/movie-final/internal/images"
+	"github.com/marcokz/movie-final/internal/problem"
+)
+
+type ImagesRepo interface {
+	SaveImage(ctx context.Context, img entity.Image) error
+}
+
+const imagesPath = "/images/"
+
+// ImageURLs ссылки на оригинал и уменьшенные копии по имени варианта
+type ImageURLs struct {
+	URL        string
+	Thumbnails map[string]string
+}
+
+func imageURLs(hash string, kind images.Kind) *ImageURLs {
+	if hash == "" {
+		return nil
+	}
+	urls := &ImageURLs{URL: imagesPath + hash, Thumbnails: map[string]string{}}
+	for _, v := range kind.Variants {
+		urls.Thumbnails[v.Name] = imagesPath + images.VariantName(hash, v.Name)
+	}
+	return urls
+}
+
+// ImageUploader общая часть загрузки аватаров и постеров
+type ImageUploader struct {
+	store      *images.Store
+	imagesRepo ImagesRepo
+}
+
+func NewImageUploader(s *images.Store, i ImagesRepo) *ImageUploader {
+	return &ImageUploader{store: s, imagesRepo: i}
+}
+
+// upload принимает картинку телом запроса или полем file в multipart/form-data.
+// При ошибке ответ уже записан и ok == false.
+func (u *ImageUploader) upload(w http.ResponseWriter, r *http.Request, kind images.Kind) (img entity.Image, ok bool) {
+	var body io.Reader = r.Body
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		mr, err := r.MultipartReader()
+		if err != nil {
+			problem.Write(w, r, problem.BadRequest("malformed multipart body"))
+			return entity.Image{}, false
+		}
+		for {
+			part, err := mr.NextPart()
+			if err != nil {
+				problem.Write(w, r, problem.Validation(problem.Required("file")))
+				return entity.Image{}, false
+			}
+			if part.FormName() == "file" {
+				body = part
+				break
+			}
+		}
+	}
+
+	// Читаем на байт больше лимита, чтобы отличить файл ровно по лимиту от большего
+	data, err := io.ReadAll(io.LimitReader(body, kind.MaxBytes+1))
+	if err != nil {
+		problem.Write(w, r, problem.BadRequest("failed to read image"))
+		return entity.Image{}, false
+	}
+
+	img, err = u.store.Save(r.Context(), data, kind)
+	if err != nil {
+		problem.Write(w, r, err)
+		return entity.Image{}, false
+	}
+
+	if err := u.imagesRepo.SaveImage(r.Context(), img); err != nil {
+		problem.Write(w, r, err)
+		return entity.Image{}, false
+	}
+
+	return img, true
+}
+
+// ServeImage GET /images/{name}. Содержимое по имени никогда не меняется, поэтому кэшируем навсегда.
+func (u *ImageUploader) ServeImage(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	rc, err := u.store.Open(r.Context(), name)
+	if errors.Is(err, blob.ErrNotFound) {
+		problem.Write(w, r, problem.NotFound("image not found"))
+		return
+	}
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	defer rc.Close()
+
+	rs, ok := rc.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(rc)
+		if err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+		rs = bytes.NewReader(data)
+	}
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(rs, head)
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", http.DetectContentType(head[:n]))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("ETag", `"`+name+`"`)
+	http.ServeContent(w, r, name, time.Time{}, rs)
+}
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/marcokz/movie-final/internal/entity"
+	"github.com/marcokz/movie-final/internal/problem"
+)
+
+type LocationsRepo interface {
+	SearchCountries(ctx context.Context, prefix string, limit int) ([]entity.Country, error)
+	SearchCities(ctx context.Context, prefix, country string, limit int) ([]entity.City, error)
+	ResolveCountry(ctx context.Context, text string) (string, error)
+	GetCity(ctx context.Context, id int64) (entity.City, error)
+}
+
+const (
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 50
+)
+
+type LocationsHandler struct {
+	locationsRepo LocationsRepo
+}
+
+func NewLocationsHandler(l LocationsRepo) *LocationsHandler {
+	return &LocationsHandler{locationsRepo: l}
+}
+
+type CountryResponse struct {
+	Code string
+	Name string
+}
+
+type CityResponse struct {
+	ID          int64
+	Name        string
+	Country     string
+	CountryName string
+	Latitude    float64
+	Longitude   float64
+}
+
+// Countries подсказки для ввода страны. Параметры: q (начало названия или код), limit.
+// Названия на языке из lang или Accept-Language.
+func (h *LocationsHandler) Countries(w http.ResponseWriter, r *http.Request) {
+	limit, messageErr := suggestLimit(r)
+	if messageErr != "" {
+		problem.Write(w, r, problem.Validation(problem.Invalid("limit", messageErr)))
+		return
+	}
+
+	countries, err := h.locationsRepo.SearchCountries(r.Context(), r.URL.Query().Get("q"), limit)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	resp := make([]CountryResponse, 0, len(countries))
+	for _, c := range countries {
+		resp = append(resp, CountryResponse{Code: c.Code, Name: c.Name})
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Cities подсказки для ввода города. Параметры: q (начало названия), country (код или название), limit.
+func (h *LocationsHandler) Cities(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	if q.Get("q") == "" {
+		problem.Write(w, r, problem.Validation(problem.Required("q")))
+		return
+	}
+
+	limit, messageErr := suggestLimit(r)
+	if messageErr != "" {
+		problem.Write(w, r, problem.Validation(problem.Invalid("limit", messageErr)))
+		return
+	}
+
+	var country string
+	if v := q.Get("country"); v != "" {
+		code, err := h.locationsRepo.ResolveCountry(r.Context(), v)
+		if err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+		country = code
+	}
+
+	cities, err := h.locationsRepo.SearchCities(r.Context(), q.Get("q"), country, limit)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	resp := make([]CityResponse, 0, len(cities))
+	for _, c := range cities {
+		resp = append(resp, CityResponse{
+			ID:          c.ID,
+			Name:        c.Name,
+			Country:     c.Country,
+			CountryName: c.CountryName,
+			Latitude:    c.Latitude,
+			Longitude:   c.Longitude,
+		})
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func suggestLimit(r *http.Request) (int, string) {
+	v := r.URL.Query().Get("limit")
+	if v == "" {
+		return defaultSuggestLimit, ""
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > maxSuggestLimit {
+		return 0, "limit from 1 to " + strconv.Itoa(maxSuggestLimit)
+	}
+	return n, ""
+}
+
+// resolveLocation приводит страну к коду ISO и проверяет, что город из этой страны.
+// Если страна не указана, она берётся из города.
+func resolveLocation(ctx context.Context, l LocationsRepo, country string, cityid int64) (string, error) {
+	var code string
+	if country != "" {
+		var err error
+		if code, err = l.ResolveCountry(ctx, country); err != nil {
+			return "", err
+		}
+	}
+
+	if cityid == 0 {
+		return code, nil
+	}
+
+	city, err := l.GetCity(ctx, cityid)
+	if err != nil {
+		return "", err
+	}
+	if code != "" && code != city.Country {
+		return "", entity.ErrCityCountryMismatch
+	}
+
+	return city.Country, nil
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marcokz/movie-final/internal/entity"
+	"github.com/marcokz/movie-final/internal/middleware"
+	"github.com/marcokz/movie-final/internal/problem"
+)
+
+type LoginFailuresRepo interface {
+	LockedUntil(ctx context.Context, key string) (time.Time, error)
+	RegisterFailure(ctx context.Context, key string, window time.Duration) (int, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	ResetFailures(ctx context.Context, key string) error
+}
+
+type AuditRepo interface {
+	RecordEvent(ctx context.Context, e entity.AuditEvent) error
+}
+
+// Лимиты неудачных попыток входа. Сначала даём несколько бесплатных попыток,
+// дальше каждая ошибка удваивает блокировку до maxLockout.
+const (
+	accountFreeAttempts = 5
+	ipFreeAttempts      = 20
+	baseLockout         = time.Second * 30
+	maxLockout          = time.Hour
+	failureWindow       = time.Hour
+)
+
+type LoginGuard struct {
+	failuresRepo LoginFailuresRepo
+	auditRepo    AuditRepo
+}
+
+func NewLoginGuard(f LoginFailuresRepo, a AuditRepo) *LoginGuard {
+	return &LoginGuard{failuresRepo: f, auditRepo: a}
+}
+
+func accountKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// clientIP адрес клиента с учётом доверенных прокси (middleware.WithClientIP)
+func clientIP(r *http.Request) string {
+	return middleware.ClientIP(r)
+}
+
+// RetryAfter возвращает, сколько ещё ждать, если аккаунт или IP заблокированы.
+func (g *LoginGuard) RetryAfter(ctx context.Context, email, ip string) (time.Duration, error) {
+	var wait time.Duration
+	for _, key := range []string{accountKey(email), ipKey(ip)} {
+		until, err := g.failuresRepo.LockedUntil(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		if d := time.Until(until); d > wait {
+			wait = d
+		}
+	}
+	return wait, nil
+}
+
+func (g *LoginGuard) Fail(ctx context.Context, userid int64, email, ip string) error {
+	loginFailed.Inc()
+
+	// Email в журнал не пишем: журнал переживает удаление аккаунта
+	err := g.register(ctx, accountKey(email), accountFreeAttempts, entity.AuditEvent{
+		UserID: userid, IP: ip, Event: entity.AuditAccountLocked,
+	})
+	if err != nil {
+		return err
+	}
+
+	return g.register(ctx, ipKey(ip), ipFreeAttempts, entity.AuditEvent{
+		UserID: userid, IP: ip, Event: entity.AuditIPLocked,
+	})
+}
+
+func (g *LoginGuard) Success(ctx context.Context, email string) error {
+	return g.failuresRepo.ResetFailures(ctx, accountKey(email))
+}
+
+// Record пишет событие в журнал. Ошибка журнала не должна мешать входу, поэтому только логируем.
+func (g *LoginGuard) Record(ctx context.Context, e entity.AuditEvent) {
+	if err := g.auditRepo.RecordEvent(ctx, e); err != nil {
+		slog.ErrorContext(ctx, "record audit event", "err", err)
+	}
+}
+
+func (g *LoginGuard) register(ctx context.Context, key string, free int, event entity.AuditEvent) error {
+	failures, err := g.failuresRepo.RegisterFailure(ctx, key, failureWindow)
+	if err != nil {
+		return err
+	}
+	if failures <= free {
+		return nil
+	}
+
+	lockout := lockoutFor(failures - free)
+	if err := g.failuresRepo.Lock(ctx, key, time.Now().Add(lockout)); err != nil {
+		return err
+	}
+
+	event.Details = strings.TrimSpace(fmt.Sprintf("%s failures=%d lockout=%s", event.Details, failures, lockout))
+	if err := g.auditRepo.RecordEvent(ctx, event); err != nil {
+		slog.ErrorContext(ctx, "record audit event", "err", err)
+	}
+	return nil
+}
+
+func lockoutFor(n int) time.Duration {
+	d := time.Duration(float64(baseLockout) * math.Pow(2, float64(n-1)))
+	if d > maxLockout || d <= 0 {
+		return maxLockout
+	}
+	return d
+}
+
+func writeTooManyAttempts(w http.ResponseWriter, r *http.Request, wait time.Duration) {
+	loginLocked.Inc()
+	seconds := int(math.Ceil(wait.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	problem.Write(w, r, problem.New(http.StatusTooManyRequests, problem.CodeTooManyRequests, "too many login attempts").With("retry_after", seconds))
+}
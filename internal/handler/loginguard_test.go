@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marcokz/movie-final/internal/entity"
+)
+
+func TestLockoutFor(t *testing.T) {
+	tests := []struct {
+		n    int
+		want time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour}, // 64 минуты упираются в maxLockout
+		{20, time.Hour},
+		{100, time.Hour}, // переполнение не даёт нулевую блокировку
+	}
+	for _, tt := range tests {
+		if got := lockoutFor(tt.n); got != tt.want {
+			t.Errorf("lockoutFor(%d) = %s, want %s", tt.n, got, tt.want)
+		}
+	}
+}
+
+// memoryFailures счётчики неудач в памяти; окно не истекает
+type memoryFailures struct {
+	failures map[string]int
+	locked   map[string]time.Time
+	windows  []time.Duration
+}
+
+func newMemoryFailures() *memoryFailures {
+	return &memoryFailures{failures: map[string]int{}, locked: map[string]time.Time{}}
+}
+
+func (m *memoryFailures) LockedUntil(_ context.Context, key string) (time.Time, error) {
+	return m.locked[key], nil
+}
+
+func (m *memoryFailures) RegisterFailure(_ context.Context, key string, window time.Duration) (int, error) {
+	m.windows = append(m.windows, window)
+	m.failures[key]++
+	return m.failures[key], nil
+}
+
+func (m *memoryFailures) Lock(_ context.Context, key string, until time.Time) error {
+	m.locked[key] = until
+	return nil
+}
+
+func (m *memoryFailures) ResetFailures(_ context.Context, key string) error {
+	delete(m.failures, key)
+	delete(m.locked, key)
+	return nil
+}
+
+type recordedAudit struct{ events []entity.AuditEvent }
+
+func (a *recordedAudit) RecordEvent(_ context.Context, e entity.AuditEvent) error {
+	a.events = append(a.events, e)
+	return nil
+}
+
+func TestLoginGuard(t *testing.T) {
+	ctx := context.Background()
+	failures := newMemoryFailures()
+	audit := &recordedAudit{}
+	g := NewLoginGuard(failures, audit)
+
+	retryAfter := func(email, ip string) time.Duration {
+		t.Helper()
+		wait, err := g.RetryAfter(ctx, email, ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return wait
+	}
+
+	// Бесплатные попытки не блокируют
+	for i := 0; i < accountFreeAttempts; i++ {
+		if err := g.Fail(ctx, 1, "Ann@Example.com", "203.0.113.7"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if wait := retryAfter("ann@example.com", "203.0.113.7"); wait != 0 {
+		t.Fatalf("locked after %d free attempts: %s", accountFreeAttempts, wait)
+	}
+	for _, w := range failures.windows {
+		if w != failureWindow {
+			t.Fatalf("window = %s, want %s", w, failureWindow)
+		}
+	}
+
+	// Следующая блокирует аккаунт на baseLockout; регистр email не важен
+	if err := g.Fail(ctx, 1, "ann@example.com", "203.0.113.7"); err != nil {
+		t.Fatal(err)
+	}
+	if wait := retryAfter(" ANN@example.com ", "198.51.100.1"); wait <= baseLockout-time.Second || wait > baseLockout {
+		t.Errorf("account lockout = %s, want about %s", wait, baseLockout)
+	}
+	if len(audit.events) != 1 || audit.events[0].Event != entity.AuditAccountLocked || audit.events[0].UserID != 1 {
+		t.Errorf("audit = %+v, want one account_locked event", audit.events)
+	}
+	if len(audit.events) == 1 && strings.Contains(audit.events[0].Details, "ann@") {
+		t.Errorf("audit details %q contain the email", audit.events[0].Details)
+	}
+
+	// Ещё одна удваивает блокировку
+	if err := g.Fail(ctx, 1, "ann@example.com", "203.0.113.7"); err != nil {
+		t.Fatal(err)
+	}
+	if wait := retryAfter("ann@example.com", "198.51.100.1"); wait <= 2*baseLockout-time.Second {
+		t.Errorf("second lockout = %s, want about %s", wait, 2*baseLockout)
+	}
+
+	// IP ещё не заблокирован: другой аккаунт с того же адреса входит
+	if wait := retryAfter("bob@example.com", "203.0.113.7"); wait != 0 {
+		t.Errorf("other account locked by IP after %d failures: %s", accountFreeAttempts+2, wait)
+	}
+
+	// Успешный вход сбрасывает счётчик аккаунта
+	if err := g.Success(ctx, "ann@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if wait := retryAfter("ann@example.com", "198.51.100.1"); wait != 0 {
+		t.Errorf("lockout after success = %s", wait)
+	}
+	if err := g.Fail(ctx, 1, "ann@example.com", "203.0.113.7"); err != nil {
+		t.Fatal(err)
+	}
+	if wait := retryAfter("ann@example.com", "198.51.100.1"); wait != 0 {
+		t.Errorf("first failure after success locked the account: %s", wait)
+	}
+
+	// Перебор разных аккаунтов с одного адреса блокирует адрес
+	for i := 0; i < ipFreeAttempts; i++ {
+		if err := g.Fail(ctx, 0, "nobody@example.com", "198.51.100.9"); err != nil {
+			t.Fatal(err)
+		}
+		g.Success(ctx, "nobody@example.com")
+	}
+	if wait := retryAfter("carol@example.com", "198.51.100.9"); wait != 0 {
+		t.Fatalf("IP locked after %d free attempts", ipFreeAttempts)
+	}
+	if err := g.Fail(ctx, 0, "nobody@example.com", "198.51.100.9"); err != nil {
+		t.Fatal(err)
+	}
+	if wait := retryAfter("carol@example.com", "198.51.100.9"); wait == 0 {
+		t.Error("IP not locked")
+	}
+}
@@ -0,0 +1,13 @@
+package handler
+
+import "github.com/marcokz/movie-final/internal/metrics"
+
+// Бизнес-метрики, отдаются на GET /metrics вместе с HTTP и пулом соединений
+var (
+	loginSucceeded = metrics.Default.Counter("login_attempts_total", "Login attempts by result.", "result", "success")
+	loginFailed    = metrics.Default.Counter("login_attempts_total", "Login attempts by result.", "result", "failure")
+	loginLocked    = metrics.Default.Counter("login_attempts_total", "Login attempts by result.", "result", "locked")
+
+	ratingsWritten  = metrics.Default.Counter("ratings_written_total", "Ratings created or updated.")
+	usersRegistered = metrics.Default.Counter("users_registered_total", "Users registered with email and password.")
+)
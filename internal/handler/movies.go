@@ -3,13 +3,14 @@ package handler
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/marcokz/movie-final/internal/auth"
 	"github.com/marcokz/movie-final/internal/entity"
+	"github.com/marcokz/movie-final/internal/images"
 	"github.com/marcokz/movie-final/internal/middleware"
+	"github.com/marcokz/movie-final/internal/problem"
 )
 
 type MoviesRepo interface {
@@ -18,33 +19,59 @@ type MoviesRepo interface {
 	GetMoviesByID(ctx context.Context, id int64) (entity.Movie, error)
 	UpdateMovieByID(ctx context.Context, m entity.Movie) error
 	DeleteMovieByID(ctx context.Context, id int64) error
+	SetPoster(ctx context.Context, id int64, hash string) error
 }
 
 type MovieHandler struct {
 	moviesRepo MoviesRepo
+	images     *ImageUploader
 }
 
-func NewMovieHandler(m MoviesRepo) *MovieHandler {
-	return &MovieHandler{moviesRepo: m}
+func NewMovieHandler(m MoviesRepo, i *ImageUploader) *MovieHandler {
+	return &MovieHandler{moviesRepo: m, images: i}
 }
 
+type Movie struct {
+	ID          int64
+	Name        string
+	Year        int
+	Description string
+	Poster      *ImageURLs `json:",omitempty"`
+	// 0, если фильм ещё никто не оценил
+	RatingsCount  int64
+	AverageRating float64
+}
+
+func movieResponse(m entity.Movie) Movie {
+	return Movie{
+		ID:          m.ID,
+		Name:        m.Name,
+		Year:        m.Year,
+		Description: m.Description,
+		Poster:      imageURLs(m.Poster, images.Poster),
+
+		RatingsCount:  m.RatingsCount,
+		AverageRating: m.AverageRating,
+	}
+}
+
+// Первый фильм снят в 1888 году
 type MovieResponse struct {
-	Name        string `json:"name"`
-	Year        int    `json:"year"`
-	Description string `json:"description"`
+	Name        string `json:"name" validate:"required,max=255"`
+	Year        int    `json:"year" validate:"min=1888,max=2100"`
+	Description string `json:"description" validate:"max=10000"`
 }
 
 func (h *MovieHandler) CreateMovie(w http.ResponseWriter, r *http.Request) {
 	_, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
+		problem.Write(w, r, problem.Unauthenticated)
 		return
 	}
 
 	var create MovieResponse
 
-	if err := json.NewDecoder(r.Body).Decode(&create); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+	if !decodeJSON(w, r, &create) {
 		return
 	}
 
@@ -56,8 +83,7 @@ func (h *MovieHandler) CreateMovie(w http.ResponseWriter, r *http.Request) {
 
 	err := h.moviesRepo.CreateMovie(r.Context(), movie)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		problem.Write(w, r, err)
 		return
 	}
 
@@ -67,55 +93,54 @@ func (h *MovieHandler) CreateMovie(w http.ResponseWriter, r *http.Request) {
 func (h *MovieHandler) GetMovies(w http.ResponseWriter, r *http.Request) {
 	movies, err := h.moviesRepo.GetMovies(r.Context())
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		problem.Write(w, r, err)
 		return
 	}
 
+	resp := make([]Movie, 0, len(movies))
+	for _, m := range movies {
+		resp = append(resp, movieResponse(m))
+	}
+
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(movies)
+	json.NewEncoder(w).Encode(resp)
 }
 
 func (h *MovieHandler) GetMoviesByID(w http.ResponseWriter, r *http.Request) {
 	pathValue := r.PathValue("id")
 	id, err := strconv.ParseInt(pathValue, 10, 64)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		problem.Write(w, r, problem.Validation(problem.Invalid("id", "id must be an integer")))
 		return
 	}
 
 	m, err := h.moviesRepo.GetMoviesByID(r.Context(), id)
-	if errors.Is(err, entity.ErrMovieNotFound) {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
-		return
-	}
 	if err != nil {
-		w.Write([]byte(err.Error()))
+		problem.Write(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(m)
+	json.NewEncoder(w).Encode(movieResponse(m))
 }
 
 func (h *MovieHandler) UpdateMovieByID(w http.ResponseWriter, r *http.Request) {
 	_, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
+		problem.Write(w, r, problem.Unauthenticated)
 		return
 	}
 
 	pathValue := r.PathValue("id")
 	id, err := strconv.ParseInt(pathValue, 10, 64)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		problem.Write(w, r, problem.Validation(problem.Invalid("id", "id must be an integer")))
 		return
 	}
 
 	var update MovieResponse
 
-	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+	if !decodeJSON(w, r, &update) {
 		return
 	}
 
@@ -126,8 +151,8 @@ func (h *MovieHandler) UpdateMovieByID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	err = h.moviesRepo.UpdateMovieByID(r.Context(), m)
-	if errors.Is(err, entity.ErrMovieNotFound) { // ???? разве они совпадут?
-		w.WriteHeader(http.StatusNotFound)
+	if err != nil {
+		problem.Write(w, r, err)
 		return
 	}
 
@@ -138,23 +163,77 @@ func (h *MovieHandler) UpdateMovieByID(w http.ResponseWriter, r *http.Request) {
 func (h *MovieHandler) DeleteMovieByID(w http.ResponseWriter, r *http.Request) {
 	_, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
+		problem.Write(w, r, problem.Unauthenticated)
 		return
 	}
 
 	pathValue := r.PathValue("id")
 	id, err := strconv.ParseInt(pathValue, 10, 64)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		problem.Write(w, r, problem.Validation(problem.Invalid("id", "id must be an integer")))
 		return
 	}
 
 	err = h.moviesRepo.DeleteMovieByID(r.Context(), id)
-	if errors.Is(err, entity.ErrMovieNotFound) {
-		w.WriteHeader(http.StatusNotFound)
+	if err != nil {
+		problem.Write(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "movie delete successfully"})
 }
+
+// UploadPoster PUT /movies/{id}/poster, картинка телом запроса или полем file формы.
+func (h *MovieHandler) UploadPoster(w http.ResponseWriter, r *http.Request) {
+	_, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, r, problem.Unauthenticated)
+		return
+	}
+
+	pathValue := r.PathValue("id")
+	id, err := strconv.ParseInt(pathValue, 10, 64)
+	if err != nil {
+		problem.Write(w, r, problem.Validation(problem.Invalid("id", "id must be an integer")))
+		return
+	}
+
+	img, ok := h.images.upload(w, r, images.Poster)
+	if !ok {
+		return
+	}
+
+	err = h.moviesRepo.SetPoster(r.Context(), id, img.Hash)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(imageURLs(img.Hash, images.Poster))
+}
+
+func (h *MovieHandler) DeletePoster(w http.ResponseWriter, r *http.Request) {
+	_, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, r, problem.Unauthenticated)
+		return
+	}
+
+	pathValue := r.PathValue("id")
+	id, err := strconv.ParseInt(pathValue, 10, 64)
+	if err != nil {
+		problem.Write(w, r, problem.Validation(problem.Invalid("id", "id must be an integer")))
+		return
+	}
+
+	err = h.moviesRepo.SetPoster(r.Context(), id, "")
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "poster deleted"})
+}
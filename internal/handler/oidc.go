@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/marcokz/movie-final/internal/entity"
+	"github.com/marcokz/movie-final/internal/oidc"
+	"github.com/marcokz/movie-final/internal/problem"
+)
+
+type IdentitiesRepo interface {
+	CreateLogin(ctx context.Context, l entity.OIDCLogin) error
+	TakeLogin(ctx context.Context, state string) (entity.OIDCLogin, error)
+	LinkIdentity(ctx context.Context, provider, subject, email string, emailVerified bool) (int64, error)
+}
+
+// Сколько пользователь может провести на странице провайдера
+const oidcLoginTTL = time.Minute * 10
+
+// oidcStateCookie привязывает вход к браузеру, который его начал. Без неё ссылку
+// на callback с чужими code и state можно подсунуть жертве, и та войдёт в аккаунт
+// атакующего (login CSRF). SameSite=Lax: cookie нужна на редиректе от провайдера.
+const oidcStateCookie = "oidc_state"
+
+type OIDCHandler struct {
+	identitiesRepo IdentitiesRepo
+	providers      map[string]*oidc.Provider
+	users          *UserHandler
+}
+
+func NewOIDCHandler(i IdentitiesRepo, providers []*oidc.Provider, u *UserHandler) *OIDCHandler {
+	m := make(map[string]*oidc.Provider, len(providers))
+	for _, p := range providers {
+		m[p.Name()] = p
+	}
+	return &OIDCHandler{identitiesRepo: i, providers: m, users: u}
+}
+
+func (h *OIDCHandler) Providers(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, 0, len(h.providers))
+	for name := range h.providers {
+		names = append(names, name)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(names)
+}
+
+func (h *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.providers[r.PathValue("provider")]
+	if !ok {
+		problem.Write(w, r, problem.New(http.StatusNotFound, "unknown_provider", "unknown identity provider"))
+		return
+	}
+
+	login := entity.OIDCLogin{
+		Provider:  p.Name(),
+		ExpiresAt: time.Now().Add(oidcLoginTTL),
+	}
+	var err error
+	for _, v := range []*string{&login.State, &login.Nonce, &login.Verifier} {
+		if *v, err = oidc.RandomString(); err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+	}
+
+	authURL, err := p.AuthCodeURL(r.Context(), login.State, login.Nonce, login.Verifier)
+	if err != nil {
+		slog.WarnContext(r.Context(), "oidc auth url", "provider", p.Name(), "err", err)
+		problem.Write(w, r, problem.New(http.StatusBadGateway, "provider_unavailable", "identity provider is unavailable"))
+		return
+	}
+
+	if err := h.identitiesRepo.CreateLogin(r.Context(), login); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    login.State,
+		Path:     "/user/oidc/",
+		MaxAge:   int(oidcLoginTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.providers[r.PathValue("provider")]
+	if !ok {
+		problem.Write(w, r, problem.New(http.StatusNotFound, "unknown_provider", "unknown identity provider"))
+		return
+	}
+
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		problem.Write(w, r, problem.New(http.StatusUnauthorized, "provider_error", e))
+		return
+	}
+
+	// Cookie одноразовая, как и state
+	cookie, err := r.Cookie(oidcStateCookie)
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Path:     "/user/oidc/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(q.Get("state"))) != 1 {
+		problem.Write(w, r, problem.BadRequest("invalid state"))
+		return
+	}
+
+	login, err := h.identitiesRepo.TakeLogin(r.Context(), q.Get("state"))
+	if errors.Is(err, entity.ErrInvalidToken) || (err == nil && (login.Provider != p.Name() || time.Now().After(login.ExpiresAt))) {
+		problem.Write(w, r, problem.BadRequest("invalid state"))
+		return
+	}
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	token, err := p.Exchange(r.Context(), q.Get("code"), login.Verifier, login.Nonce)
+	if err != nil {
+		slog.WarnContext(r.Context(), "oidc code exchange", "provider", p.Name(), "err", err)
+		problem.Write(w, r, problem.Unauthorized("identity provider rejected the login"))
+		return
+	}
+
+	userid, err := h.identitiesRepo.LinkIdentity(r.Context(), p.Name(), token.Subject, token.Email, token.EmailVerified)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	u, err := h.users.userRepo.GetUserByID(r.Context(), userid)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	h.users.completeLogin(w, r, u)
+}
@@ -0,0 +1,397 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/marcokz/movie-final/internal/auth"
+	"github.com/marcokz/movie-final/internal/entity"
+	"github.com/marcokz/movie-final/internal/oidc"
+	"github.com/marcokz/movie-final/internal/oidc/oidctest"
+)
+
+const testRedirectURL = "http://app.test/user/oidc/mock/callback"
+
+type fakeIdentities struct {
+	mu     sync.Mutex
+	logins map[string]entity.OIDCLogin
+}
+
+func (f *fakeIdentities) CreateLogin(_ context.Context, l entity.OIDCLogin) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.logins[l.State] = l
+	return nil
+}
+
+func (f *fakeIdentities) TakeLogin(_ context.Context, state string) (entity.OIDCLogin, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	l, ok := f.logins[state]
+	if !ok {
+		return entity.OIDCLogin{}, entity.ErrInvalidToken
+	}
+	delete(f.logins, state)
+	return l, nil
+}
+
+func (f *fakeIdentities) LinkIdentity(context.Context, string, string, string, bool) (int64, error) {
+	return 1, nil
+}
+
+func (f *fakeIdentities) update(state string, fn func(*entity.OIDCLogin)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	l := f.logins[state]
+	fn(&l)
+	f.logins[state] = l
+}
+
+// Встроенные интерфейсы закрывают методы, которые вход не вызывает
+type fakeUserRepo struct{ UserRepo }
+
+func (fakeUserRepo) GetUserByID(_ context.Context, id int64) (entity.User, error) {
+	return entity.User{ID: id, Email: "dev@example.com", Role: entity.RoleUser}, nil
+}
+
+func (fakeUserRepo) CancelDeletion(context.Context, int64) (bool, error) {
+	return false, nil
+}
+
+type fakeSessionsRepo struct{ SessionsRepo }
+
+func (fakeSessionsRepo) CreateSession(context.Context, entity.Session) (int64, error) {
+	return 1, nil
+}
+
+type fakeAuditRepo struct{}
+
+func (fakeAuditRepo) RecordEvent(context.Context, entity.AuditEvent) error {
+	return nil
+}
+
+type oidcTest struct {
+	idp        *oidctest.Provider
+	identities *fakeIdentities
+	app        *http.ServeMux
+	// browserState — значение cookie oidc_state в браузере пользователя
+	browserState string
+}
+
+func newOIDCTest(t *testing.T) *oidcTest {
+	t.Helper()
+	if err := auth.SetJWTKey([]byte("0123456789abcdef0123456789abcdef")); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	idp, err := oidctest.New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux.Handle("/", idp.Handler())
+
+	provider := oidc.NewProvider(oidc.ProviderConfig{
+		Name:         "mock",
+		Issuer:       srv.URL,
+		ClientID:     "movie-final",
+		ClientSecret: "secret",
+		RedirectURL:  testRedirectURL,
+	})
+	identities := &fakeIdentities{logins: map[string]entity.OIDCLogin{}}
+	users := NewUserHandler(fakeUserRepo{}, fakeSessionsRepo{}, nil, NewLoginGuard(nil, fakeAuditRepo{}), nil, nil, nil)
+	h := NewOIDCHandler(identities, []*oidc.Provider{provider}, users)
+
+	app := http.NewServeMux()
+	app.HandleFunc("GET /user/oidc/{provider}/login", h.Login)
+	app.HandleFunc("GET /user/oidc/{provider}/callback", h.Callback)
+
+	return &oidcTest{idp: idp, identities: identities, app: app}
+}
+
+// login начинает вход и возвращает сохранённое состояние и ссылку на провайдера
+func (o *oidcTest) login(t *testing.T) (entity.OIDCLogin, *url.URL) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	o.app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/user/oidc/mock/login", nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("login: status %d, body %s", rec.Code, rec.Body)
+	}
+
+	authURL, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.identities.mu.Lock()
+	stored, ok := o.identities.logins[authURL.Query().Get("state")]
+	o.identities.mu.Unlock()
+	if !ok {
+		t.Fatalf("login: state %q is not stored", authURL.Query().Get("state"))
+	}
+
+	cookie := stateCookie(rec)
+	if cookie == nil || cookie.Value != stored.State {
+		t.Fatalf("login: state cookie %v, want %q", cookie, stored.State)
+	}
+	if !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteLaxMode || cookie.MaxAge <= 0 {
+		t.Errorf("login: state cookie attributes %v", cookie)
+	}
+	o.browserState = cookie.Value
+	return stored, authURL
+}
+
+// authorize проходит страницу провайдера и возвращает параметры редиректа обратно
+func (o *oidcTest) authorize(t *testing.T, authURL *url.URL) url.Values {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: status %d", resp.StatusCode)
+	}
+
+	back, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return back.Query()
+}
+
+func (o *oidcTest) callback(q url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/user/oidc/mock/callback?"+q.Encode(), nil)
+	if o.browserState != "" {
+		req.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: o.browserState})
+	}
+	rec := httptest.NewRecorder()
+	o.app.ServeHTTP(rec, req)
+	if c := stateCookie(rec); c != nil && c.MaxAge < 0 {
+		o.browserState = ""
+	}
+	return rec
+}
+
+func stateCookie(rec *httptest.ResponseRecorder) *http.Cookie {
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == oidcStateCookie {
+			return c
+		}
+	}
+	return nil
+}
+
+func problemCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode problem %q: %v", rec.Body, err)
+	}
+	return body.Code
+}
+
+func TestOIDCLoginCallback(t *testing.T) {
+	o := newOIDCTest(t)
+
+	stored, authURL := o.login(t)
+	q := authURL.Query()
+	checks := map[string]string{
+		"client_id":             "movie-final",
+		"redirect_uri":          testRedirectURL,
+		"response_type":         "code",
+		"state":                 stored.State,
+		"nonce":                 stored.Nonce,
+		"code_challenge":        oidc.CodeChallenge(stored.Verifier),
+		"code_challenge_method": "S256",
+	}
+	for k, want := range checks {
+		if got := q.Get(k); got != want {
+			t.Errorf("auth url %s = %q, want %q", k, got, want)
+		}
+	}
+	if stored.State == stored.Nonce || stored.State == stored.Verifier {
+		t.Error("state, nonce and verifier must be independent")
+	}
+
+	back := o.authorize(t, authURL)
+	if back.Get("state") != stored.State {
+		t.Fatalf("provider returned state %q, want %q", back.Get("state"), stored.State)
+	}
+
+	rec := o.callback(back)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("callback: status %d, body %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Message == "" {
+		t.Errorf("callback body %q, want a login message", rec.Body)
+	}
+	var session bool
+	for _, c := range rec.Result().Cookies() {
+		session = session || (c.Name == "auth_token" && c.Value != "")
+	}
+	if !session {
+		t.Error("callback did not set the session cookie")
+	}
+	if len(o.identities.logins) != 0 {
+		t.Error("state must be single-use")
+	}
+	if o.browserState != "" {
+		t.Error("callback did not clear the state cookie")
+	}
+}
+
+func TestOIDCCallbackRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		// prepare меняет состояние перед callback и возвращает его параметры
+		prepare  func(t *testing.T, o *oidcTest) url.Values
+		status   int
+		wantCode string
+	}{
+		{
+			name: "unknown state",
+			prepare: func(t *testing.T, o *oidcTest) url.Values {
+				_, authURL := o.login(t)
+				back := o.authorize(t, authURL)
+				back.Set("state", "forged")
+				o.browserState = "forged"
+				return back
+			},
+			status:   http.StatusBadRequest,
+			wantCode: "bad_request",
+		},
+		{
+			name: "state used twice",
+			prepare: func(t *testing.T, o *oidcTest) url.Values {
+				_, authURL := o.login(t)
+				back := o.authorize(t, authURL)
+				if rec := o.callback(back); rec.Code != http.StatusOK {
+					t.Fatalf("first callback: status %d", rec.Code)
+				}
+				// Повтор вместе с cookie, чтобы дошло до проверки state в базе
+				o.browserState = back.Get("state")
+				return back
+			},
+			status:   http.StatusBadRequest,
+			wantCode: "bad_request",
+		},
+		{
+			name: "no state cookie",
+			prepare: func(t *testing.T, o *oidcTest) url.Values {
+				// Атакующий начал вход у себя и подсунул жертве ссылку на callback
+				_, authURL := o.login(t)
+				back := o.authorize(t, authURL)
+				o.browserState = ""
+				return back
+			},
+			status:   http.StatusBadRequest,
+			wantCode: "bad_request",
+		},
+		{
+			name: "state cookie of another login",
+			prepare: func(t *testing.T, o *oidcTest) url.Values {
+				_, authURL := o.login(t)
+				back := o.authorize(t, authURL)
+				o.login(t)
+				return back
+			},
+			status:   http.StatusBadRequest,
+			wantCode: "bad_request",
+		},
+		{
+			name: "expired state",
+			prepare: func(t *testing.T, o *oidcTest) url.Values {
+				stored, authURL := o.login(t)
+				o.identities.update(stored.State, func(l *entity.OIDCLogin) { l.ExpiresAt = time.Now().Add(-time.Second) })
+				return o.authorize(t, authURL)
+			},
+			status:   http.StatusBadRequest,
+			wantCode: "bad_request",
+		},
+		{
+			name: "replayed code",
+			prepare: func(t *testing.T, o *oidcTest) url.Values {
+				_, authURL := o.login(t)
+				first := o.authorize(t, authURL)
+				if rec := o.callback(first); rec.Code != http.StatusOK {
+					t.Fatalf("first callback: status %d", rec.Code)
+				}
+				// Новый вход с чужим, уже обменянным кодом
+				stored, _ := o.login(t)
+				return url.Values{"state": {stored.State}, "code": {first.Get("code")}}
+			},
+			status:   http.StatusUnauthorized,
+			wantCode: "unauthorized",
+		},
+		{
+			name: "wrong PKCE verifier",
+			prepare: func(t *testing.T, o *oidcTest) url.Values {
+				stored, authURL := o.login(t)
+				o.identities.update(stored.State, func(l *entity.OIDCLogin) { l.Verifier += "x" })
+				return o.authorize(t, authURL)
+			},
+			status:   http.StatusUnauthorized,
+			wantCode: "unauthorized",
+		},
+		{
+			name: "wrong nonce",
+			prepare: func(t *testing.T, o *oidcTest) url.Values {
+				stored, authURL := o.login(t)
+				o.identities.update(stored.State, func(l *entity.OIDCLogin) { l.Nonce = "other" })
+				return o.authorize(t, authURL)
+			},
+			status:   http.StatusUnauthorized,
+			wantCode: "unauthorized",
+		},
+		{
+			name: "token for another audience",
+			prepare: func(t *testing.T, o *oidcTest) url.Values {
+				o.idp.SetAudience("another-client")
+				_, authURL := o.login(t)
+				return o.authorize(t, authURL)
+			},
+			status:   http.StatusUnauthorized,
+			wantCode: "unauthorized",
+		},
+		{
+			name: "provider error",
+			prepare: func(t *testing.T, o *oidcTest) url.Values {
+				return url.Values{"error": {"access_denied"}}
+			},
+			status:   http.StatusUnauthorized,
+			wantCode: "provider_error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newOIDCTest(t)
+			rec := o.callback(tt.prepare(t, o))
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d, body %s", rec.Code, tt.status, rec.Body)
+			}
+			if code := problemCode(t, rec); code != tt.wantCode {
+				t.Errorf("code %q, want %q", code, tt.wantCode)
+			}
+			for _, c := range rec.Result().Cookies() {
+				if c.Name == "auth_token" {
+					t.Error("rejected callback must not set the session cookie")
+				}
+			}
+		})
+	}
+}
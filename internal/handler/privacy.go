@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/marcokz/movie-final/internal/auth"
+	"github.com/marcokz/movie-final/internal/entity"
+	"github.com/marcokz/movie-final/internal/middleware"
+	"github.com/marcokz/movie-final/internal/problem"
+)
+
+// PrivacySettings для каждого поля: public, followers или private.
+// Дату рождения другие всегда видят только как возрастной диапазон.
+type PrivacySettings struct {
+	Sex         string `json:"sex" validate:"oneof=public followers private"`
+	DateOfBirth string `json:"dateofbirth" validate:"oneof=public followers private"`
+	Country     string `json:"country" validate:"oneof=public followers private"`
+	City        string `json:"city" validate:"oneof=public followers private"`
+	Ratings     string `json:"ratings" validate:"oneof=public followers private"`
+}
+
+func (h *UserHandler) GetPrivacy(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, r, problem.Unauthenticated)
+		return
+	}
+
+	pr, err := h.userRepo.GetPrivacy(r.Context(), claims.ID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(PrivacySettings(pr))
+}
+
+func (h *UserHandler) UpdatePrivacy(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, r, problem.Unauthenticated)
+		return
+	}
+
+	var req PrivacySettings
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	err := h.userRepo.UpdatePrivacy(r.Context(), claims.ID, entity.Privacy(req))
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "privacy update successfully"})
+}
@@ -0,0 +1,230 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marcokz/movie-final/internal/auth"
+	"github.com/marcokz/movie-final/internal/entity"
+	"github.com/marcokz/movie-final/internal/images"
+	"github.com/marcokz/movie-final/internal/middleware"
+	"github.com/marcokz/movie-final/internal/problem"
+)
+
+type MeResponse struct {
+	User
+	Email       string
+	Role        string
+	TOTPEnabled bool
+}
+
+func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, r, problem.Unauthenticated)
+		return
+	}
+
+	u, err := h.userRepo.GetUserInfo(r.Context(), claims.ID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(MeResponse{
+		User:        userResponse(u),
+		Email:       u.Email,
+		Role:        u.Role,
+		TOTPEnabled: u.TOTPEnabled,
+	})
+}
+
+// UserPatchRequest поля, которых нет в запросе, остаются без изменений
+type UserPatchRequest struct {
+	Name        *string `json:"name" validate:"max=100"`
+	Surname     *string `json:"surname" validate:"max=100"`
+	Sex         *string `json:"sex" validate:"omitempty,oneof=male female other"` // "" очищает
+	DateOfBirth *string `json:"dateofbirth" validate:"date,past"`
+	Country     *string `json:"country" validate:"max=100"` // код ISO или название, "" очищает
+	CityID      *int64  `json:"cityid" validate:"min=0"`    // 0 очищает
+}
+
+func (h *UserHandler) PatchMe(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, r, problem.Unauthenticated)
+		return
+	}
+
+	var req UserPatchRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	patch := entity.UserPatch{
+		Name:    req.Name,
+		Surname: req.Surname,
+		Sex:     req.Sex,
+		CityID:  req.CityID,
+	}
+
+	// Город задаёт и страну; страна без города сохраняет город, только если он в этой стране
+	if req.Country != nil || (req.CityID != nil && *req.CityID != 0) {
+		var country string
+		if req.Country != nil {
+			country = *req.Country
+		}
+		var cityid int64
+		if req.CityID != nil {
+			cityid = *req.CityID
+		}
+
+		code, err := resolveLocation(r.Context(), h.locationsRepo, country, cityid)
+		if err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+		if req.Country != nil || code != "" {
+			patch.Country = &code
+		}
+	}
+	if req.DateOfBirth != nil {
+		parsedDate, err := time.Parse("2006-01-02", *req.DateOfBirth)
+		if err != nil {
+			problem.Write(w, r, problem.Validation(problem.Invalid("dateofbirth", "dateofbirth must be a date in YYYY-MM-DD format")))
+			return
+		}
+		patch.DateOfBirth = &parsedDate
+	}
+
+	err := h.userRepo.PatchUserInfo(r.Context(), claims.ID, patch)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "user update successfully"})
+}
+
+type ProfileMovie struct {
+	ID     int64
+	Name   string
+	Year   int
+	Poster *ImageURLs `json:",omitempty"`
+	Rating int64
+}
+
+type ProfileResponse struct {
+	User
+	RatingsHidden bool
+	RatingsCount  int64
+	AverageRating float64
+	Followers     int64
+	Following     int64
+	TopMovies     []ProfileMovie
+}
+
+func (h *UserHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, r, problem.Unauthenticated)
+		return
+	}
+
+	// Профиль доступен и по id, и по нику: /users/@{handle}
+	var id int64
+	var redirect string
+	var err error
+	pathValue := r.PathValue("id")
+	if handle, ok := strings.CutPrefix(pathValue, "@"); ok {
+		id, redirect, err = h.resolveHandle(r, handle)
+	} else if id, err = strconv.ParseInt(pathValue, 10, 64); err != nil {
+		problem.Write(w, r, problem.Validation(problem.Invalid("id", "id must be an integer or @handle")))
+		return
+	}
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	pr, err := h.userRepo.GetPublicProfile(r.Context(), claims.ID, id)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	// Редирект только после проверки доступа, чтобы не раскрыть новый ник
+	// заблокировавшего пользователя. Временный: старый ник может занять другой
+	// пользователь, а 301 браузеры и прокси запоминают навсегда.
+	if redirect != "" {
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+
+	resp := ProfileResponse{
+		User:          userResponse(pr.Users),
+		RatingsHidden: pr.RatingsHidden,
+		RatingsCount:  pr.RatingsCount,
+		AverageRating: pr.AverageRating,
+		Followers:     pr.Followers,
+		Following:     pr.Following,
+		TopMovies:     make([]ProfileMovie, 0, len(pr.TopMovies)),
+	}
+	for _, m := range pr.TopMovies {
+		resp.TopMovies = append(resp.TopMovies, ProfileMovie{
+			ID:     m.Movies.ID,
+			Name:   m.Movies.Name,
+			Year:   m.Movies.Year,
+			Poster: imageURLs(m.Movies.Poster, images.Poster),
+			Rating: m.Rating,
+		})
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// UploadAvatar PUT /user/me/avatar, картинка телом запроса или полем file формы.
+func (h *UserHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, r, problem.Unauthenticated)
+		return
+	}
+
+	img, ok := h.images.upload(w, r, images.Avatar)
+	if !ok {
+		return
+	}
+
+	err := h.userRepo.SetAvatar(r.Context(), claims.ID, img.Hash)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(imageURLs(img.Hash, images.Avatar))
+}
+
+func (h *UserHandler) DeleteAvatar(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, r, problem.Unauthenticated)
+		return
+	}
+
+	err := h.userRepo.SetAvatar(r.Context(), claims.ID, "")
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "avatar deleted"})
+}
@@ -7,12 +7,14 @@ import (
 
 	"github.com/marcokz/movie-final/internal/auth"
 	"github.com/marcokz/movie-final/internal/entity"
+	"github.com/marcokz/movie-final/internal/images"
 	"github.com/marcokz/movie-final/internal/middleware"
+	"github.com/marcokz/movie-final/internal/problem"
 )
 
 type RatingsRepo interface {
-	GetMoviesWithRatingFromUser(ctx context.Context, userid, minrating, maxrating int64) ([]entity.MovieWithRating, error)
-	GetUsersByRatingOfMovie(ctx context.Context, movieid, minrating, maxrating int64) ([]entity.UserWithRating, error)
+	GetMoviesWithRatingFromUser(ctx context.Context, viewerid, userid, minrating, maxrating int64) ([]entity.MovieWithRating, error)
+	GetUsersByRatingOfMovie(ctx context.Context, viewerid, movieid, minrating, maxrating int64) ([]entity.UserWithRating, error)
 	UpdateRating(ctx context.Context, r entity.Rating) error
 }
 
@@ -25,63 +27,50 @@ func NewRatingsHandler(r RatingsRepo) *RatingsHandler {
 }
 
 type GetMovieByRating struct {
-	UserID    int64 `json:"userid"`
-	MinRating int64 `json:"minrating"`
-	MaxRating int64 `json:"maxrating"`
+	UserID    int64 `json:"userid" validate:"required,min=1"`
+	MinRating int64 `json:"minrating" validate:"min=1,max=10"`
+	MaxRating int64 `json:"maxrating" validate:"min=1,max=10,gtefield=MinRating"`
 }
 
 type UsersWithRating struct {
 	ID          int64
 	Name        string
 	Surname     string
+	Avatar      *ImageURLs `json:",omitempty"`
 	Sex         string
 	DateOfBirth string
+	AgeRange    string `json:",omitempty"`
 	Country     string
+	CountryName string
+	CityID      int64
 	City        string
 	Rating      int64
 }
 
-func CorrectMinMaxRating(minrating, maxrating int64) (string, int) {
-	switch {
-	case minrating < 1 || minrating > 10:
-		return "MinRating from 1 to 10", http.StatusBadRequest
-	case maxrating < 1 || maxrating > 10:
-		return "MaxRating from 1 to 10", http.StatusBadRequest
-	case minrating > maxrating:
-		return "Min rating is higher than max", http.StatusBadRequest
-	}
-	return "", 0
-}
-
 func (h *RatingsHandler) GetAllMovieFromUserWithRating(w http.ResponseWriter, r *http.Request) {
 	_, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
+		problem.Write(w, r, problem.Unauthenticated)
 		return
 	}
 }
 
 func (h *RatingsHandler) GetMoviesWithRatingFromUser(w http.ResponseWriter, r *http.Request) {
-	_, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
+		problem.Write(w, r, problem.Unauthenticated)
 		return
 	}
 
 	var getMovie GetMovieByRating
-	if err := json.NewDecoder(r.Body).Decode(&getMovie); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+	if !decodeJSON(w, r, &getMovie) {
 		return
 	}
 
-	messageErr, statusCode := CorrectMinMaxRating(getMovie.MinRating, getMovie.MaxRating)
-	if messageErr != "" && statusCode != 0 {
-		http.Error(w, messageErr, statusCode)
-	}
-
-	movies, err := h.ratingsRepo.GetMoviesWithRatingFromUser(r.Context(), getMovie.UserID, getMovie.MinRating, getMovie.MaxRating)
+	movies, err := h.ratingsRepo.GetMoviesWithRatingFromUser(r.Context(), claims.ID, getMovie.UserID, getMovie.MinRating, getMovie.MaxRating)
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		problem.Write(w, r, err)
+		return
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -89,33 +78,27 @@ func (h *RatingsHandler) GetMoviesWithRatingFromUser(w http.ResponseWriter, r *h
 }
 
 type GetUserByRatingOgMovie struct {
-	MovieID   int64 `json:"movieid"`
-	MinRating int64 `json:"minrating"`
-	MaxRating int64 `json:"maxrating"`
+	MovieID   int64 `json:"movieid" validate:"required,min=1"`
+	MinRating int64 `json:"minrating" validate:"min=1,max=10"`
+	MaxRating int64 `json:"maxrating" validate:"min=1,max=10,gtefield=MinRating"`
 }
 
 func (h *RatingsHandler) GetUsersByRatingOfMovie(w http.ResponseWriter, r *http.Request) {
-	_, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
+		problem.Write(w, r, problem.Unauthenticated)
 		return
 	}
 
 	var getUser GetUserByRatingOgMovie
 
-	if err := json.NewDecoder(r.Body).Decode(&getUser); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+	if !decodeJSON(w, r, &getUser) {
 		return
 	}
 
-	messageErr, statusCode := CorrectMinMaxRating(getUser.MinRating, getUser.MaxRating)
-	if messageErr != "" && statusCode != 0 {
-		http.Error(w, messageErr, statusCode)
-	}
-
-	users, err := h.ratingsRepo.GetUsersByRatingOfMovie(r.Context(), getUser.MovieID, getUser.MinRating, getUser.MaxRating)
+	users, err := h.ratingsRepo.GetUsersByRatingOfMovie(r.Context(), claims.ID, getUser.MovieID, getUser.MinRating, getUser.MaxRating)
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		problem.Write(w, r, err)
 		return
 	}
 
@@ -126,9 +109,13 @@ func (h *RatingsHandler) GetUsersByRatingOfMovie(w http.ResponseWriter, r *http.
 			ID:          user.Users.ID,
 			Name:        user.Users.Name,
 			Surname:     user.Users.Surname,
+			Avatar:      imageURLs(user.Users.Avatar, images.Avatar),
 			Sex:         user.Users.Sex,
-			DateOfBirth: user.Users.DateOfBirth.Format("2006-01-02"),
+			DateOfBirth: formatDate(user.Users.DateOfBirth),
+			AgeRange:    user.Users.AgeRange,
 			Country:     user.Users.Country,
+			CountryName: user.Users.CountryName,
+			CityID:      user.Users.CityID,
 			City:        user.Users.City,
 			Rating:      user.Rating,
 		}
@@ -140,21 +127,19 @@ func (h *RatingsHandler) GetUsersByRatingOfMovie(w http.ResponseWriter, r *http.
 }
 
 type UpdateRating struct {
-	MovieID int64 `json:"movieid"`
-	Rating  int64 `json:"rating"`
+	MovieID int64 `json:"movieid" validate:"required,min=1"`
+	Rating  int64 `json:"rating" validate:"min=1,max=10"`
 }
 
 func (h *RatingsHandler) UpdateRating(w http.ResponseWriter, r *http.Request) {
 	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
+		problem.Write(w, r, problem.Unauthenticated)
 		return
 	}
 
 	var updateRating UpdateRating
-	err := json.NewDecoder(r.Body).Decode(&updateRating)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+	if !decodeJSON(w, r, &updateRating) {
 		return
 	}
 
@@ -163,17 +148,13 @@ func (h *RatingsHandler) UpdateRating(w http.ResponseWriter, r *http.Request) {
 		MovieID: updateRating.MovieID,
 		Rating:  updateRating.Rating,
 	}
-	if rating.Rating < 1 || rating.Rating > 10 {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "rating from 1 to 10"})
-		return
-	}
 
-	err = h.ratingsRepo.UpdateRating(r.Context(), rating)
+	err := h.ratingsRepo.UpdateRating(r.Context(), rating)
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		problem.Write(w, r, err)
 		return
 	}
+	ratingsWritten.Inc()
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "rating update"})
@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/marcokz/movie-final/internal/problem"
+	"github.com/marcokz/movie-final/internal/validate"
+)
+
+// fieldErrors поля и коды ошибок в том виде, в каком они уходят клиенту
+func fieldErrors(t *testing.T, err error) map[string]string {
+	t.Helper()
+	got := map[string]string{}
+	if err == nil {
+		return got
+	}
+	p := problem.From(err)
+	if p.Status != 400 {
+		t.Fatalf("error %v is %d, want 400", err, p.Status)
+	}
+	for _, fe := range p.Errors {
+		got[fe.Field] = fe.Code
+	}
+	return got
+}
+
+type requestCase struct {
+	name string
+	req  any
+	want map[string]string
+}
+
+func runRequestCases(t *testing.T, tests []requestCase) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fieldErrors(t, validate.Struct(tt.req))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("errors = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
+
+var none = map[string]string{}
+
+func TestRegisterRequest(t *testing.T) {
+	runRequestCases(t, []requestCase{
+		{"ok", RegisterRequest{Email: "a@example.com", Password: "secret"}, none},
+		{"empty", RegisterRequest{}, map[string]string{"email": "required", "password": "required"}},
+		{"bad email", RegisterRequest{Email: "a@", Password: "secret"}, map[string]string{"email": "email"}},
+		{"long email", RegisterRequest{Email: strings.Repeat("a", 250) + "@example.com", Password: "secret"}, map[string]string{"email": "max"}},
+	})
+}
+
+func TestLoginRequest(t *testing.T) {
+	runRequestCases(t, []requestCase{
+		{"ok", LoginRequest{Email: "a@example.com", Password: "secret"}, none},
+		{"empty", LoginRequest{}, map[string]string{"email": "required", "password": "required"}},
+		{"blank password", LoginRequest{Email: "a@example.com", Password: "   "}, map[string]string{"password": "required"}},
+		{"long email", LoginRequest{Email: strings.Repeat("a", 321), Password: "secret"}, map[string]string{"email": "max"}},
+	})
+}
+
+func TestMovieRequest(t *testing.T) {
+	runRequestCases(t, []requestCase{
+		{"ok", MovieResponse{Name: "Arrival", Year: 2016}, none},
+		{"first film", MovieResponse{Name: "Roundhay Garden Scene", Year: 1888}, none},
+		{"no name", MovieResponse{Year: 2016}, map[string]string{"name": "required"}},
+		{"no year", MovieResponse{Name: "Arrival"}, map[string]string{"year": "min"}},
+		{"future year", MovieResponse{Name: "Arrival", Year: 2101}, map[string]string{"year": "max"}},
+		{"long", MovieResponse{Name: strings.Repeat("a", 256), Year: 2016, Description: strings.Repeat("a", 10001)},
+			map[string]string{"name": "max", "description": "max"}},
+	})
+}
+
+func TestUpdateRatingRequest(t *testing.T) {
+	runRequestCases(t, []requestCase{
+		{"ok", UpdateRating{MovieID: 1, Rating: 10}, none},
+		{"empty", UpdateRating{}, map[string]string{"movieid": "required", "rating": "min"}},
+		{"negative movie", UpdateRating{MovieID: -1, Rating: 5}, map[string]string{"movieid": "min"}},
+		{"too high", UpdateRating{MovieID: 1, Rating: 11}, map[string]string{"rating": "max"}},
+	})
+}
+
+func TestRatingFilterRequests(t *testing.T) {
+	runRequestCases(t, []requestCase{
+		{"movies ok", GetMovieByRating{UserID: 1, MinRating: 3, MaxRating: 3}, none},
+		{"movies no user", GetMovieByRating{MinRating: 1, MaxRating: 10}, map[string]string{"userid": "required"}},
+		{"movies out of range", GetMovieByRating{UserID: 1, MinRating: 0, MaxRating: 11}, map[string]string{"minrating": "min", "maxrating": "max"}},
+		{"movies min above max", GetMovieByRating{UserID: 1, MinRating: 8, MaxRating: 2}, map[string]string{"maxrating": "gtefield"}},
+		{"users ok", GetUserByRatingOgMovie{MovieID: 1, MinRating: 1, MaxRating: 10}, none},
+		{"users min above max", GetUserByRatingOgMovie{MovieID: 1, MinRating: 9, MaxRating: 1}, map[string]string{"maxrating": "gtefield"}},
+	})
+}
+
+func TestAgeRequest(t *testing.T) {
+	runRequestCases(t, []requestCase{
+		{"ok", Age{MinAge: 18, MaxAge: 30}, none},
+		{"any age", Age{MaxAge: 150}, none},
+		{"negative", Age{MinAge: -1, MaxAge: 30}, map[string]string{"minage": "min"}},
+		{"too old", Age{MinAge: 18, MaxAge: 151}, map[string]string{"maxage": "max"}},
+		{"min above max", Age{MinAge: 30, MaxAge: 18}, map[string]string{"maxage": "gtefield"}},
+	})
+}
+
+func TestUserUpdateRequest(t *testing.T) {
+	runRequestCases(t, []requestCase{
+		{"ok", UserUpdateRequest{Name: "Ann", Sex: "female", DateOfBirth: "1990-05-17", Country: "RU", CityID: 1}, none},
+		{"sex optional", UserUpdateRequest{DateOfBirth: "1990-05-17"}, none},
+		{"no birth date", UserUpdateRequest{}, map[string]string{"DateOfBirth": "required"}},
+		{"bad birth date", UserUpdateRequest{DateOfBirth: "17.05.1990"}, map[string]string{"DateOfBirth": "date"}},
+		{"future birth date", UserUpdateRequest{DateOfBirth: "2999-01-01"}, map[string]string{"DateOfBirth": "past"}},
+		{"unknown sex", UserUpdateRequest{Sex: "robot", DateOfBirth: "1990-05-17"}, map[string]string{"Sex": "oneof"}},
+		{"long names", UserUpdateRequest{Name: strings.Repeat("a", 101), Surname: strings.Repeat("a", 101), Country: strings.Repeat("a", 101), DateOfBirth: "1990-05-17"},
+			map[string]string{"Name": "max", "Surname": "max", "Country": "max"}},
+		{"negative city", UserUpdateRequest{DateOfBirth: "1990-05-17", CityID: -1}, map[string]string{"CityID": "min"}},
+	})
+}
+
+func TestUserPatchRequest(t *testing.T) {
+	runRequestCases(t, []requestCase{
+		{"nothing", UserPatchRequest{}, none},
+		{"ok", UserPatchRequest{Name: ptr("Ann"), Sex: ptr("other"), DateOfBirth: ptr("1990-05-17"), CityID: ptr(int64(0))}, none},
+		{"clear sex", UserPatchRequest{Sex: ptr("")}, none},
+		{"unknown sex", UserPatchRequest{Sex: ptr("robot")}, map[string]string{"sex": "oneof"}},
+		{"bad birth date", UserPatchRequest{DateOfBirth: ptr("1990-13-01")}, map[string]string{"dateofbirth": "date"}},
+		{"future birth date", UserPatchRequest{DateOfBirth: ptr("2999-01-01")}, map[string]string{"dateofbirth": "past"}},
+		{"long name", UserPatchRequest{Name: ptr(strings.Repeat("a", 101))}, map[string]string{"name": "max"}},
+		{"negative city", UserPatchRequest{CityID: ptr(int64(-1))}, map[string]string{"cityid": "min"}},
+	})
+}
+
+func TestPrivacySettingsRequest(t *testing.T) {
+	all := func(v string) PrivacySettings {
+		return PrivacySettings{Sex: v, DateOfBirth: v, Country: v, City: v, Ratings: v}
+	}
+	runRequestCases(t, []requestCase{
+		{"public", all("public"), none},
+		{"mixed", PrivacySettings{Sex: "private", DateOfBirth: "followers", Country: "public", City: "private", Ratings: "followers"}, none},
+		{"empty", PrivacySettings{}, map[string]string{"sex": "oneof", "dateofbirth": "oneof", "country": "oneof", "city": "oneof", "ratings": "oneof"}},
+		{"unknown", PrivacySettings{Sex: "friends", DateOfBirth: "public", Country: "public", City: "public", Ratings: "PUBLIC"},
+			map[string]string{"sex": "oneof", "ratings": "oneof"}},
+	})
+}
+
+func TestUserBySexRequest(t *testing.T) {
+	runRequestCases(t, []requestCase{
+		{"ok", UserBySex{Sex: "male"}, none},
+		{"empty", UserBySex{}, map[string]string{"sex": "required"}},
+		{"unknown", UserBySex{Sex: "robot"}, map[string]string{"sex": "oneof"}},
+	})
+}
+
+func TestParseUserFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  map[string]string
+	}{
+		{"defaults", "", none},
+		{"all", "minage=18&maxage=30&sex=female&city=1&movieid=2&minrating=7&maxrating=10&minsimilarity=0.5&radius_km=50&limit=100&offset=20", none},
+		{"not numbers", "minage=x&city=y&minsimilarity=z&limit=1.5", map[string]string{"minage": "invalid", "city": "invalid", "minsimilarity": "invalid", "limit": "invalid"}},
+		{"nan", "radius_km=NaN", map[string]string{"radius_km": "invalid"}},
+		{"age range", "minage=-1&maxage=151", map[string]string{"minage": "min", "maxage": "max"}},
+		{"age min above max", "minage=30&maxage=18", map[string]string{"maxage": "gtefield"}},
+		{"rating range", "movieid=1&minrating=0&maxrating=11", map[string]string{"minrating": "min", "maxrating": "max"}},
+		{"rating min above max", "movieid=1&minrating=9&maxrating=2", map[string]string{"maxrating": "gtefield"}},
+		{"sex", "sex=robot", map[string]string{"sex": "oneof"}},
+		{"similarity", "minsimilarity=1.5", map[string]string{"minsimilarity": "max"}},
+		{"radius", "radius_km=501", map[string]string{"radius_km": "max"}},
+		{"limit", "limit=0&offset=-1", map[string]string{"limit": "min", "offset": "min"}},
+		{"sort", "sort=email", map[string]string{"sort": "invalid"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+			_, err := parseUserFilter(q)
+			got := fieldErrors(t, err)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("errors = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseUserFilterValues(t *testing.T) {
+	q, _ := url.ParseQuery("minage=18&movieid=2&maxrating=8&sort=-rating")
+	f, err := parseUserFilter(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.MinAge == nil || *f.MinAge != 18 || f.MaxAge != nil {
+		t.Errorf("age = %v..%v, want 18..", f.MinAge, f.MaxAge)
+	}
+	if f.MovieID != 2 || f.MinRating != 1 || f.MaxRating != 8 {
+		t.Errorf("rating = movie %d %d..%d, want movie 2 1..8", f.MovieID, f.MinRating, f.MaxRating)
+	}
+	if f.Sort != "rating" || !f.Desc || f.Limit != defaultSearchLimit {
+		t.Errorf("sort = %q desc %v limit %d", f.Sort, f.Desc, f.Limit)
+	}
+
+	// Без movieid диапазон оценок не применяется
+	q, _ = url.ParseQuery("minrating=5")
+	if f, err = parseUserFilter(q); err != nil || f.MovieID != 0 || f.MinRating != 0 {
+		t.Errorf("rating without movie = %d..%d, %v", f.MinRating, f.MaxRating, err)
+	}
+
+	for _, query := range []string{"sort=rating", "sort=distance"} {
+		q, _ := url.ParseQuery(query)
+		if _, err := parseUserFilter(q); problem.From(err).Code != problem.CodeBadRequest {
+			t.Errorf("%s: error = %v, want bad_request", query, err)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/marcokz/movie-final/internal/auth"
+	"github.com/marcokz/movie-final/internal/entity"
+	"github.com/marcokz/movie-final/internal/middleware"
+	"github.com/marcokz/movie-final/internal/problem"
+	"github.com/marcokz/movie-final/internal/validate"
+)
+
+const defaultSearchLimit = 20
+
+// SearchQuery параметры поиска из строки запроса. Имена в тегах json совпадают
+// с параметрами, поэтому ошибки validate указывают на параметр.
+type SearchQuery struct {
+	MinAge        *int64   `json:"minage" validate:"min=0,max=150"`
+	MaxAge        *int64   `json:"maxage" validate:"min=0,max=150,gtefield=MinAge"`
+	Sex           string   `json:"sex" validate:"omitempty,oneof=male female other"`
+	Country       string   `json:"country" validate:"max=100"`
+	CityID        int64    `json:"city" validate:"min=0"`
+	MovieID       int64    `json:"movieid" validate:"min=0"`
+	MinRating     int64    `json:"minrating" validate:"min=1,max=10"`
+	MaxRating     int64    `json:"maxrating" validate:"min=1,max=10,gtefield=MinRating"`
+	MinSimilarity *float64 `json:"minsimilarity" validate:"min=0,max=1"`
+	RadiusKm      float64  `json:"radius_km" validate:"min=0,max=500"`
+	Limit         int      `json:"limit" validate:"min=1,max=100"`
+	Offset        int      `json:"offset" validate:"min=0"`
+}
+
+type UserSearchItem struct {
+	User
+	Rating     *int64   `json:",omitempty"`
+	Similarity *float64 `json:",omitempty"`
+	DistanceKm *float64 `json:",omitempty"`
+}
+
+type UserSearchResponse struct {
+	Users  []UserSearchItem `json:"users"`
+	Total  int              `json:"total"`
+	Limit  int              `json:"limit"`
+	Offset int              `json:"offset"`
+}
+
+// SearchUsers объединяет фильтры по возрасту, полу, месту, оценке фильма и схожести вкусов.
+// Параметры: minage, maxage, sex, country (код или название), city (id), movieid, minrating, maxrating,
+// minsimilarity, radius_km и near (см. NearbyUsers), sort (id, name, age, rating, similarity, distance;
+// "-" в начале для убывания), limit, offset.
+func (h *UserHandler) SearchUsers(w http.ResponseWriter, r *http.Request) {
+	f, err := parseUserFilter(r.URL.Query())
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	h.searchUsers(w, r, f)
+}
+
+// NearbyUsers пользователи, чей город не дальше radius_km от города near (id)
+// или, если near не задан, от города того, кто ищет. Остальные параметры как
+// у SearchUsers, по умолчанию ближние первыми.
+func (h *UserHandler) NearbyUsers(w http.ResponseWriter, r *http.Request) {
+	f, err := parseUserFilter(r.URL.Query())
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if f.RadiusKm == 0 {
+		problem.Write(w, r, problem.Validation(problem.Required("radius_km")))
+		return
+	}
+	if r.URL.Query().Get("sort") == "" {
+		f.Sort = "distance"
+	}
+
+	h.searchUsers(w, r, f)
+}
+
+func (h *UserHandler) searchUsers(w http.ResponseWriter, r *http.Request, f entity.UserFilter) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, r, problem.Unauthenticated)
+		return
+	}
+
+	// Страну можно передать кодом или названием на любом языке
+	if f.Country != "" {
+		code, err := h.locationsRepo.ResolveCountry(r.Context(), f.Country)
+		if err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+		f.Country = code
+	}
+
+	if f.RadiusKm != 0 {
+		near, messageErr, err := h.searchCenter(r, claims.ID)
+		if err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+		if messageErr != "" {
+			problem.Write(w, r, problem.BadRequest(messageErr))
+			return
+		}
+		f.Near = &near
+	}
+
+	// Схожесть считаем относительно того, кто ищет
+	f.Viewer = claims.ID
+	f.WithSimilarity = f.MinSimilarity != nil || f.Sort == "similarity"
+
+	users, total, err := h.userRepo.SearchUsers(r.Context(), f)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	resp := UserSearchResponse{
+		Users:  make([]UserSearchItem, 0, len(users)),
+		Total:  total,
+		Limit:  f.Limit,
+		Offset: f.Offset,
+	}
+
+	for _, u := range users {
+		// Расстояние между центрами городов, точнее 100 м оно всё равно не бывает
+		if u.DistanceKm != nil {
+			d := math.Round(*u.DistanceKm*10) / 10
+			u.DistanceKm = &d
+		}
+		resp.Users = append(resp.Users, UserSearchItem{
+			User:       userResponse(u.Users),
+			Rating:     u.Rating,
+			Similarity: u.Similarity,
+			DistanceKm: u.DistanceKm,
+		})
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// searchCenter откуда считать расстояние: город из параметра near или город пользователя.
+func (h *UserHandler) searchCenter(r *http.Request, userid int64) (entity.GeoPoint, string, error) {
+	var cityid int64
+	if v := r.URL.Query().Get("near"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return entity.GeoPoint{}, "near must be a city id", nil
+		}
+		cityid = id
+	} else {
+		u, err := h.userRepo.GetUserInfo(r.Context(), userid)
+		if err != nil {
+			return entity.GeoPoint{}, "", err
+		}
+		if u.CityID == 0 {
+			return entity.GeoPoint{}, "set your city in the profile or pass near", nil
+		}
+		cityid = u.CityID
+	}
+
+	city, err := h.locationsRepo.GetCity(r.Context(), cityid)
+	if errors.Is(err, entity.ErrCityNotFound) {
+		return entity.GeoPoint{}, err.Error(), nil
+	}
+	if err != nil {
+		return entity.GeoPoint{}, "", err
+	}
+
+	return city.Point(), "", nil
+}
+
+// parseUserFilter разбирает параметры поиска: числа, диапазоны по тегам SearchQuery
+// и допустимые сочетания параметров. Ошибка уже готова для problem.Write.
+func parseUserFilter(q url.Values) (entity.UserFilter, error) {
+	sq := SearchQuery{
+		Sex:       q.Get("sex"),
+		Country:   q.Get("country"),
+		MinRating: 1,
+		MaxRating: 10,
+		Limit:     defaultSearchLimit,
+	}
+
+	var errs []problem.FieldError
+	intParam(q, "city", &sq.CityID, &errs)
+	intParam(q, "movieid", &sq.MovieID, &errs)
+	intParam(q, "minrating", &sq.MinRating, &errs)
+	intParam(q, "maxrating", &sq.MaxRating, &errs)
+	intParam(q, "limit", &sq.Limit, &errs)
+	intParam(q, "offset", &sq.Offset, &errs)
+	floatParam(q, "radius_km", &sq.RadiusKm, &errs)
+	var minAge, maxAge int64
+	if intParam(q, "minage", &minAge, &errs) {
+		sq.MinAge = &minAge
+	}
+	if intParam(q, "maxage", &maxAge, &errs) {
+		sq.MaxAge = &maxAge
+	}
+	var minSimilarity float64
+	if floatParam(q, "minsimilarity", &minSimilarity, &errs) {
+		sq.MinSimilarity = &minSimilarity
+	}
+	if len(errs) > 0 {
+		return entity.UserFilter{}, problem.Validation(errs...)
+	}
+
+	if err := validate.Struct(sq); err != nil {
+		return entity.UserFilter{}, err
+	}
+
+	f := entity.UserFilter{
+		MinAge:        sq.MinAge,
+		MaxAge:        sq.MaxAge,
+		Sex:           sq.Sex,
+		Country:       sq.Country,
+		CityID:        sq.CityID,
+		MinSimilarity: sq.MinSimilarity,
+		RadiusKm:      sq.RadiusKm,
+		Limit:         sq.Limit,
+		Offset:        sq.Offset,
+	}
+	// Диапазон оценок без фильма ничего не фильтрует
+	if sq.MovieID != 0 {
+		f.MovieID, f.MinRating, f.MaxRating = sq.MovieID, sq.MinRating, sq.MaxRating
+	}
+
+	if v := q.Get("sort"); v != "" {
+		f.Sort, f.Desc = strings.TrimPrefix(v, "-"), strings.HasPrefix(v, "-")
+		switch f.Sort {
+		case "id", "name", "age", "similarity":
+		case "rating":
+			if f.MovieID == 0 {
+				return f, problem.BadRequest("sort by rating requires movieid")
+			}
+		case "distance":
+			if f.RadiusKm == 0 {
+				return f, problem.BadRequest("sort by distance requires radius_km")
+			}
+		default:
+			return f, problem.Validation(problem.Invalid("sort", "sort must be one of id, name, age, rating, similarity, distance"))
+		}
+	}
+
+	return f, nil
+}
+
+// intParam разбирает целый параметр name в dst, если он задан. Ошибка копится в errs.
+func intParam[T int | int64](q url.Values, name string, dst *T, errs *[]problem.FieldError) bool {
+	v := q.Get(name)
+	if v == "" {
+		return false
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		*errs = append(*errs, problem.Invalid(name, name+" must be an integer"))
+		return false
+	}
+	*dst = T(n)
+	return true
+}
+
+func floatParam(q url.Values, name string, dst *float64, errs *[]problem.FieldError) bool {
+	v := q.Get(name)
+	if v == "" {
+		return false
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+		*errs = append(*errs, problem.Invalid(name, name+" must be a number"))
+		return false
+	}
+	*dst = n
+	return true
+}
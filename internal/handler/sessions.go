@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/marcokz/movie-final/internal/auth"
+	"github.com/marcokz/movie-final/internal/middleware"
+	"github.com/marcokz/movie-final/internal/problem"
+)
+
+// Размер колонки sessions.user_agent, длинный заголовок обрезается, а не ломает вход
+const maxUserAgentLength = 512
+
+// truncate обрезает строку до n символов, не разрывая многобайтовые руны
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+type SessionResponse struct {
+	ID         int64  `json:"id"`
+	UserAgent  string `json:"useragent"`
+	IP         string `json:"ip"`
+	CreatedAt  string `json:"createdat"`
+	LastSeenAt string `json:"lastseenat"`
+	Current    bool   `json:"current"`
+}
+
+func (h *UserHandler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, r, problem.Unauthenticated)
+		return
+	}
+
+	sessions, err := h.sessionsRepo.GetActiveSessions(r.Context(), claims.ID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	sessionsResp := make([]SessionResponse, 0, len(sessions))
+
+	for _, s := range sessions {
+		sessionsResp = append(sessionsResp, SessionResponse{
+			ID:         s.ID,
+			UserAgent:  s.UserAgent,
+			IP:         s.IP,
+			CreatedAt:  s.CreatedAt.Format(time.RFC3339),
+			LastSeenAt: s.LastSeenAt.Format(time.RFC3339),
+			Current:    s.ID == claims.SessionID,
+		})
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(sessionsResp)
+}
+
+func (h *UserHandler) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, r, problem.Unauthenticated)
+		return
+	}
+
+	pathValue := r.PathValue("id")
+	id, err := strconv.ParseInt(pathValue, 10, 64)
+	if err != nil {
+		problem.Write(w, r, problem.Validation(problem.Invalid("id", "id must be an integer")))
+		return
+	}
+
+	err = h.sessionsRepo.RevokeUserSession(r.Context(), id, claims.ID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "session revoked"})
+}
@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		n    int
+		want string
+	}{
+		{"short", "curl/8.0", 512, "curl/8.0"},
+		{"exact", strings.Repeat("a", 512), 512, strings.Repeat("a", 512)},
+		{"long", strings.Repeat("a", 600), 512, strings.Repeat("a", 512)},
+		{"multibyte", strings.Repeat("ж", 600), 512, strings.Repeat("ж", 512)},
+		{"empty", "", 512, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncate(tt.in, tt.n)
+			if got != tt.want {
+				t.Errorf("truncate() = %d runes, want %d", utf8.RuneCountInString(got), utf8.RuneCountInString(tt.want))
+			}
+			if !utf8.ValidString(got) {
+				t.Error("truncate() split a rune")
+			}
+		})
+	}
+}
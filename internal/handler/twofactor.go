@@ -0,0 +1,243 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/marcokz/movie-final/internal/auth"
+	"github.com/marcokz/movie-final/internal/entity"
+	"github.com/marcokz/movie-final/internal/middleware"
+	"github.com/marcokz/movie-final/internal/problem"
+)
+
+const recoveryCodesCount = 10
+
+type EnrollTOTPResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+func (h *UserHandler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, r, problem.Unauthenticated)
+		return
+	}
+
+	u, err := h.userRepo.GetUserByID(r.Context(), claims.ID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	err = h.userRepo.SetTOTPSecret(r.Context(), u.ID, secret)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(EnrollTOTPResponse{
+		Secret: secret,
+		URI:    auth.TOTPProvisioningURI(secret, u.Email),
+	})
+}
+
+type TOTPCodeRequest struct {
+	Code string `json:"code" validate:"required,max=32"`
+}
+
+func (h *UserHandler) VerifyTOTP(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, r, problem.Unauthenticated)
+		return
+	}
+
+	var req TOTPCodeRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	u, err := h.userRepo.GetUserByID(r.Context(), claims.ID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if u.TOTPEnabled {
+		problem.Write(w, r, entity.ErrTOTPAlreadyEnabled)
+		return
+	}
+	if u.TOTPSecret == "" {
+		problem.Write(w, r, problem.BadRequest("2fa enrollment not started"))
+		return
+	}
+
+	ok, err = h.checkTOTP(r.Context(), u, req.Code)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	if !ok {
+		problem.Write(w, r, problem.New(http.StatusUnauthorized, "invalid_code", "invalid code"))
+		return
+	}
+
+	codes, hashes, err := auth.NewRecoveryCodes(recoveryCodesCount)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	err = h.userRepo.EnableTOTP(r.Context(), u.ID, hashes)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	// Коды показываем один раз, в базе остаются только хеши
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string][]string{"recoverycodes": codes})
+}
+
+func (h *UserHandler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, r, problem.Unauthenticated)
+		return
+	}
+
+	var req TOTPCodeRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	u, err := h.userRepo.GetUserByID(r.Context(), claims.ID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if !u.TOTPEnabled {
+		problem.Write(w, r, problem.BadRequest("2fa is not enabled"))
+		return
+	}
+
+	ok, err = h.checkSecondFactor(r.Context(), u, req.Code)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	if !ok {
+		problem.Write(w, r, problem.New(http.StatusUnauthorized, "invalid_code", "invalid code"))
+		return
+	}
+
+	err = h.userRepo.DisableTOTP(r.Context(), u.ID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "2fa disabled"})
+}
+
+type LoginTOTPRequest struct {
+	MFAToken string `json:"mfatoken" validate:"required"`
+	Code     string `json:"code" validate:"required,max=32"`
+}
+
+// LoginTOTP второй шаг входа: принимает токен из Login и код из приложения или код восстановления.
+func (h *UserHandler) LoginTOTP(w http.ResponseWriter, r *http.Request) {
+	var req LoginTOTPRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	id, err := auth.ValidationMFAToken(req.MFAToken)
+	if err != nil {
+		problem.Write(w, r, problem.New(http.StatusUnauthorized, "invalid_token", "invalid or expired mfatoken"))
+		return
+	}
+
+	u, err := h.userRepo.GetUserByID(r.Context(), id)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if !u.TOTPEnabled {
+		problem.Write(w, r, problem.New(http.StatusUnauthorized, "invalid_token", "invalid or expired mfatoken"))
+		return
+	}
+	// mfatoken живёт дольше, чем решение администратора: блокировку и сброс пароля проверяем заново
+	if err := loginDenied(u); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	// Подбор кода ограничиваем так же, как подбор пароля
+	ip := clientIP(r)
+	wait, err := h.loginGuard.RetryAfter(r.Context(), u.Email, ip)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	if wait > 0 {
+		writeTooManyAttempts(w, r, wait)
+		return
+	}
+
+	ok, err := h.checkSecondFactor(r.Context(), u, req.Code)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	if !ok {
+		if err := h.loginGuard.Fail(r.Context(), u.ID, u.Email, ip); err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+		problem.Write(w, r, problem.New(http.StatusUnauthorized, "invalid_code", "invalid code"))
+		return
+	}
+
+	if err := h.loginGuard.Success(r.Context(), u.Email); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if err := h.startSession(w, r, u, u.Role); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	h.cancelDeletion(w, r, u.ID)
+}
+
+func (h *UserHandler) checkTOTP(ctx context.Context, u entity.User, code string) (bool, error) {
+	step, ok := auth.ValidateTOTP(u.TOTPSecret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	return h.userRepo.UseTOTPStep(ctx, u.ID, step)
+}
+
+func (h *UserHandler) checkSecondFactor(ctx context.Context, u entity.User, code string) (bool, error) {
+	ok, err := h.checkTOTP(ctx, u, code)
+	if err != nil || ok {
+		return ok, err
+	}
+	return h.userRepo.UseRecoveryCode(ctx, u.ID, auth.HashToken(strings.ToUpper(code)))
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/marcokz/movie-final/internal/auth"
+	"github.com/marcokz/movie-final/internal/entity"
+)
+
+type totpUserRepo struct {
+	UserRepo
+	user entity.User
+}
+
+func (f totpUserRepo) GetUserByID(context.Context, int64) (entity.User, error) {
+	return f.user, nil
+}
+
+// TestLoginTOTPRechecksAccount mfatoken выдан до блокировки или сброса пароля: сессии быть не должно
+func TestLoginTOTPRechecksAccount(t *testing.T) {
+	if err := auth.SetJWTKey([]byte("0123456789abcdef0123456789abcdef")); err != nil {
+		t.Fatal(err)
+	}
+	token, err := auth.GenerateMFAToken(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		user entity.User
+		code string
+	}{
+		{"password reset required", entity.User{ID: 1, TOTPEnabled: true, PasswordResetRequired: true}, "password_reset_required"},
+		{"banned", entity.User{ID: 1, TOTPEnabled: true, Status: entity.StatusBanned}, "account_suspended"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Сессии и счётчик попыток не подставлены: до них дойти не должно
+			h := NewUserHandler(totpUserRepo{user: tt.user}, nil, nil, nil, nil, nil, nil)
+
+			body, _ := json.Marshal(LoginTOTPRequest{MFAToken: token, Code: "123456"})
+			rec := httptest.NewRecorder()
+			h.LoginTOTP(rec, httptest.NewRequest(http.MethodPost, "/user/auth/2fa", bytes.NewReader(body)))
+
+			if rec.Code != http.StatusForbidden {
+				t.Fatalf("status = %d, want 403", rec.Code)
+			}
+			if got := problemCode(t, rec); got != tt.code {
+				t.Errorf("code = %q, want %q", got, tt.code)
+			}
+			if len(rec.Result().Cookies()) > 0 {
+				t.Error("session cookie was set")
+			}
+		})
+	}
+}
@@ -3,108 +3,332 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/mail"
 	"time"
 
 	"github.com/marcokz/movie-final/internal/auth"
 	"github.com/marcokz/movie-final/internal/entity"
+	"github.com/marcokz/movie-final/internal/images"
 	"github.com/marcokz/movie-final/internal/middleware"
+	"github.com/marcokz/movie-final/internal/problem"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
 type UserRepo interface {
-	CreateUser(ctx context.Context, email, password string) error
+	CreateUser(ctx context.Context, email, password, handle string) error
 	GetUserByEmail(ctx context.Context, loginOrEmail string) (entity.User, error)
-	GetUserByAge(ctx context.Context, minAge, maxAge int64) ([]entity.User, error)
-	GetUserByCountry(ctx context.Context, country string) ([]entity.User, error)
-	GetUserByCity(ctx context.Context, city string) ([]entity.User, error)
-	GetUserBySex(ctx context.Context, sex string) ([]entity.User, error)
+	GetUserByAge(ctx context.Context, viewerid, minAge, maxAge int64) ([]entity.User, error)
+	GetUserByCountry(ctx context.Context, viewerid int64, country string) ([]entity.User, error)
+	GetUserByCity(ctx context.Context, viewerid, cityid int64) ([]entity.User, error)
+	GetUserBySex(ctx context.Context, viewerid int64, sex string) ([]entity.User, error)
 	UpdateUserInfo(ctx context.Context, u entity.User) error
+	GetUserByID(ctx context.Context, id int64) (entity.User, error)
+	UpdatePassword(ctx context.Context, id int64, password string) error
+	CreateEmailChange(ctx context.Context, c entity.EmailChange) error
+	ConfirmEmailChange(ctx context.Context, userid int64, tokenHash string) (string, error)
+	SetTOTPSecret(ctx context.Context, id int64, secret string) error
+	EnableTOTP(ctx context.Context, id int64, recoveryHashes []string) error
+	DisableTOTP(ctx context.Context, id int64) error
+	UseTOTPStep(ctx context.Context, id, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, id int64, codeHash string) (bool, error)
+	SearchUsers(ctx context.Context, f entity.UserFilter) ([]entity.UserSearchResult, int, error)
+	GetUserInfo(ctx context.Context, id int64) (entity.User, error)
+	GetPublicProfile(ctx context.Context, viewerid, id int64) (entity.Profile, error)
+	PatchUserInfo(ctx context.Context, id int64, patch entity.UserPatch) error
+	GetPrivacy(ctx context.Context, id int64) (entity.Privacy, error)
+	UpdatePrivacy(ctx context.Context, id int64, pr entity.Privacy) error
+	RequestDeletion(ctx context.Context, id int64) error
+	CancelDeletion(ctx context.Context, id int64) (bool, error)
+	ExportUserData(ctx context.Context, id int64) (entity.UserExport, error)
+	ResetPassword(ctx context.Context, tokenHash, password string) (int64, error)
+	RenewPasswordReset(ctx context.Context, r entity.PasswordReset) (bool, error)
+	SetAvatar(ctx context.Context, id int64, hash string) error
+	SetHandle(ctx context.Context, id int64, handle string) error
+	ResolveHandle(ctx context.Context, handle string) (int64, string, error)
+}
+
+type SessionsRepo interface {
+	CreateSession(ctx context.Context, s entity.Session) (int64, error)
+	GetActiveSessions(ctx context.Context, userid int64) ([]entity.Session, error)
+	RevokeSession(ctx context.Context, id int64) error
+	RevokeUserSession(ctx context.Context, id, userid int64) error
+	RevokeOtherSessions(ctx context.Context, userid, keepID int64) error
+	RevokeAllSessions(ctx context.Context, userid int64) error
+}
+
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
 }
 
 type UserHandler struct {
-	userRepo UserRepo
+	userRepo       UserRepo
+	sessionsRepo   SessionsRepo
+	mailer         Mailer
+	loginGuard     *LoginGuard
+	passwordPolicy *auth.PasswordPolicy
+	locationsRepo  LocationsRepo
+	images         *ImageUploader
 }
 
-func NewUserHandler(u UserRepo) *UserHandler {
-	return &UserHandler{userRepo: u}
+func NewUserHandler(u UserRepo, s SessionsRepo, m Mailer, g *LoginGuard, p *auth.PasswordPolicy, l LocationsRepo, i *ImageUploader) *UserHandler {
+	return &UserHandler{userRepo: u, sessionsRepo: s, mailer: m, loginGuard: g, passwordPolicy: p, locationsRepo: l, images: i}
 }
 
 type RegisterRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required,email,max=254"`
+	Password string `json:"password" validate:"required"`
+	Handle   string `json:"handle"` // необязательный, можно выбрать позже
 }
 
+// User профиль в ответах
 type User struct {
 	ID          int64
+	Handle      string `json:",omitempty"` // профиль доступен по GET /users/@{handle}
 	Name        string
 	Surname     string
+	Avatar      *ImageURLs `json:",omitempty"`
 	Sex         string
 	DateOfBirth string
-	Country     string
+	AgeRange    string `json:",omitempty"`
+	Country     string // ISO 3166-1 alpha-2
+	CountryName string
+	CityID      int64
 	City        string
 }
 
+// UserUpdateRequest тело PUT /user/update: заменяет все поля профиля. Имена полей
+// те же, что в ответе User.
+type UserUpdateRequest struct {
+	Name        string `validate:"max=100"`
+	Surname     string `validate:"max=100"`
+	Sex         string `validate:"omitempty,oneof=male female other"`
+	DateOfBirth string `validate:"required,date,past"`
+	Country     string `validate:"max=100"` // ISO 3166-1 alpha-2 или название
+	CityID      int64  `validate:"min=0"`   // id из GET /locations/cities
+}
+
+func userResponse(u entity.User) User {
+	return User{
+		ID:          u.ID,
+		Handle:      u.Handle,
+		Name:        u.Name,
+		Surname:     u.Surname,
+		Avatar:      imageURLs(u.Avatar, images.Avatar),
+		Sex:         u.Sex,
+		DateOfBirth: formatDate(u.DateOfBirth),
+		AgeRange:    u.AgeRange,
+		Country:     u.Country,
+		CountryName: u.CountryName,
+		CityID:      u.CityID,
+		City:        u.City,
+	}
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
 func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	var regReq RegisterRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&regReq); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+	if !decodeJSON(w, r, &regReq) {
 		return
 	}
 
 	email, err := mail.ParseAddress(regReq.Email)
 	if err != nil {
-		http.Error(w, "Incorrect email", http.StatusBadRequest)
+		problem.Write(w, r, problem.Validation(problem.Invalid("email", "email is not a valid address")))
 		return
 	}
 
-	err = h.userRepo.CreateUser(context.Background(), email.Address, regReq.Password)
+	if err := h.passwordPolicy.Validate(regReq.Password); err != nil {
+		problem.Write(w, r, problem.InvalidField("password", err))
+		return
+	}
+
+	var handle string
+	if regReq.Handle != "" {
+		handle, err = entity.NormalizeHandle(regReq.Handle)
+		if err != nil {
+			problem.Write(w, r, problem.InvalidField("handle", err))
+			return
+		}
+	}
+
+	err = h.userRepo.CreateUser(context.Background(), email.Address, regReq.Password, handle)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		problem.Write(w, r, err)
 		return
 	}
+	usersRegistered.Inc()
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]string{"message": "user create successfully"})
 }
 
 type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required,max=254"`
+	Password string `json:"password" validate:"required"`
 }
 
 func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var request LoginRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+	if !decodeJSON(w, r, &request) {
 		return
 	}
 
-	u, err := h.userRepo.GetUserByEmail(context.Background(), request.Email)
+	ip := clientIP(r)
+
+	wait, err := h.loginGuard.RetryAfter(r.Context(), request.Email, ip)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error:": err.Error()})
+		problem.Write(w, r, err)
+		return
+	}
+	if wait > 0 {
+		writeTooManyAttempts(w, r, wait)
+		return
+	}
+
+	u, err := h.userRepo.GetUserByEmail(context.Background(), request.Email)
+	if err != nil && !errors.Is(err, entity.ErrUserNotFound) {
+		problem.Write(w, r, err)
 		return
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(request.Password))
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(map[string]string{"error:": "invalid password"})
+		u = entity.User{}
+	}
+
+	// Неизвестный email считаем такой же неудачной попыткой, как неверный пароль
+	if !checkPassword(u.Password, request.Password) {
+		if err := h.loginGuard.Fail(r.Context(), u.ID, request.Email, ip); err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+		problem.Write(w, r, problem.New(http.StatusUnauthorized, "invalid_credentials", "invalid email or password"))
+		return
+	}
+
+	if err := h.loginGuard.Success(r.Context(), request.Email); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	h.completeLogin(w, r, u)
+}
+
+// dummyPasswordHash сравнивается с паролем, когда аккаунта нет или у него нет пароля.
+// Иначе такой ответ приходит заметно быстрее, и по времени видно, зарегистрирован ли email.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+// checkPassword bcrypt выполняется всегда, даже без хэша
+func checkPassword(hash, password string) bool {
+	if hash == "" {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// completeLogin выдаёт сессию пользователю, который прошёл первый фактор
+// (пароль или внешний провайдер).
+func (h *UserHandler) completeLogin(w http.ResponseWriter, r *http.Request, u entity.User) {
+	if err := loginDenied(u); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	// Второй фактор включён: сессию не создаём, пока не придёт код
+	if u.TOTPEnabled {
+		mfaToken, err := auth.GenerateMFAToken(u.ID)
+		if err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"message": "2fa required", "mfatoken": mfaToken})
+		return
+	}
+
+	// Админ и модератор без 2FA входят как обычный пользователь, пока не подключат второй фактор
+	role := u.Role
+	if entity.RoleRequiresMFA(role) {
+		role = entity.RoleUser
+	}
+
+	if err := h.startSession(w, r, u, role); err != nil {
+		problem.Write(w, r, err)
 		return
 	}
 
-	tokenString, err := auth.GenerateJWT(u.ID, u.Email, "user")
+	h.cancelDeletion(w, r, u.ID)
+}
+
+// loginDenied ошибка, если пользователю нельзя выдать сессию, хотя факторы проверены.
+// Проверяется на каждом шаге входа: между паролем и кодом 2FA аккаунт могли заблокировать.
+func loginDenied(u entity.User) error {
+	if u.Blocked(time.Now()) {
+		p := problem.From(entity.ErrAccountSuspended).With("account_status", u.Status)
+		if u.StatusReason != "" {
+			p = p.With("reason", u.StatusReason)
+		}
+		if u.StatusUntil != nil {
+			p = p.With("until", u.StatusUntil.Format(time.RFC3339))
+		}
+		return p
+	}
+
+	// Администратор потребовал сменить пароль: войти можно только после сброса по ссылке из письма
+	if u.PasswordResetRequired {
+		return entity.ErrPasswordResetRequired
+	}
+
+	return nil
+}
+
+// cancelDeletion вход в аккаунт в течение срока ожидания отменяет его удаление.
+// Завершает ответ на вход, сессия к этому моменту уже выдана.
+func (h *UserHandler) cancelDeletion(w http.ResponseWriter, r *http.Request, id int64) {
+	cancelled, err := h.userRepo.CancelDeletion(r.Context(), id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": "failed to generate token"})
+		problem.Write(w, r, err)
 		return
 	}
 
+	message := "logged in"
+	if cancelled {
+		message = "logged in, account deletion cancelled"
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+func (h *UserHandler) startSession(w http.ResponseWriter, r *http.Request, u entity.User, role string) error {
+	sessionID, err := h.sessionsRepo.CreateSession(r.Context(), entity.Session{
+		UserID:    u.ID,
+		UserAgent: truncate(r.UserAgent(), maxUserAgentLength),
+		IP:        clientIP(r),
+	})
+	if err != nil {
+		return err
+	}
+
+	h.loginGuard.Record(r.Context(), entity.AuditEvent{UserID: u.ID, IP: clientIP(r), Event: entity.AuditLogin})
+
+	tokenString, err := auth.GenerateJWT(u.ID, sessionID, u.Email, role)
+	if err != nil {
+		return err
+	}
+	loginSucceeded.Inc()
+
 	http.SetCookie(w, &http.Cookie{
 		Name:     "auth_token",
 		Value:    tokenString,
@@ -115,10 +339,20 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		SameSite: http.SameSiteStrictMode, // Защита от CSRF
 	})
 
-	w.WriteHeader(http.StatusOK)
+	return nil
 }
 
 func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(string(middleware.UserContextKey)); err == nil {
+		if claims, err := auth.ValidationJWT(cookie.Value); err == nil {
+			// Без отзыва токен остаётся рабочим, удаление cookie этого не исправит
+			if err := h.sessionsRepo.RevokeSession(r.Context(), claims.SessionID); err != nil {
+				problem.Write(w, r, err)
+				return
+			}
+		}
+	}
+
 	http.SetCookie(w, &http.Cookie{
 		Name:    "auth_token",
 		Expires: time.Now().Add(-time.Hour),
@@ -129,44 +363,33 @@ func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
 }
 
 type Age struct {
-	MinAge int64 `json:"minage"`
-	MaxAge int64 `json:"maxage"`
+	MinAge int64 `json:"minage" validate:"min=0,max=150"`
+	MaxAge int64 `json:"maxage" validate:"min=0,max=150,gtefield=MinAge"`
 }
 
 func (h *UserHandler) GetUserByAge(w http.ResponseWriter, r *http.Request) {
-	_, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
+		problem.Write(w, r, problem.Unauthenticated)
 		return
 	}
 
 	var age Age
 
-	if err := json.NewDecoder(r.Body).Decode(&age); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+	if !decodeJSON(w, r, &age) {
 		return
 	}
 
-	users, err := h.userRepo.GetUserByAge(r.Context(), age.MinAge, age.MaxAge)
+	users, err := h.userRepo.GetUserByAge(r.Context(), claims.ID, age.MinAge, age.MaxAge)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		problem.Write(w, r, err)
 		return
 	}
 
 	userResp := make([]User, 0, len(users))
 
 	for _, user := range users {
-		u := User{
-			ID:          user.ID,
-			Name:        user.Name,
-			Surname:     user.Surname,
-			Sex:         user.Sex,
-			DateOfBirth: user.DateOfBirth.Format("2006-01-02"),
-			Country:     user.Country,
-			City:        user.City,
-		}
-		userResp = append(userResp, u)
-
+		userResp = append(userResp, userResponse(user))
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -174,42 +397,37 @@ func (h *UserHandler) GetUserByAge(w http.ResponseWriter, r *http.Request) {
 }
 
 type GetByCountry struct {
-	Country string `json:"country"`
+	Country string `json:"country" validate:"required,max=100"`
 }
 
 func (h *UserHandler) GetUserByCountry(w http.ResponseWriter, r *http.Request) {
-	_, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
+		problem.Write(w, r, problem.Unauthenticated)
 		return
 	}
 
 	var country GetByCountry
-	if err := json.NewDecoder(r.Body).Decode(&country); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+	if !decodeJSON(w, r, &country) {
+		return
+	}
+
+	code, err := h.locationsRepo.ResolveCountry(r.Context(), country.Country)
+	if err != nil {
+		problem.Write(w, r, err)
 		return
 	}
 
-	users, err := h.userRepo.GetUserByCountry(r.Context(), country.Country)
+	users, err := h.userRepo.GetUserByCountry(r.Context(), claims.ID, code)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		problem.Write(w, r, err)
 		return
 	}
 
 	userResp := make([]User, 0, len(users))
 
 	for _, user := range users {
-		u := User{
-			ID:          user.ID,
-			Name:        user.Name,
-			Surname:     user.Surname,
-			Sex:         user.Sex,
-			DateOfBirth: user.DateOfBirth.Format("2006-01-02"),
-			Country:     user.Country,
-			City:        user.City,
-		}
-		userResp = append(userResp, u)
-
+		userResp = append(userResp, userResponse(user))
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -217,41 +435,31 @@ func (h *UserHandler) GetUserByCountry(w http.ResponseWriter, r *http.Request) {
 }
 
 type GetCity struct {
-	City string `json:"city"`
+	CityID int64 `json:"cityid" validate:"required,min=1"`
 }
 
 func (h *UserHandler) GetUserByCity(w http.ResponseWriter, r *http.Request) {
-	_, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
+		problem.Write(w, r, problem.Unauthenticated)
 		return
 	}
 
 	var c GetCity
-	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+	if !decodeJSON(w, r, &c) {
 		return
 	}
 
-	users, err := h.userRepo.GetUserByCity(r.Context(), c.City)
+	users, err := h.userRepo.GetUserByCity(r.Context(), claims.ID, c.CityID)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		problem.Write(w, r, err)
 		return
 	}
 
 	userResp := make([]User, 0, len(users))
 
 	for _, u := range users {
-		city := User{
-			ID:          u.ID,
-			Name:        u.Name,
-			Surname:     u.Surname,
-			Sex:         u.Sex,
-			DateOfBirth: u.DateOfBirth.Format("2006-01-02"),
-			Country:     u.Country,
-			City:        u.City,
-		}
-		userResp = append(userResp, city)
+		userResp = append(userResp, userResponse(u))
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -259,42 +467,32 @@ func (h *UserHandler) GetUserByCity(w http.ResponseWriter, r *http.Request) {
 }
 
 type UserBySex struct {
-	Sex string `json:"sex"`
+	Sex string `json:"sex" validate:"required,oneof=male female other"`
 }
 
 func (h *UserHandler) GetUserBySex(w http.ResponseWriter, r *http.Request) {
-	_, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
+		problem.Write(w, r, problem.Unauthenticated)
 		return
 	}
 
 	var userBySex UserBySex
 
-	if err := json.NewDecoder(r.Body).Decode(&userBySex); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+	if !decodeJSON(w, r, &userBySex) {
 		return
 	}
 
-	users, err := h.userRepo.GetUserBySex(r.Context(), userBySex.Sex)
+	users, err := h.userRepo.GetUserBySex(r.Context(), claims.ID, userBySex.Sex)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		problem.Write(w, r, err)
 		return
 	}
 
 	userResp := make([]User, 0, len(users))
 
 	for _, u := range users {
-		city := User{
-			ID:          u.ID,
-			Name:        u.Name,
-			Surname:     u.Surname,
-			Sex:         u.Sex,
-			DateOfBirth: u.DateOfBirth.Format("2006-01-02"),
-			Country:     u.Country,
-			City:        u.City,
-		}
-		userResp = append(userResp, city)
+		userResp = append(userResp, userResponse(u))
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -304,21 +502,25 @@ func (h *UserHandler) GetUserBySex(w http.ResponseWriter, r *http.Request) {
 func (h *UserHandler) UpdateUserInfo(w http.ResponseWriter, r *http.Request) {
 	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
+		problem.Write(w, r, problem.Unauthenticated)
 		return
 	}
 
-	var update User
+	var update UserUpdateRequest
 
-	err := json.NewDecoder(r.Body).Decode(&update)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+	if !decodeJSON(w, r, &update) {
 		return
 	}
 
 	parsedDate, err := time.Parse("2006-01-02", update.DateOfBirth)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		problem.Write(w, r, problem.Validation(problem.Invalid("DateOfBirth", "DateOfBirth must be a date in YYYY-MM-DD format")))
+		return
+	}
+
+	country, err := resolveLocation(r.Context(), h.locationsRepo, update.Country, update.CityID)
+	if err != nil {
+		problem.Write(w, r, err)
 		return
 	}
 
@@ -328,16 +530,242 @@ func (h *UserHandler) UpdateUserInfo(w http.ResponseWriter, r *http.Request) {
 		Surname:     update.Surname,
 		Sex:         update.Sex,
 		DateOfBirth: parsedDate,
-		Country:     update.Country,
-		City:        update.City,
+		Country:     country,
+		CityID:      update.CityID,
 	}
 
 	err = h.userRepo.UpdateUserInfo(r.Context(), u)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		problem.Write(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "user update successfully"})
 }
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentpassword" validate:"required"`
+	NewPassword     string `json:"newpassword" validate:"required"`
+}
+
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, r, problem.Unauthenticated)
+		return
+	}
+
+	var req ChangePasswordRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := h.passwordPolicy.Validate(req.NewPassword); err != nil {
+		problem.Write(w, r, problem.InvalidField("newpassword", err))
+		return
+	}
+
+	u, err := h.userRepo.GetUserByID(r.Context(), claims.ID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(req.CurrentPassword))
+	if err != nil {
+		problem.Write(w, r, problem.New(http.StatusUnauthorized, "invalid_credentials", "invalid password"))
+		return
+	}
+
+	err = h.userRepo.UpdatePassword(r.Context(), claims.ID, req.NewPassword)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	err = h.sessionsRepo.RevokeOtherSessions(r.Context(), claims.ID, claims.SessionID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "password update successfully"})
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"newpassword" validate:"required"`
+}
+
+// ResetPassword сброс пароля по токену из письма, которое отправляется,
+// когда администратор требует сменить пароль.
+func (h *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := h.passwordPolicy.Validate(req.NewPassword); err != nil {
+		problem.Write(w, r, problem.InvalidField("newpassword", err))
+		return
+	}
+
+	userid, err := h.userRepo.ResetPassword(r.Context(), auth.HashToken(req.Token), req.NewPassword)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	h.loginGuard.Record(r.Context(), entity.AuditEvent{UserID: userid, IP: clientIP(r), Event: entity.AuditPasswordReset})
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "password reset successfully"})
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"email" validate:"required,email,max=254"`
+}
+
+// RequestPasswordReset повторно отправляет код сброса, если администратор потребовал
+// сменить пароль, а прошлый код истёк. Ответ одинаковый для любого email, чтобы по нему
+// нельзя было узнать, есть ли такой аккаунт.
+func (h *UserHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req PasswordResetRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	u, err := h.userRepo.GetUserByEmail(r.Context(), req.Email)
+	if err != nil && !errors.Is(err, entity.ErrUserNotFound) {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if err == nil && u.PasswordResetRequired {
+		token, tokenHash, err := auth.NewToken()
+		if err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+
+		renewed, err := h.userRepo.RenewPasswordReset(r.Context(), entity.PasswordReset{
+			UserID:    u.ID,
+			TokenHash: tokenHash,
+			ExpiresAt: time.Now().Add(passwordResetTTL),
+		})
+		if err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+
+		if renewed {
+			err = h.mailer.Send(r.Context(), u.Email, "Password reset required", "Your password reset code: "+token)
+			if err != nil {
+				problem.Write(w, r, err)
+				return
+			}
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "if the account requires a password reset, a new code has been sent"})
+}
+
+type ChangeEmailRequest struct {
+	Email    string `json:"email" validate:"required,email,max=254"`
+	Password string `json:"password"`
+}
+
+// Через сколько истекает ссылка подтверждения нового email
+const emailChangeTTL = time.Hour * 24
+
+func (h *UserHandler) ChangeEmail(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, r, problem.Unauthenticated)
+		return
+	}
+
+	var req ChangeEmailRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	email, err := mail.ParseAddress(req.Email)
+	if err != nil {
+		problem.Write(w, r, problem.Validation(problem.Invalid("email", "email is not a valid address")))
+		return
+	}
+
+	u, err := h.userRepo.GetUserByID(r.Context(), claims.ID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(req.Password))
+	if err != nil {
+		problem.Write(w, r, problem.New(http.StatusUnauthorized, "invalid_credentials", "invalid password"))
+		return
+	}
+
+	token, tokenHash, err := auth.NewToken()
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	err = h.userRepo.CreateEmailChange(r.Context(), entity.EmailChange{
+		UserID:    claims.ID,
+		NewEmail:  email.Address,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(emailChangeTTL),
+	})
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	err = h.mailer.Send(r.Context(), email.Address, "Confirm your new email", "Your confirmation code: "+token)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"message": "confirmation sent to the new email"})
+}
+
+type ConfirmEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+func (h *UserHandler) ConfirmEmail(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, r, problem.Unauthenticated)
+		return
+	}
+
+	var req ConfirmEmailRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	_, err := h.userRepo.ConfirmEmailChange(r.Context(), claims.ID, auth.HashToken(req.Token))
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	err = h.sessionsRepo.RevokeOtherSessions(r.Context(), claims.ID, claims.SessionID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "email update successfully"})
+}
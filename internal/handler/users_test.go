@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/marcokz/movie-final/internal/auth"
+	"github.com/marcokz/movie-final/internal/middleware"
+)
+
+type revokeSessionsRepo struct {
+	SessionsRepo
+	err     error
+	revoked []int64
+}
+
+func (f *revokeSessionsRepo) RevokeSession(_ context.Context, id int64) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.revoked = append(f.revoked, id)
+	return nil
+}
+
+func TestLogout(t *testing.T) {
+	if err := auth.SetJWTKey([]byte("0123456789abcdef0123456789abcdef")); err != nil {
+		t.Fatal(err)
+	}
+	token, err := auth.GenerateJWT(1, 7, "dev@example.com", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		err         error
+		status      int
+		wantRevoked bool
+		wantCleared bool
+	}{
+		{"revoked", nil, http.StatusOK, true, true},
+		{"revoke failed", errors.New("connection reset"), http.StatusInternalServerError, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessions := &revokeSessionsRepo{err: tt.err}
+			h := NewUserHandler(nil, sessions, nil, nil, nil, nil, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/user/logout", nil)
+			req.AddCookie(&http.Cookie{Name: string(middleware.UserContextKey), Value: token})
+			rec := httptest.NewRecorder()
+			h.Logout(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := len(sessions.revoked) == 1 && sessions.revoked[0] == 7; got != tt.wantRevoked {
+				t.Errorf("revoked = %v, want session 7 revoked: %v", sessions.revoked, tt.wantRevoked)
+			}
+			if got := len(rec.Result().Cookies()) > 0; got != tt.wantCleared {
+				t.Errorf("cookie cleared = %v, want %v", got, tt.wantCleared)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+// Package images загрузка картинок: проверка формата по содержимому, ограничения
+// размера, уменьшенные копии и хранение по sha256 содержимого, чтобы одинаковые
+// файлы хранились один раз.
+package images
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/marcokz/movie-final/internal/blob"
+	"github.com/marcokz/movie-final/internal/entity"
+)
+
+// Картинка больше этого числа пикселей может разжаться в гигабайты памяти
+const maxPixels = 40_000_000
+
+const jpegQuality = 85
+
+// Variant уменьшенная копия. Если Height == 0, сохраняются пропорции по ширине,
+// иначе картинка обрезается по центру до Width x Height.
+type Variant struct {
+	Name   string
+	Width  int
+	Height int
+}
+
+// Kind назначение картинки: ограничения и набор уменьшенных копий
+type Kind struct {
+	MaxBytes int64
+	Variants []Variant
+}
+
+var Avatar = Kind{
+	MaxBytes: 5 << 20,
+	Variants: []Variant{{Name: "64", Width: 64, Height: 64}, {Name: "256", Width: 256, Height: 256}},
+}
+
+var Poster = Kind{
+	MaxBytes: 10 << 20,
+	Variants: []Variant{{Name: "w154", Width: 154}, {Name: "w342", Width: 342}},
+}
+
+// Поддерживаем только то, что умеет декодировать стандартная библиотека
+var decoders = map[string]func(io.Reader) (image.Image, error){
+	"image/jpeg": jpeg.Decode,
+	"image/png":  png.Decode,
+	"image/gif":  gif.Decode,
+}
+
+var configDecoders = map[string]func(io.Reader) (image.Config, error){
+	"image/jpeg": jpeg.DecodeConfig,
+	"image/png":  png.DecodeConfig,
+	"image/gif":  gif.DecodeConfig,
+}
+
+// Имя картинки в URL: хэш оригинала и, для уменьшенной копии, "_" и имя варианта
+var nameRe = regexp.MustCompile(`^[0-9a-f]{64}(_[a-z0-9]+)?$`)
+
+type Store struct {
+	blobs blob.Store
+}
+
+func NewStore(b blob.Store) *Store {
+	return &Store{blobs: b}
+}
+
+func key(name string) string {
+	return name[:2] + "/" + name
+}
+
+// VariantName имя уменьшенной копии картинки hash
+func VariantName(hash, variant string) string {
+	return hash + "_" + variant
+}
+
+// Save проверяет картинку, сохраняет оригинал и недостающие уменьшенные копии.
+// Повторная загрузка того же файла ничего не пишет.
+func (s *Store) Save(ctx context.Context, data []byte, kind Kind) (entity.Image, error) {
+	if int64(len(data)) > kind.MaxBytes {
+		return entity.Image{}, entity.ErrImageTooLarge
+	}
+
+	// Формат определяем по содержимому, заголовку Content-Type клиента не верим
+	contentType := http.DetectContentType(data)
+	decodeConfig, ok := configDecoders[contentType]
+	if !ok {
+		return entity.Image{}, entity.ErrUnsupportedImage
+	}
+
+	cfg, err := decodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 {
+		return entity.Image{}, entity.ErrUnsupportedImage
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return entity.Image{}, entity.ErrImageTooLarge
+	}
+
+	sum := sha256.Sum256(data)
+	img := entity.Image{
+		Hash:        hex.EncodeToString(sum[:]),
+		ContentType: contentType,
+		Width:       cfg.Width,
+		Height:      cfg.Height,
+		Size:        int64(len(data)),
+	}
+
+	var decoded image.Image
+	for _, v := range kind.Variants {
+		k := key(VariantName(img.Hash, v.Name))
+		exists, err := s.blobs.Exists(ctx, k)
+		if err != nil {
+			return entity.Image{}, err
+		}
+		if exists {
+			continue
+		}
+
+		if decoded == nil {
+			if decoded, err = decoders[contentType](bytes.NewReader(data)); err != nil {
+				return entity.Image{}, entity.ErrUnsupportedImage
+			}
+		}
+
+		thumb, err := encode(thumbnail(decoded, v), contentType)
+		if err != nil {
+			return entity.Image{}, err
+		}
+		if err := s.blobs.Put(ctx, k, bytes.NewReader(thumb)); err != nil {
+			return entity.Image{}, err
+		}
+	}
+
+	exists, err := s.blobs.Exists(ctx, key(img.Hash))
+	if err != nil {
+		return entity.Image{}, err
+	}
+	if !exists {
+		if err := s.blobs.Put(ctx, key(img.Hash), bytes.NewReader(data)); err != nil {
+			return entity.Image{}, err
+		}
+	}
+
+	return img, nil
+}
+
+// Open открывает картинку по имени из URL.
+func (s *Store) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	if !nameRe.MatchString(name) {
+		return nil, blob.ErrNotFound
+	}
+	return s.blobs.Get(ctx, key(name))
+}
+
+func thumbnail(img image.Image, v Variant) image.Image {
+	b := img.Bounds()
+
+	if v.Height == 0 {
+		w := min(v.Width, b.Dx())
+		h := max(1, b.Dy()*w/b.Dx())
+		return resize(img, b, w, h)
+	}
+
+	crop := centerCrop(b, v.Width, v.Height)
+	w, h := v.Width, v.Height
+	if crop.Dx() < w {
+		w, h = crop.Dx(), crop.Dy()
+	}
+	return resize(img, crop, w, h)
+}
+
+// encode JPEG остаётся JPEG, остальное сохраняем в PNG, чтобы не потерять прозрачность.
+func encode(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if strings.HasSuffix(contentType, "jpeg") {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	return buf.Bytes(), err
+}
@@ -0,0 +1,163 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"testing"
+
+	"github.com/marcokz/movie-final/internal/blob"
+	"github.com/marcokz/movie-final/internal/entity"
+)
+
+// memoryBlobs blob.Store в памяти, считает записи
+type memoryBlobs struct {
+	data map[string][]byte
+	puts int
+}
+
+func (m *memoryBlobs) Put(_ context.Context, key string, r io.Reader) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	m.data[key] = b
+	m.puts++
+	return nil
+}
+
+func (m *memoryBlobs) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	b, ok := m.data[key]
+	if !ok {
+		return nil, blob.ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (m *memoryBlobs) Exists(_ context.Context, key string) (bool, error) {
+	_, ok := m.data[key]
+	return ok, nil
+}
+
+func (m *memoryBlobs) Delete(_ context.Context, key string) error {
+	delete(m.data, key)
+	return nil
+}
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		img.Set(x, 0, color.RGBA{R: uint8(x), A: 255})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestSaveRejects(t *testing.T) {
+	// Заголовок GIF 65535 x 65535 без данных: до декодирования пикселей дойти не должно
+	hugeGIF := []byte("GIF89a\xff\xff\xff\xff\x00\x00\x00")
+
+	tests := []struct {
+		name string
+		data []byte
+		kind Kind
+		err  error
+	}{
+		{"over the byte limit", testPNG(t, 10, 10), Kind{MaxBytes: 10}, entity.ErrImageTooLarge},
+		{"over the pixel limit", hugeGIF, Poster, entity.ErrImageTooLarge},
+		{"not an image", []byte("<svg xmlns='http://www.w3.org/2000/svg'/>"), Poster, entity.ErrUnsupportedImage},
+		{"broken png", testPNG(t, 10, 10)[:40], Poster, entity.ErrUnsupportedImage},
+	}
+	for _, tt := range tests {
+		blobs := &memoryBlobs{data: map[string][]byte{}}
+		_, err := NewStore(blobs).Save(context.Background(), tt.data, tt.kind)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+		}
+		if blobs.puts != 0 {
+			t.Errorf("%s: %d blobs written", tt.name, blobs.puts)
+		}
+	}
+}
+
+func TestSaveDedupe(t *testing.T) {
+	ctx := context.Background()
+	blobs := &memoryBlobs{data: map[string][]byte{}}
+	s := NewStore(blobs)
+	data := testPNG(t, 600, 400)
+
+	img, err := s.Save(ctx, data, Avatar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.ContentType != "image/png" || img.Width != 600 || img.Height != 400 || img.Size != int64(len(data)) {
+		t.Errorf("image = %+v", img)
+	}
+	if blobs.puts != 1+len(Avatar.Variants) {
+		t.Fatalf("%d blobs written, want original and %d variants", blobs.puts, len(Avatar.Variants))
+	}
+
+	for _, v := range Avatar.Variants {
+		rc, err := s.Open(ctx, VariantName(img.Hash, v.Name))
+		if err != nil {
+			t.Fatalf("variant %s: %v", v.Name, err)
+		}
+		cfg, err := png.DecodeConfig(rc)
+		rc.Close()
+		if err != nil || cfg.Width != v.Width || cfg.Height != v.Height {
+			t.Errorf("variant %s = %dx%d, %v, want %dx%d", v.Name, cfg.Width, cfg.Height, err, v.Width, v.Height)
+		}
+	}
+
+	// Тот же файл ещё раз ничего не пишет и получает то же имя
+	again, err := s.Save(ctx, data, Avatar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Hash != img.Hash || blobs.puts != 1+len(Avatar.Variants) {
+		t.Errorf("second save: hash %s, %d blobs written", again.Hash, blobs.puts)
+	}
+
+	// Тот же файл как постер дописывает только его варианты
+	if _, err := s.Save(ctx, data, Poster); err != nil {
+		t.Fatal(err)
+	}
+	if want := 1 + len(Avatar.Variants) + len(Poster.Variants); blobs.puts != want {
+		t.Errorf("poster save: %d blobs written, want %d", blobs.puts, want)
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	tests := []struct {
+		src  image.Rectangle
+		v    Variant
+		w, h int
+	}{
+		// По ширине с сохранением пропорций
+		{image.Rect(0, 0, 300, 200), Variant{Width: 154}, 154, 102},
+		{image.Rect(0, 0, 200, 300), Variant{Width: 154}, 154, 231},
+		// Не увеличиваем
+		{image.Rect(0, 0, 100, 50), Variant{Width: 154}, 100, 50},
+		{image.Rect(0, 0, 1000, 1), Variant{Width: 154}, 154, 1},
+		// Обрезка по центру до квадрата
+		{image.Rect(0, 0, 300, 200), Variant{Width: 64, Height: 64}, 64, 64},
+		{image.Rect(0, 0, 200, 300), Variant{Width: 64, Height: 64}, 64, 64},
+		{image.Rect(0, 0, 100, 50), Variant{Width: 256, Height: 256}, 50, 50},
+		// Исходник со смещёнными границами
+		{image.Rect(10, 20, 310, 220), Variant{Width: 64, Height: 64}, 64, 64},
+	}
+	for _, tt := range tests {
+		got := thumbnail(image.NewRGBA(tt.src), tt.v).Bounds()
+		if got.Dx() != tt.w || got.Dy() != tt.h {
+			t.Errorf("thumbnail(%v, %+v) = %dx%d, want %dx%d", tt.src, tt.v, got.Dx(), got.Dy(), tt.w, tt.h)
+		}
+	}
+}
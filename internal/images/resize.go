@@ -0,0 +1,108 @@
+package images
+
+import (
+	"image"
+	"image/draw"
+	"math"
+)
+
+// contrib вклад пикселя исходника в пиксель результата
+type contrib struct {
+	index  int
+	weight float32
+}
+
+// areaWeights для уменьшения: каждый пиксель результата усредняет покрываемый им
+// отрезок исходника с учётом частично покрытых краёв.
+func areaWeights(dst, src int) [][]contrib {
+	scale := float64(src) / float64(dst)
+	out := make([][]contrib, dst)
+	for i := range out {
+		start, end := float64(i)*scale, float64(i+1)*scale
+		for j := int(math.Floor(start)); j < int(math.Ceil(end)) && j < src; j++ {
+			w := math.Min(end, float64(j+1)) - math.Max(start, float64(j))
+			if w > 0 {
+				out[i] = append(out[i], contrib{index: j, weight: float32(w / scale)})
+			}
+		}
+	}
+	return out
+}
+
+// resize уменьшает часть rect изображения src до размера w x h. Увеличение не делаем,
+// вызывающий сам ограничивает размер исходником. Усредняем в premultiplied RGBA,
+// чтобы прозрачные пиксели не давали тёмную кайму.
+func resize(src image.Image, rect image.Rectangle, w, h int) *image.RGBA {
+	sw, sh := rect.Dx(), rect.Dy()
+
+	in := image.NewRGBA(image.Rect(0, 0, sw, sh))
+	draw.Draw(in, in.Bounds(), src, rect.Min, draw.Src)
+
+	if sw == w && sh == h {
+		return in
+	}
+
+	// По горизонтали: sh строк по w пикселей
+	tmp := make([]float32, w*sh*4)
+	xw := areaWeights(w, sw)
+	for y := 0; y < sh; y++ {
+		row := in.Pix[y*in.Stride:]
+		for x, cs := range xw {
+			var r, g, b, a float32
+			for _, c := range cs {
+				p := row[c.index*4:]
+				r += float32(p[0]) * c.weight
+				g += float32(p[1]) * c.weight
+				b += float32(p[2]) * c.weight
+				a += float32(p[3]) * c.weight
+			}
+			t := tmp[(y*w+x)*4:]
+			t[0], t[1], t[2], t[3] = r, g, b, a
+		}
+	}
+
+	// По вертикали в результат
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+	yw := areaWeights(h, sh)
+	for y, cs := range yw {
+		row := out.Pix[y*out.Stride:]
+		for x := 0; x < w; x++ {
+			var r, g, b, a float32
+			for _, c := range cs {
+				t := tmp[(c.index*w+x)*4:]
+				r += t[0] * c.weight
+				g += t[1] * c.weight
+				b += t[2] * c.weight
+				a += t[3] * c.weight
+			}
+			p := row[x*4:]
+			p[0], p[1], p[2], p[3] = clamp(r), clamp(g), clamp(b), clamp(a)
+		}
+	}
+
+	return out
+}
+
+func clamp(v float32) uint8 {
+	v = float32(math.Round(float64(v)))
+	switch {
+	case v < 0:
+		return 0
+	case v > 255:
+		return 255
+	}
+	return uint8(v)
+}
+
+// centerCrop наибольший прямоугольник с соотношением сторон w:h по центру b.
+func centerCrop(b image.Rectangle, w, h int) image.Rectangle {
+	sw, sh := b.Dx(), b.Dy()
+	if sw*h > sh*w {
+		cw := sh * w / h
+		x := b.Min.X + (sw-cw)/2
+		return image.Rect(x, b.Min.Y, x+cw, b.Max.Y)
+	}
+	ch := sw * h / w
+	y := b.Min.Y + (sh-ch)/2
+	return image.Rect(b.Min.X, y, b.Max.X, y+ch)
+}
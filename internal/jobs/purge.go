@@ -0,0 +1,59 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+type UserPurger interface {
+	PurgeDeletedUsers(ctx context.Context, grace time.Duration) (int, error)
+}
+
+// PurgeDeletedUsers раз в interval удаляет аккаунты, у которых истёк срок на отмену удаления.
+// Работает, пока не отменён ctx.
+func PurgeDeletedUsers(ctx context.Context, p UserPurger, interval, grace time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := p.PurgeDeletedUsers(ctx, grace)
+		if err != nil {
+			slog.ErrorContext(ctx, "purge deleted users", "err", err)
+		} else if n > 0 {
+			slog.InfoContext(ctx, "purged deleted users", "count", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type LoginPurger interface {
+	PurgeExpiredLogins(ctx context.Context) (int, error)
+}
+
+// PurgeExpiredLogins раз в interval удаляет просроченные входы через внешних провайдеров.
+// Работает, пока не отменён ctx.
+func PurgeExpiredLogins(ctx context.Context, p LoginPurger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := p.PurgeExpiredLogins(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "purge expired oidc logins", "err", err)
+		} else if n > 0 {
+			slog.InfoContext(ctx, "purged expired oidc logins", "count", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Package locale язык, на котором отдаём названия стран и городов.
+package locale
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// Default язык, если клиент ничего не попросил или перевода нет
+const Default = "en"
+
+type contextKey struct{}
+
+func WithLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, contextKey{}, lang)
+}
+
+// Language язык запроса из контекста, по умолчанию Default.
+func Language(ctx context.Context) string {
+	if lang, ok := ctx.Value(contextKey{}).(string); ok && lang != "" {
+		return lang
+	}
+	return Default
+}
+
+// Parse выбирает язык из заголовка Accept-Language ("ru-RU,ru;q=0.9,en;q=0.8").
+// Берётся только основной подтег с наибольшим весом, регион не учитывается,
+// потому что справочник GeoNames хранит переводы по языкам.
+func Parse(header string) string {
+	best, bestQ := "", -1.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		tag, _, _ = strings.Cut(tag, "-")
+		if tag == "" || tag == "*" || len(tag) > 7 {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = f
+		}
+		if q > bestQ {
+			best, bestQ = tag, q
+		}
+	}
+	if best == "" || bestQ <= 0 {
+		return Default
+	}
+	return best
+}
@@ -0,0 +1,92 @@
+// Package logging структурные логи на log/slog. Записи, сделанные с контекстом
+// запроса (slog.InfoContext и т.п.), получают request_id и user_id автоматически.
+package logging
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+)
+
+// New логгер с уровнем debug, info, warn или error и форматом text или json
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, errors.New("unknown log level " + level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	var h slog.Handler
+	switch strings.ToLower(format) {
+	case "text":
+		h = slog.NewTextHandler(w, opts)
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, errors.New("unknown log format " + format)
+	}
+
+	return slog.New(contextHandler{h}), nil
+}
+
+type requestKey struct{}
+
+// request сведения о запросе. Пользователь становится известен только после
+// авторизации глубже по цепочке middleware, поэтому поле меняется на месте.
+type request struct {
+	id     string
+	userID atomic.Int64
+}
+
+// WithRequestID начинает запрос с идентификатором id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestKey{}, &request{id: id})
+}
+
+func RequestID(ctx context.Context) string {
+	if req, ok := ctx.Value(requestKey{}).(*request); ok {
+		return req.id
+	}
+	return ""
+}
+
+// SetUserID запоминает авторизованного пользователя для логов этого запроса
+func SetUserID(ctx context.Context, id int64) {
+	if req, ok := ctx.Value(requestKey{}).(*request); ok {
+		req.userID.Store(id)
+	}
+}
+
+func UserID(ctx context.Context) int64 {
+	if req, ok := ctx.Value(requestKey{}).(*request); ok {
+		return req.userID.Load()
+	}
+	return 0
+}
+
+// contextHandler дописывает к записи сведения о запросе из контекста
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if req, ok := ctx.Value(requestKey{}).(*request); ok {
+		r.AddAttrs(slog.String("request_id", req.id))
+		if id := req.userID.Load(); id != 0 {
+			r.AddAttrs(slog.Int64("user_id", id))
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
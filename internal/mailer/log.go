@@ -0,0 +1,19 @@
+package mailer
+
+import (
+	"context"
+	"log/slog"
+)
+
+// LogMailer не отправляет письма, а пишет их в лог. Подходит для локальной разработки,
+// пока нет настоящего SMTP.
+type LogMailer struct{}
+
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+func (m *LogMailer) Send(ctx context.Context, to, subject, body string) error {
+	slog.InfoContext(ctx, "mail", "to", to, "subject", subject, "body", body)
+	return nil
+}
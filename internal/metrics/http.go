@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StatusWriter ResponseWriter, который помнит код ответа. Если запрос уже
+// обёрнут таким (access log), Instrument не создаёт свою обёртку.
+type StatusWriter interface {
+	http.ResponseWriter
+	Status() int
+}
+
+// route метрики одного шаблона ServeMux
+type route struct {
+	method, path string
+	duration     *Histogram
+
+	mu    sync.Mutex
+	codes [600]atomic.Pointer[Counter]
+}
+
+// Instrument считает запросы и время ответа обработчика h, зарегистрированного
+// на шаблон pattern, например "GET /movies/{id}". Вызывать при регистрации маршрута.
+func Instrument(pattern string, h http.HandlerFunc) http.HandlerFunc {
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok {
+		method, path = "ANY", pattern
+	}
+
+	rt := &route{
+		method:   method,
+		path:     path,
+		duration: Default.Histogram("http_request_duration_seconds", "HTTP request latency by route.", DefaultBuckets, "method", method, "route", path),
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		sw, ok := w.(StatusWriter)
+		if !ok {
+			sw = &statusWriter{ResponseWriter: w}
+		}
+
+		h(sw, r)
+
+		rt.duration.Observe(time.Since(start).Seconds())
+		rt.requests(sw.Status()).Inc()
+	}
+}
+
+// requests счётчик для кода ответа; создаётся при первом таком ответе
+func (rt *route) requests(code int) *Counter {
+	if code < 0 || code >= len(rt.codes) {
+		code = 0
+	}
+	if c := rt.codes[code].Load(); c != nil {
+		return c
+	}
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	if c := rt.codes[code].Load(); c != nil {
+		return c
+	}
+	c := Default.Counter("http_requests_total", "HTTP requests by route and status code.",
+		"method", rt.method, "route", rt.path, "code", strconv.Itoa(code))
+	rt.codes[code].Store(c)
+	return c
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,224 @@
+// Package metrics счётчики, гистограммы и показатели в текстовом формате Prometheus
+// (https://prometheus.io/docs/instrumenting/exposition_formats/).
+//
+// Метрики создаются заранее, при старте: на горячем пути только атомарные операции,
+// без поиска по меткам и без выделения памяти.
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Default реестр, который отдаёт GET /metrics
+var Default = NewRegistry()
+
+type Registry struct {
+	mu       sync.Mutex
+	families []*family
+	byName   map[string]*family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{byName: map[string]*family{}}
+}
+
+// family метрики одного имени с разными метками
+type family struct {
+	name, help, typ string
+	series          []series
+}
+
+type series struct {
+	labels string // уже в виде {k="v",...}
+	write  func(w *bufio.Writer, name, labels string)
+}
+
+func (r *Registry) add(name, help, typ string, labels []string, write func(w *bufio.Writer, name, labels string)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, ok := r.byName[name]
+	if !ok {
+		f = &family{name: name, help: help, typ: typ}
+		r.byName[name] = f
+		r.families = append(r.families, f)
+	}
+	if f.typ != typ {
+		panic("metrics: " + name + " registered as " + f.typ + " and " + typ)
+	}
+	f.series = append(f.series, series{labels: renderLabels(labels), write: write})
+}
+
+// Counter счётчик. labels пары имя, значение.
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	c := &Counter{}
+	r.add(name, help, "counter", labels, func(w *bufio.Writer, name, labels string) {
+		writeSample(w, name, labels, float64(c.v.Load()))
+	})
+	return c
+}
+
+// CounterFunc счётчик, значение которого берётся при каждом чтении метрик
+func (r *Registry) CounterFunc(name, help string, fn func() float64, labels ...string) {
+	r.add(name, help, "counter", labels, func(w *bufio.Writer, name, labels string) {
+		writeSample(w, name, labels, fn())
+	})
+}
+
+// GaugeFunc текущее значение, берётся при каждом чтении метрик
+func (r *Registry) GaugeFunc(name, help string, fn func() float64, labels ...string) {
+	r.add(name, help, "gauge", labels, func(w *bufio.Writer, name, labels string) {
+		writeSample(w, name, labels, fn())
+	})
+}
+
+// Histogram с верхними границами корзин buckets по возрастанию
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{buckets: buckets, counts: make([]atomic.Uint64, len(buckets)+1)}
+	r.add(name, help, "histogram", labels, h.write)
+	return h
+}
+
+// WriteText пишет все метрики в текстовом формате
+func (r *Registry) WriteText(out io.Writer) error {
+	r.mu.Lock()
+	families := make([]*family, len(r.families))
+	copy(families, r.families)
+	for i, f := range families {
+		cp := *f
+		cp.series = append([]series(nil), f.series...)
+		families[i] = &cp
+	}
+	r.mu.Unlock()
+
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	w := bufio.NewWriter(out)
+	for _, f := range families {
+		w.WriteString("# HELP " + f.name + " " + escapeHelp(f.help) + "\n")
+		w.WriteString("# TYPE " + f.name + " " + f.typ + "\n")
+		for _, s := range f.series {
+			s.write(w, f.name, s.labels)
+		}
+	}
+	return w.Flush()
+}
+
+// Handler GET /metrics
+func (r *Registry) Handler(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	r.WriteText(w)
+}
+
+type Counter struct {
+	v atomic.Uint64
+}
+
+func (c *Counter) Inc() {
+	c.v.Add(1)
+}
+
+func (c *Counter) Add(n uint64) {
+	c.v.Add(n)
+}
+
+type Histogram struct {
+	buckets []float64
+	counts  []atomic.Uint64 // последняя корзина +Inf
+	count   atomic.Uint64
+	sum     atomic.Uint64 // биты float64
+}
+
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+	h.counts[i].Add(1)
+	h.count.Add(1)
+	for {
+		old := h.sum.Load()
+		if h.sum.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+func (h *Histogram) write(w *bufio.Writer, name, labels string) {
+	var cumulative uint64
+	for i, le := range h.buckets {
+		cumulative += h.counts[i].Load()
+		writeSample(w, name+"_bucket", withLabel(labels, "le", formatFloat(le)), float64(cumulative))
+	}
+	cumulative += h.counts[len(h.buckets)].Load()
+	writeSample(w, name+"_bucket", withLabel(labels, "le", "+Inf"), float64(cumulative))
+	writeSample(w, name+"_sum", labels, math.Float64frombits(h.sum.Load()))
+	writeSample(w, name+"_count", labels, float64(cumulative))
+}
+
+// DefaultBuckets границы в секундах для времени ответа
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+func writeSample(w *bufio.Writer, name, labels string, v float64) {
+	w.WriteString(name)
+	w.WriteString(labels)
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func renderLabels(pairs []string) string {
+	if len(pairs) == 0 {
+		return ""
+	}
+	if len(pairs)%2 != 0 {
+		panic("metrics: labels must be name, value pairs")
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(pairs[i] + `="` + escapeLabel(pairs[i+1]) + `"`)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func withLabel(labels, name, value string) string {
+	l := name + `="` + value + `"`
+	if labels == "" {
+		return "{" + l + "}"
+	}
+	return labels[:len(labels)-1] + "," + l + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
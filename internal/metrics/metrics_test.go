@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHistogram(t *testing.T) {
+	r := NewRegistry()
+	h := r.Histogram("latency_seconds", "Latency.", []float64{0.1, 1}, "route", "/a")
+	for _, v := range []float64{0.05, 0.1, 0.5, 3} {
+		h.Observe(v)
+	}
+
+	var out strings.Builder
+	if err := r.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+	// Граница корзины включается в неё, корзины накопительные
+	want := `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/a",le="0.1"} 2
+latency_seconds_bucket{route="/a",le="1"} 3
+latency_seconds_bucket{route="/a",le="+Inf"} 4
+latency_seconds_sum{route="/a"} 3.65
+latency_seconds_count{route="/a"} 4
+`
+	if out.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestWriteTextWithoutLabels(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("b_total", "Second.\nLine").Add(3)
+	r.Histogram("a_seconds", `Back\slash.`, []float64{1}).Observe(2)
+
+	var out strings.Builder
+	if err := r.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP a_seconds Back\\slash.
+# TYPE a_seconds histogram
+a_seconds_bucket{le="1"} 0
+a_seconds_bucket{le="+Inf"} 1
+a_seconds_sum 2
+a_seconds_count 1
+# HELP b_total Second.\nLine
+# TYPE b_total counter
+b_total 3
+`
+	if out.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestRenderLabels(t *testing.T) {
+	tests := []struct {
+		pairs []string
+		want  string
+	}{
+		{nil, ""},
+		{[]string{"route", "/movies/{id}"}, `{route="/movies/{id}"}`},
+		{[]string{"a", "1", "b", "2"}, `{a="1",b="2"}`},
+		{[]string{"q", `say "hi"`}, `{q="say \"hi\""}`},
+		{[]string{"path", `C:\tmp`}, `{path="C:\\tmp"}`},
+		{[]string{"msg", "two\nlines"}, `{msg="two\nlines"}`},
+	}
+	for _, tt := range tests {
+		if got := renderLabels(tt.pairs); got != tt.want {
+			t.Errorf("renderLabels(%q) = %s, want %s", tt.pairs, got, tt.want)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("odd number of label values must panic")
+		}
+	}()
+	renderLabels([]string{"route"})
+}
+
+// ownStatusWriter обёртка, как у access log
+type ownStatusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *ownStatusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *ownStatusWriter) Status() int {
+	return w.status
+}
+
+func TestInstrument(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		wrap    bool
+		code    string
+	}{
+		{"implicit 200 on write", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) }, false, "200"},
+		{"no write at all", func(w http.ResponseWriter, r *http.Request) {}, false, "200"},
+		{"explicit status", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNotFound) }, false, "404"},
+		{"first status wins", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			w.WriteHeader(http.StatusInternalServerError)
+		}, false, "201"},
+		{"status after write", func(w http.ResponseWriter, r *http.Request) {
+			w.Write(nil)
+			w.WriteHeader(http.StatusTeapot)
+		}, false, "200"},
+		{"existing status writer", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusConflict) }, true, "409"},
+	}
+	for i, tt := range tests {
+		path := "/instrument-test/" + string(rune('a'+i))
+		h := Instrument("POST "+path, tt.handler)
+
+		var w http.ResponseWriter = httptest.NewRecorder()
+		if tt.wrap {
+			w = &ownStatusWriter{ResponseWriter: w}
+		}
+		h(w, httptest.NewRequest(http.MethodPost, path, nil))
+
+		var out strings.Builder
+		if err := Default.WriteText(&out); err != nil {
+			t.Fatal(err)
+		}
+		requests := `http_requests_total{method="POST",route="` + path + `",code="` + tt.code + `"} 1` + "\n"
+		if !strings.Contains(out.String(), requests) {
+			t.Errorf("%s: no %q in output", tt.name, requests)
+		}
+		duration := `http_request_duration_seconds_count{method="POST",route="` + path + `"} 1` + "\n"
+		if !strings.Contains(out.String(), duration) {
+			t.Errorf("%s: no %q in output", tt.name, duration)
+		}
+	}
+}
@@ -2,9 +2,12 @@ package middleware
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 
 	"github.com/marcokz/movie-final/internal/auth"
+	"github.com/marcokz/movie-final/internal/logging"
+	"github.com/marcokz/movie-final/internal/problem"
 )
 
 // Создаем кастомный тип для ключа контекста. Это уменьшит вероятность
@@ -13,28 +16,52 @@ type ContextKey string
 
 const UserContextKey ContextKey = "auth_token"
 
-func Authorize(roles ...string) func(next http.HandlerFunc) http.HandlerFunc {
+type SessionsRepo interface {
+	TouchSession(ctx context.Context, id, userid int64) (bool, error)
+}
+
+type Auth struct {
+	sessionsRepo SessionsRepo
+}
+
+func NewAuth(s SessionsRepo) *Auth {
+	return &Auth{sessionsRepo: s}
+}
+
+func (a *Auth) Authorize(roles ...string) func(next http.HandlerFunc) http.HandlerFunc {
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
 			cookie, err := r.Cookie(string(UserContextKey))
 			if err != nil {
 				if err == http.ErrNoCookie {
-					w.WriteHeader(http.StatusUnauthorized)
+					problem.Write(w, r, problem.Unauthenticated)
 					return
 				}
-				w.WriteHeader(http.StatusBadRequest)
+				problem.Write(w, r, problem.BadRequest("malformed auth cookie"))
 				return
 			}
 
 			// Проверяем JWT токен
 			claims, err := auth.ValidationJWT(cookie.Value)
 			if err != nil {
-				http.Error(w, "Неверный токен", http.StatusUnauthorized)
+				problem.Write(w, r, problem.New(http.StatusUnauthorized, "invalid_token", "invalid or expired token"))
+				return
+			}
+
+			// Токен может быть валидным, но сессия уже отозвана (смена пароля, выход на другом устройстве)
+			active, err := a.sessionsRepo.TouchSession(r.Context(), claims.SessionID, claims.ID)
+			if err != nil {
+				problem.Write(w, r, fmt.Errorf("touch session: %w", err))
+				return
+			}
+			if !active {
+				problem.Write(w, r, problem.New(http.StatusUnauthorized, "session_revoked", "session has been revoked"))
 				return
 			}
 
 			// Сохраняем данные о пользователе в контексте запроса
 			ctx := context.WithValue(r.Context(), UserContextKey, claims)
+			logging.SetUserID(ctx, claims.ID)
 
 			// Проверяем роли пользователя
 			// Если хотя бы одна из ролей пользователя совпадает с ролями, переданными в параметрах, то пропускаем запрос
@@ -45,7 +72,7 @@ func Authorize(roles ...string) func(next http.HandlerFunc) http.HandlerFunc {
 					return
 				}
 			}
-			w.WriteHeader(http.StatusForbidden)
+			problem.Write(w, r, problem.Forbidden("insufficient role"))
 		}
 	}
 }
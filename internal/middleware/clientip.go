@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type clientIPKey struct{}
+
+// WithClientIP определяет адрес клиента. Если соединение пришло от доверенного прокси,
+// адрес берётся из X-Forwarded-For: справа налево до первого адреса не из trusted.
+// Левее него значения присылает сам клиент, им верить нельзя.
+func WithClientIP(trusted []netip.Prefix, next http.Handler) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := forwardedFor(r, trusted)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip)))
+	})
+}
+
+// ClientIP адрес клиента из WithClientIP, без него адрес соединения
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	return remoteHost(r)
+}
+
+func forwardedFor(r *http.Request, trusted []netip.Prefix) string {
+	remote := remoteHost(r)
+	addr, err := netip.ParseAddr(remote)
+	if err != nil || !isTrusted(addr, trusted) {
+		return remote
+	}
+
+	// Заголовок может повторяться, каждый прокси дописывает адрес в конец
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	ip := addr
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		ip = hop.Unmap()
+		if !isTrusted(ip, trusted) {
+			break
+		}
+	}
+	return ip.String()
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, p := range trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
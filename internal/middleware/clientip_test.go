@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("fd00::/8")}
+
+	tests := []struct {
+		name    string
+		trusted []netip.Prefix
+		remote  string
+		xff     []string
+		want    string
+	}{
+		{"no proxies configured", nil, "10.0.0.1:5000", []string{"203.0.113.7"}, "10.0.0.1"},
+		{"direct client ignores header", trusted, "198.51.100.1:5000", []string{"203.0.113.7"}, "198.51.100.1"},
+		{"one proxy", trusted, "10.0.0.1:5000", []string{"203.0.113.7"}, "203.0.113.7"},
+		{"spoofed left entries", trusted, "10.0.0.1:5000", []string{"1.2.3.4, 203.0.113.7"}, "203.0.113.7"},
+		{"chain of proxies", trusted, "10.0.0.1:5000", []string{"203.0.113.7, 10.1.1.1"}, "203.0.113.7"},
+		{"repeated header", trusted, "10.0.0.1:5000", []string{"203.0.113.7", "10.1.1.1"}, "203.0.113.7"},
+		{"garbage stops the walk", trusted, "10.0.0.1:5000", []string{"203.0.113.7, junk, 10.1.1.1"}, "10.1.1.1"},
+		{"only proxies", trusted, "10.0.0.1:5000", []string{"10.2.2.2"}, "10.2.2.2"},
+		{"no header", trusted, "10.0.0.1:5000", nil, "10.0.0.1"},
+		{"ipv6", trusted, "[fd00::1]:5000", []string{"2001:db8::7"}, "2001:db8::7"},
+		{"ipv4 mapped", trusted, "[::ffff:10.0.0.1]:5000", []string{"203.0.113.7"}, "203.0.113.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remote
+			for _, v := range tt.xff {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+
+			var got string
+			WithClientIP(tt.trusted, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = ClientIP(r)
+			})).ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
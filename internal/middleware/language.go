@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/marcokz/movie-final/internal/locale"
+)
+
+// WithLanguage кладёт в контекст язык ответа: параметр lang важнее заголовка Accept-Language.
+func WithLanguage(next http.Handler) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lang := r.Header.Get("Accept-Language")
+		if v := r.URL.Query().Get("lang"); v != "" {
+			lang = v
+		}
+		lang = locale.Parse(lang)
+		next.ServeHTTP(w, r.WithContext(locale.WithLanguage(r.Context(), lang)))
+	})
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/marcokz/movie-final/internal/problem"
+)
+
+// WithRouteErrors отдаёт 404 и 405 самого ServeMux, когда запрос не попал ни в один
+// шаблон, в формате problem+json, как и ошибки обработчиков. Заголовок Allow сохраняется.
+func WithRouteErrors(mux *http.ServeMux) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, pattern := mux.Handler(r)
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		// Без шаблона mux отвечает сам: 404, 405 или редирект на канонический путь
+		rw := &routeErrorWriter{ResponseWriter: w}
+		h.ServeHTTP(rw, r)
+
+		switch rw.status {
+		case http.StatusNotFound:
+			problem.Write(w, r, problem.NotFound("no route for "+r.URL.Path))
+		case http.StatusMethodNotAllowed:
+			problem.Write(w, r, problem.New(http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed,
+				"method "+r.Method+" is not allowed, allowed: "+strings.Join(w.Header().Values("Allow"), ", ")))
+		}
+	})
+}
+
+// routeErrorWriter глотает текстовый ответ mux на 404 и 405, остальное пропускает как есть
+type routeErrorWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *routeErrorWriter) WriteHeader(status int) {
+	if status == http.StatusNotFound || status == http.StatusMethodNotAllowed {
+		w.status = status
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *routeErrorWriter) Write(b []byte) (int, error) {
+	if w.status != 0 {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
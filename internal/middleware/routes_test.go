@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/marcokz/movie-final/internal/problem"
+)
+
+func TestWithRouteErrors(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /movies/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "0" {
+			problem.Write(w, r, problem.NotFound("movie not found"))
+			return
+		}
+		w.Write([]byte(r.PathValue("id")))
+	})
+	mux.HandleFunc("GET /docs/", func(w http.ResponseWriter, r *http.Request) {})
+	h := WithRouteErrors(mux)
+
+	tests := []struct {
+		name, method, path string
+		status             int
+		code               string
+		allow              string
+		location           string
+	}{
+		{name: "match", method: "GET", path: "/movies/7", status: http.StatusOK},
+		{name: "handler 404 untouched", method: "GET", path: "/movies/0", status: http.StatusNotFound, code: problem.CodeNotFound},
+		{name: "no route", method: "GET", path: "/nope", status: http.StatusNotFound, code: problem.CodeNotFound},
+		{name: "wrong method", method: "DELETE", path: "/movies/7", status: http.StatusMethodNotAllowed, code: problem.CodeMethodNotAllowed, allow: "GET, HEAD"},
+		{name: "redirect", method: "GET", path: "/docs", status: http.StatusTemporaryRedirect, location: "/docs/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Header().Get("Allow"); got != tt.allow {
+				t.Errorf("Allow = %q, want %q", got, tt.allow)
+			}
+			if got := rec.Header().Get("Location"); got != tt.location {
+				t.Errorf("Location = %q, want %q", got, tt.location)
+			}
+			if tt.code == "" {
+				return
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != problem.ContentType {
+				t.Errorf("Content-Type = %q, want %q", ct, problem.ContentType)
+			}
+			var body struct {
+				Code   string `json:"code"`
+				Status int    `json:"status"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("body is not JSON: %v", err)
+			}
+			if body.Code != tt.code || body.Status != tt.status {
+				t.Errorf("body code = %q status = %d, want %q %d", body.Code, body.Status, tt.code, tt.status)
+			}
+		})
+	}
+}
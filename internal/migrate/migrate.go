@@ -0,0 +1,268 @@
+// Package migrate применяет SQL миграции в формате goose. Учёт ведётся в той же
+// таблице goose_db_version, поэтому базы, которые раньше мигрировали через goose,
+// подхватываются как есть.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Ключ pg_advisory_lock: несколько реплик с автомиграцией не должны мигрировать одновременно
+const lockKey = 7_414_520_250_111
+
+var ErrNothingToRollback = errors.New("no applied migrations to roll back")
+
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []Migration
+}
+
+func New(pool *pgxpool.Pool, fsys fs.FS) (*Migrator, error) {
+	list, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{pool: pool, migrations: list}, nil
+}
+
+// Latest версия последней миграции, под которую собран код
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Status все известные миграции и когда они применены
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx, m.pool)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Status, 0, len(m.migrations))
+	for _, mg := range m.migrations {
+		s := Status{Migration: mg}
+		if t, ok := applied[mg.Version]; ok {
+			s.AppliedAt = &t
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+// Check схема должна быть ровно той, что ждёт код: все миграции применены
+// и нет применённых, о которых код не знает.
+func (m *Migrator) Check(ctx context.Context) error {
+	applied, err := m.applied(ctx, m.pool)
+	if err != nil {
+		return err
+	}
+	return check(m.migrations, applied)
+}
+
+func check(list []Migration, applied map[int64]time.Time) error {
+	if p := pending(list, applied); len(p) > 0 {
+		return fmt.Errorf("schema is behind the code: %d pending migrations starting with %d, run migrate up", len(p), p[0].Version)
+	}
+
+	known := map[int64]bool{}
+	for _, mg := range list {
+		known[mg.Version] = true
+	}
+	var unknown []int64
+	for version := range applied {
+		if !known[version] {
+			unknown = append(unknown, version)
+		}
+	}
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		return fmt.Errorf("schema has migration %d unknown to this build, the code is older than the database", unknown[len(unknown)-1])
+	}
+
+	return nil
+}
+
+// pending неприменённые миграции по возрастанию версии, в том числе добавленные
+// задним числом, с версией меньше уже применённых.
+func pending(list []Migration, applied map[int64]time.Time) []Migration {
+	var p []Migration
+	for _, mg := range list {
+		if _, ok := applied[mg.Version]; !ok {
+			p = append(p, mg)
+		}
+	}
+	return p
+}
+
+// lastApplied применённая миграция с наибольшей версией
+func lastApplied(list []Migration, applied map[int64]time.Time) (Migration, bool) {
+	for i := len(list) - 1; i >= 0; i-- {
+		if _, ok := applied[list[i].Version]; ok {
+			return list[i], true
+		}
+	}
+	return Migration{}, false
+}
+
+// Up применяет все неприменённые миграции по возрастанию версии, в том числе
+// добавленные задним числом. Возвращает применённые.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mg := range pending(m.migrations, applied) {
+			if err := run(ctx, conn, mg, mg.Up, "insert into goose_db_version (version_id, is_applied) values ($1, true)"); err != nil {
+				return err
+			}
+			done = append(done, mg)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down откатывает последнюю применённую миграцию
+func (m *Migrator) Down(ctx context.Context) (Migration, error) {
+	var undone Migration
+	err := m.locked(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		mg, ok := lastApplied(m.migrations, applied)
+		if !ok {
+			return ErrNothingToRollback
+		}
+		undone = mg
+		return run(ctx, conn, mg, mg.Down, "delete from goose_db_version where version_id = $1")
+	})
+	return undone, err
+}
+
+// run выполняет операторы миграции и отмечает её в goose_db_version одной транзакцией
+func run(ctx context.Context, conn *pgxpool.Conn, mg Migration, statements []string, record string) error {
+	if mg.NoTx {
+		for _, s := range statements {
+			if _, err := conn.Exec(ctx, s); err != nil {
+				return fmt.Errorf("migration %s: %w", mg.Name, err)
+			}
+		}
+		_, err := conn.Exec(ctx, record, mg.Version)
+		return err
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, s := range statements {
+		if _, err := tx.Exec(ctx, s); err != nil {
+			return fmt.Errorf("migration %s: %w", mg.Name, err)
+		}
+	}
+	if _, err := tx.Exec(ctx, record, mg.Version); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (m *Migrator) locked(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "select pg_advisory_lock($1)", lockKey); err != nil {
+		return err
+	}
+	defer conn.Exec(context.Background(), "select pg_advisory_unlock($1)", lockKey)
+
+	// Таблица как у goose: нулевая версия отмечает её создание
+	_, err = conn.Exec(ctx, `
+	CREATE TABLE IF NOT EXISTS goose_db_version (
+		id SERIAL PRIMARY KEY,
+		version_id BIGINT NOT NULL,
+		is_applied BOOLEAN NOT NULL,
+		tstamp TIMESTAMP DEFAULT now()
+	)`)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec(ctx, `
+	insert into goose_db_version (version_id, is_applied)
+	select 0, true where not exists (select 1 from goose_db_version)`)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// applied версии и время применения. Старый goose при откате писал строку с
+// is_applied = false вместо удаления, поэтому смотрим на последнюю запись по версии.
+func (m *Migrator) applied(ctx context.Context, q querier) (map[int64]time.Time, error) {
+	applied := map[int64]time.Time{}
+
+	// На пустой базе таблицы ещё нет
+	var exists bool
+	if err := q.QueryRow(ctx, "select to_regclass('goose_db_version') is not null").Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return applied, nil
+	}
+
+	rows, err := q.Query(ctx, `
+	select distinct on (version_id) version_id, is_applied, coalesce(tstamp, now())
+	from goose_db_version
+	where version_id > 0
+	order by version_id, id desc
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int64
+		var ok bool
+		var at time.Time
+		if err := rows.Scan(&version, &ok, &at); err != nil {
+			return nil, err
+		}
+		if ok {
+			applied[version] = at
+		}
+	}
+
+	return applied, rows.Err()
+}
@@ -0,0 +1,198 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func versions(list []Migration) []int64 {
+	var v []int64
+	for _, m := range list {
+		v = append(v, m.Version)
+	}
+	return v
+}
+
+func appliedAt(versions ...int64) map[int64]time.Time {
+	applied := map[int64]time.Time{}
+	for _, v := range versions {
+		applied[v] = time.Now()
+	}
+	return applied
+}
+
+var testList = []Migration{{Version: 1}, {Version: 2}, {Version: 3}}
+
+func TestPending(t *testing.T) {
+	tests := []struct {
+		name    string
+		applied map[int64]time.Time
+		want    []int64
+	}{
+		{"empty database", appliedAt(), []int64{1, 2, 3}},
+		{"up to date", appliedAt(1, 2, 3), nil},
+		{"new migration", appliedAt(1, 2), []int64{3}},
+		{"added out of order", appliedAt(1, 3), []int64{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := versions(pending(testList, tt.applied))
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("pending() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLastApplied(t *testing.T) {
+	if m, ok := lastApplied(testList, appliedAt(1, 2)); !ok || m.Version != 2 {
+		t.Errorf("lastApplied() = %d, %v, want 2", m.Version, ok)
+	}
+	if m, ok := lastApplied(testList, appliedAt(3, 1)); !ok || m.Version != 3 {
+		t.Errorf("lastApplied() = %d, %v, want 3", m.Version, ok)
+	}
+	if _, ok := lastApplied(testList, appliedAt()); ok {
+		t.Error("lastApplied() on an empty database must report nothing")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		applied map[int64]time.Time
+		wantErr string
+	}{
+		{"up to date", appliedAt(1, 2, 3), ""},
+		{"behind", appliedAt(1), "2 pending migrations starting with 2"},
+		{"gap", appliedAt(1, 3), "1 pending migrations starting with 2"},
+		{"ahead", appliedAt(1, 2, 3, 5, 4), "migration 5 unknown to this build"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := check(testList, tt.applied)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("check() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("check() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestMigratorPostgres прогоняет миграции на настоящей базе из MOVIE_TEST_DB_URL
+// в отдельной временной схеме.
+func TestMigratorPostgres(t *testing.T) {
+	dbURL := os.Getenv("MOVIE_TEST_DB_URL")
+	if dbURL == "" {
+		t.Skip("MOVIE_TEST_DB_URL is not set")
+	}
+	ctx := context.Background()
+
+	admin, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+
+	schema := fmt.Sprintf("migrate_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE")
+
+	cfg, err := pgxpool.ParseConfig(dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.ConnConfig.RuntimeParams["search_path"] = schema
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	file := func(table string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(fmt.Sprintf(
+			"-- +goose Up\nCREATE TABLE %[1]s (id INT);\n-- +goose Down\nDROP TABLE %[1]s;\n", table))}
+	}
+	migrator := func(t *testing.T, fsys fstest.MapFS) *Migrator {
+		t.Helper()
+		m, err := New(pool, fsys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	v1v3 := fstest.MapFS{"1_a.sql": file("a"), "3_c.sql": file("c")}
+	all := fstest.MapFS{"1_a.sql": file("a"), "2_b.sql": file("b"), "3_c.sql": file("c")}
+
+	if err := migrator(t, v1v3).Check(ctx); err == nil {
+		t.Error("Check() on an empty schema must fail")
+	}
+
+	done, err := migrator(t, v1v3).Up(ctx)
+	if err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if fmt.Sprint(versions(done)) != "[1 3]" {
+		t.Errorf("Up() applied %v, want [1 3]", versions(done))
+	}
+
+	// Миграция 2 добавлена задним числом: код её ждёт, Up применяет только её
+	m := migrator(t, all)
+	if err := m.Check(ctx); err == nil || !strings.Contains(err.Error(), "starting with 2") {
+		t.Errorf("Check() error = %v, want pending 2", err)
+	}
+	done, err = m.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if fmt.Sprint(versions(done)) != "[2]" {
+		t.Errorf("Up() applied %v, want [2]", versions(done))
+	}
+	if err := m.Check(ctx); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+
+	// Код старее базы
+	if err := migrator(t, v1v3).Check(ctx); err == nil || !strings.Contains(err.Error(), "migration 2 unknown") {
+		t.Errorf("Check() error = %v, want unknown 2", err)
+	}
+
+	undone, err := m.Down(ctx)
+	if err != nil {
+		t.Fatalf("Down() error = %v", err)
+	}
+	if undone.Version != 3 {
+		t.Errorf("Down() rolled back %d, want 3", undone.Version)
+	}
+	var exists bool
+	if err := pool.QueryRow(ctx, "select to_regclass('c') is not null").Scan(&exists); err != nil {
+		t.Fatal(err)
+	}
+	if exists {
+		t.Error("Down() left table c")
+	}
+
+	for range 2 {
+		if _, err := m.Down(ctx); err != nil {
+			t.Fatalf("Down() error = %v", err)
+		}
+	}
+	if _, err := m.Down(ctx); !errors.Is(err, ErrNothingToRollback) {
+		t.Errorf("Down() error = %v, want ErrNothingToRollback", err)
+	}
+}
@@ -0,0 +1,138 @@
+package migrate
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Migration одна миграция в формате goose: версия и имя из имени файла
+// 20241109100000_follows.sql, SQL после "-- +goose Up" и "-- +goose Down".
+type Migration struct {
+	Version int64
+	Name    string
+	Up      []string
+	Down    []string
+	NoTx    bool // "-- +goose NO TRANSACTION"
+}
+
+// Load читает *.sql из корня fsys, по возрастанию версии
+func Load(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	var list []Migration
+	seen := map[int64]string{}
+	for _, name := range names {
+		prefix, _, ok := strings.Cut(name, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("%s: name must start with a version, e.g. 20241109100000_name.sql", name)
+		}
+		if other, dup := seen[version]; dup {
+			return nil, fmt.Errorf("%s: version %d already used by %s", name, version, other)
+		}
+		seen[version] = name
+
+		f, err := fsys.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		m, err := parse(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		m.Version = version
+		m.Name = strings.TrimSuffix(path.Base(name), ".sql")
+		list = append(list, m)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// parse разбирает файл по правилам goose: вне StatementBegin/StatementEnd
+// оператор заканчивается строкой с ";" на конце, внутри блок уходит в базу целиком.
+func parse(r io.Reader) (Migration, error) {
+	var m Migration
+	var section *[]string
+	var buf strings.Builder
+	var inBlock bool
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if annotation, ok := strings.CutPrefix(trimmed, "-- +goose "); ok {
+			switch strings.TrimSpace(annotation) {
+			case "Up":
+				section = &m.Up
+			case "Down":
+				section = &m.Down
+			case "StatementBegin":
+				if inBlock || section == nil {
+					return Migration{}, fmt.Errorf("line %d: unexpected StatementBegin", n)
+				}
+				inBlock = true
+			case "StatementEnd":
+				if !inBlock {
+					return Migration{}, fmt.Errorf("line %d: StatementEnd without StatementBegin", n)
+				}
+				inBlock = false
+				appendStatement(section, &buf)
+			case "NO TRANSACTION":
+				m.NoTx = true
+			default:
+				return Migration{}, fmt.Errorf("line %d: unknown annotation %q", n, annotation)
+			}
+			continue
+		}
+
+		if section == nil {
+			continue
+		}
+		if !inBlock && buf.Len() == 0 && (trimmed == "" || strings.HasPrefix(trimmed, "--")) {
+			continue
+		}
+
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+		if !inBlock && strings.HasSuffix(trimmed, ";") {
+			appendStatement(section, &buf)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Migration{}, err
+	}
+
+	if inBlock {
+		return Migration{}, errors.New("StatementBegin without StatementEnd")
+	}
+	if strings.TrimSpace(buf.String()) != "" {
+		return Migration{}, errors.New("statement is not terminated with ;")
+	}
+	if m.Up == nil {
+		return Migration{}, errors.New("no -- +goose Up section")
+	}
+
+	return m, nil
+}
+
+func appendStatement(section *[]string, buf *strings.Builder) {
+	if s := strings.TrimSpace(buf.String()); s != "" {
+		*section = append(*section, s)
+	}
+	buf.Reset()
+}
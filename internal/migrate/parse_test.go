@@ -0,0 +1,188 @@
+package migrate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/marcokz/movie-final/migrations"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		wantUp   []string
+		wantDown []string
+		wantNoTx bool
+	}{
+		{
+			name: "up and down",
+			sql: `-- +goose Up
+CREATE TABLE a (id INT);
+CREATE INDEX a_id ON a (id);
+
+-- +goose Down
+DROP TABLE a;
+`,
+			wantUp:   []string{"CREATE TABLE a (id INT);", "CREATE INDEX a_id ON a (id);"},
+			wantDown: []string{"DROP TABLE a;"},
+		},
+		{
+			name: "statement over several lines",
+			sql: `-- +goose Up
+CREATE TABLE a (
+    id INT
+);
+`,
+			wantUp: []string{"CREATE TABLE a (\n    id INT\n);"},
+		},
+		{
+			name: "comments and blank lines between statements",
+			sql: `-- описание миграции
+-- +goose Up
+
+-- таблица a
+CREATE TABLE a (id INT);
+`,
+			wantUp: []string{"CREATE TABLE a (id INT);"},
+		},
+		{
+			name: "block keeps inner semicolons",
+			sql: `-- +goose Up
+-- +goose StatementBegin
+CREATE FUNCTION f() RETURNS INT AS $$
+BEGIN
+    RETURN 1;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP FUNCTION f();
+-- +goose StatementEnd
+`,
+			wantUp:   []string{"CREATE FUNCTION f() RETURNS INT AS $$\nBEGIN\n    RETURN 1;\nEND;\n$$ LANGUAGE plpgsql;"},
+			wantDown: []string{"DROP FUNCTION f();"},
+		},
+		{
+			name: "no transaction",
+			sql: `-- +goose NO TRANSACTION
+-- +goose Up
+CREATE INDEX CONCURRENTLY a_id ON a (id);
+`,
+			wantUp:   []string{"CREATE INDEX CONCURRENTLY a_id ON a (id);"},
+			wantNoTx: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := parse(strings.NewReader(tt.sql))
+			if err != nil {
+				t.Fatalf("parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(m.Up, tt.wantUp) {
+				t.Errorf("Up = %q, want %q", m.Up, tt.wantUp)
+			}
+			if !reflect.DeepEqual(m.Down, tt.wantDown) {
+				t.Errorf("Down = %q, want %q", m.Down, tt.wantDown)
+			}
+			if m.NoTx != tt.wantNoTx {
+				t.Errorf("NoTx = %v, want %v", m.NoTx, tt.wantNoTx)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		wantErr string
+	}{
+		{"no up section", "CREATE TABLE a (id INT);\n", "no -- +goose Up section"},
+		{"unterminated statement", "-- +goose Up\nCREATE TABLE a (id INT)\n", "not terminated"},
+		{"unclosed block", "-- +goose Up\n-- +goose StatementBegin\nSELECT 1;\n", "StatementBegin without StatementEnd"},
+		{"end without begin", "-- +goose Up\nSELECT 1;\n-- +goose StatementEnd\n", "line 3: StatementEnd without StatementBegin"},
+		{"nested block", "-- +goose Up\n-- +goose StatementBegin\n-- +goose StatementBegin\n", "line 3: unexpected StatementBegin"},
+		{"block before section", "-- +goose StatementBegin\n", "line 1: unexpected StatementBegin"},
+		{"unknown annotation", "-- +goose Up\n-- +goose envsub on\n", `line 2: unknown annotation "envsub on"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse(strings.NewReader(tt.sql))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parse() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	up := &fstest.MapFile{Data: []byte("-- +goose Up\nSELECT 1;\n")}
+
+	list, err := Load(fstest.MapFS{
+		"20241109100000_follows.sql":  up,
+		"20240907000000_users.sql":    up,
+		"20241005101500_sessions.sql": up,
+		"README.md":                   {Data: []byte("not a migration")},
+	})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	var got []string
+	for _, m := range list {
+		got = append(got, m.Name)
+	}
+	want := []string{"20240907000000_users", "20241005101500_sessions", "20241109100000_follows"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Load() order = %q, want %q", got, want)
+	}
+	if list[0].Version != 20240907000000 {
+		t.Errorf("Version = %d", list[0].Version)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	up := &fstest.MapFile{Data: []byte("-- +goose Up\nSELECT 1;\n")}
+
+	tests := []struct {
+		name    string
+		fsys    fstest.MapFS
+		wantErr string
+	}{
+		{"no version", fstest.MapFS{"users.sql": up}, "name must start with a version"},
+		{"zero version", fstest.MapFS{"0_users.sql": up}, "name must start with a version"},
+		{"duplicate version", fstest.MapFS{"1_users.sql": up, "1_movies.sql": up}, "version 1 already used"},
+		{"broken file", fstest.MapFS{"1_users.sql": {Data: []byte("SELECT 1;\n")}}, "1_users.sql: no -- +goose Up section"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.fsys)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Встроенные миграции проекта должны разбираться, и у каждой должен быть откат
+func TestLoadProjectMigrations(t *testing.T) {
+	list, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(list) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	for _, m := range list {
+		if len(m.Up) == 0 || len(m.Down) == 0 {
+			t.Errorf("%s: up %d statements, down %d", m.Name, len(m.Up), len(m.Down))
+		}
+	}
+}
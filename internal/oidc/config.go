@@ -0,0 +1,41 @@
+package oidc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// LoadProviders читает список провайдеров из JSON файла. Пустой путь значит, что вход
+// через внешних провайдеров выключен.
+func LoadProviders(path string) ([]*Provider, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []ProviderConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, err
+	}
+
+	providers := make([]*Provider, 0, len(configs))
+	seen := map[string]bool{}
+	for _, c := range configs {
+		if c.Name == "" || c.Issuer == "" || c.ClientID == "" || c.RedirectURL == "" {
+			return nil, errors.New("oidc: provider requires name, issuer, clientid and redirecturl")
+		}
+		if seen[c.Name] {
+			return nil, fmt.Errorf("oidc: duplicate provider %q", c.Name)
+		}
+		seen[c.Name] = true
+		providers = append(providers, NewProvider(c))
+	}
+
+	return providers, nil
+}
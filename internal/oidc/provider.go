@@ -0,0 +1,264 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+type ProviderConfig struct {
+	Name         string   `json:"name"`
+	Issuer       string   `json:"issuer"`
+	ClientID     string   `json:"clientid"`
+	ClientSecret string   `json:"clientsecret"`
+	RedirectURL  string   `json:"redirecturl"`
+	Scopes       []string `json:"scopes"`
+}
+
+// IDToken данные пользователя из проверенного id_token
+type IDToken struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider внешний OpenID Connect провайдер. Метаданные и ключи подгружаются
+// при первом обращении, чтобы недоступный провайдер не мешал старту сервиса.
+type Provider struct {
+	cfg    ProviderConfig
+	client *http.Client
+
+	mu   sync.Mutex
+	meta *discovery
+	keys map[string]*rsa.PublicKey
+}
+
+func NewProvider(cfg ProviderConfig) *Provider {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email"}
+	}
+	return &Provider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+func (p *Provider) discover(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.meta != nil {
+		return p.meta, nil
+	}
+
+	var d discovery
+	err := p.getJSON(ctx, strings.TrimSuffix(p.cfg.Issuer, "/")+"/.well-known/openid-configuration", &d)
+	if err != nil {
+		return nil, err
+	}
+	if d.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("oidc: issuer mismatch: %q != %q", d.Issuer, p.cfg.Issuer)
+	}
+
+	p.meta = &d
+	return p.meta, nil
+}
+
+// AuthCodeURL ссылка на страницу входа провайдера (authorization code + PKCE S256).
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	v := url.Values{}
+	v.Set("response_type", "code")
+	v.Set("client_id", p.cfg.ClientID)
+	v.Set("redirect_uri", p.cfg.RedirectURL)
+	v.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	v.Set("state", state)
+	v.Set("nonce", nonce)
+	v.Set("code_challenge", CodeChallenge(verifier))
+	v.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return d.AuthorizationEndpoint + sep + v.Encode(), nil
+}
+
+// Exchange меняет code на токены и возвращает проверенный id_token.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (IDToken, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return IDToken{}, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("client_id", p.cfg.ClientID)
+	form.Set("client_secret", p.cfg.ClientSecret)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return IDToken{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return IDToken{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return IDToken{}, fmt.Errorf("oidc: token endpoint returned %s", resp.Status)
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return IDToken{}, err
+	}
+	if tokens.IDToken == "" {
+		return IDToken{}, errors.New("oidc: no id_token in response")
+	}
+
+	return p.verify(ctx, tokens.IDToken, nonce)
+}
+
+func (p *Provider) verify(ctx context.Context, raw, nonce string) (IDToken, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("oidc: unexpected signing method %v", t.Header["alg"])
+		}
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, kid)
+	})
+	if err != nil {
+		return IDToken{}, err
+	}
+
+	switch {
+	case !claims.VerifyIssuer(p.cfg.Issuer, true):
+		return IDToken{}, errors.New("oidc: invalid issuer")
+	case !claims.VerifyAudience(p.cfg.ClientID, true):
+		return IDToken{}, errors.New("oidc: invalid audience")
+	case !claims.VerifyExpiresAt(time.Now().Unix(), true):
+		return IDToken{}, errors.New("oidc: token expired")
+	case claims["nonce"] != nonce:
+		return IDToken{}, errors.New("oidc: invalid nonce")
+	}
+
+	var t IDToken
+	t.Subject, _ = claims["sub"].(string)
+	t.Email, _ = claims["email"].(string)
+	t.EmailVerified, _ = claims["email_verified"].(bool)
+	if t.Subject == "" {
+		return IDToken{}, errors.New("oidc: empty subject")
+	}
+	return t, nil
+}
+
+// key ищет ключ по kid, при промахе перечитывает JWKS (провайдер мог сменить ключи).
+func (p *Provider) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	k, ok := p.keys[kid]
+	p.mu.Unlock()
+	if ok {
+		return k, nil
+	}
+
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := p.getJSON(ctx, d.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+
+	k, ok = keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("oidc: unknown key id %q", kid)
+	}
+	return k, nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: GET %s returned %s", u, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
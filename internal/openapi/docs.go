@@ -0,0 +1,64 @@
+package openapi
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"strings"
+
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+var swaggerIndex = template.Must(template.New("index").Parse(`<!doctype html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="swagger-ui-bundle.js"></script>
+<script>
+SwaggerUIBundle({url: {{.SpecURL}}, dom_id: "#swagger-ui", withCredentials: true});
+</script>
+</body>
+</html>
+`))
+
+// Docs Swagger UI под prefix (например /docs/) для документа specURL. Файлы пакета
+// swagger-ui-dist встроены в бинарник; swaggerUIDir заменяет их каталогом с другой версией.
+func Docs(prefix, title, specURL, swaggerUIDir string) (http.Handler, error) {
+	files := swaggerFiles.FS
+	if swaggerUIDir != "" {
+		files = os.DirFS(swaggerUIDir)
+		if _, err := fs.Stat(files, "swagger-ui-bundle.js"); err != nil {
+			return nil, fmt.Errorf("docs.swagger_ui_dir: %w", err)
+		}
+	}
+
+	var index strings.Builder
+	err := swaggerIndex.Execute(&index, struct{ Title, SpecURL string }{title, specURL})
+	if err != nil {
+		return nil, err
+	}
+	page := index.String()
+
+	fileServer := http.StripPrefix(prefix, http.FileServer(http.FS(files)))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Content-Type, выставленный заранее для JSON API, не подходит ни странице, ни скриптам
+		w.Header().Del("Content-Type")
+
+		// index.html из пакета открывает демонстрационный документ, поэтому своя страница
+		if r.URL.Path == prefix || r.URL.Path == prefix+"index.html" {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(page))
+			return
+		}
+		fileServer.ServeHTTP(w, r)
+	}), nil
+}
@@ -0,0 +1,68 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDocsEmbedded(t *testing.T) {
+	docs, err := Docs("/docs/", "movie-final API", "/openapi.json", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path        string
+		status      int
+		contentType string
+		contains    string
+	}{
+		{"/docs/", http.StatusOK, "text/html; charset=utf-8", `url: "/openapi.json"`},
+		{"/docs/index.html", http.StatusOK, "text/html; charset=utf-8", "<title>movie-final API</title>"},
+		{"/docs/swagger-ui-bundle.js", http.StatusOK, "text/javascript; charset=utf-8", "SwaggerUIBundle"},
+		{"/docs/swagger-ui.css", http.StatusOK, "text/css; charset=utf-8", ""},
+		{"/docs/missing.js", http.StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			// Так делает WithContentTypeJSON перед всеми обработчиками
+			rec.Header().Set("Content-Type", "application/json")
+			docs.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.contentType != "" && rec.Header().Get("Content-Type") != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", rec.Header().Get("Content-Type"), tt.contentType)
+			}
+			if !strings.Contains(rec.Body.String(), tt.contains) {
+				t.Errorf("body does not contain %q", tt.contains)
+			}
+		})
+	}
+}
+
+func TestDocsDir(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Docs("/docs/", "API", "/openapi.json", dir); err == nil {
+		t.Fatal("Docs() accepted a directory without swagger-ui-bundle.js")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "swagger-ui-bundle.js"), []byte("// pinned"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	docs, err := Docs("/docs/", "API", "/openapi.json", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	docs.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs/swagger-ui-bundle.js", nil))
+	if rec.Body.String() != "// pinned" {
+		t.Errorf("body = %q, want the file from the directory", rec.Body)
+	}
+}
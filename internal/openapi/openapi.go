@@ -0,0 +1,305 @@
+// Package openapi описание HTTP API в формате OpenAPI 3.1. Операции перечисляются
+// рядом с регистрацией маршрутов, схемы тел собираются из типов Go по тегам json и
+// validate, а Check сверяет документ с маршрутами, зарегистрированными в ServeMux.
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/marcokz/movie-final/internal/problem"
+)
+
+const version = "3.1.0"
+
+// Param параметр запроса или пути. Параметры пути, не перечисленные в
+// операции, берутся из шаблона: id целое, остальные строки.
+type Param struct {
+	Name        string
+	In          string // query или path, по умолчанию query
+	Type        string // string, integer, number или boolean, по умолчанию string
+	Description string
+	Required    bool
+}
+
+// Operation один маршрут. Pattern пишется так же, как при регистрации в ServeMux.
+type Operation struct {
+	Pattern     string
+	Summary     string
+	Description string
+	Roles       []string // пусто для маршрутов без входа
+
+	Params []Param
+	Body   any      // значение типа JSON тела или *Schema
+	Media  []string // тело не JSON: картинка, multipart/form-data с полем file
+
+	Status       int // по умолчанию 200
+	Response     any // значение типа ответа или *Schema, nil для ответа без тела
+	ResponseType string
+}
+
+type Info struct {
+	Title   string
+	Version string
+}
+
+// Document готовый документ, собирается один раз при старте
+type Document struct {
+	patterns []string
+	data     []byte
+}
+
+// New собирает документ. Ошибка означает опечатку в описании операций.
+func New(info Info, ops []Operation) (*Document, error) {
+	c := newSchemas()
+	paths := map[string]map[string]any{}
+	var patterns []string
+
+	for _, op := range ops {
+		method, path, ok := strings.Cut(op.Pattern, " ")
+		if !ok || method == "" || !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("openapi: pattern %q must be METHOD /path", op.Pattern)
+		}
+		if slices.Contains(patterns, op.Pattern) {
+			return nil, fmt.Errorf("openapi: duplicate operation %q", op.Pattern)
+		}
+		patterns = append(patterns, op.Pattern)
+
+		item, err := operation(c, op, path)
+		if err != nil {
+			return nil, err
+		}
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+		paths[path][strings.ToLower(method)] = item
+	}
+
+	c.byName["Problem"] = problemSchema(c)
+
+	doc := map[string]any{
+		"openapi": version,
+		"info":    map[string]string{"title": info.Title, "version": info.Version},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": c.byName,
+			"responses": map[string]any{
+				"Problem": map[string]any{
+					"description": "Error in RFC 7807 problem details format",
+					"content":     map[string]any{problem.ContentType: map[string]any{"schema": ref("Problem")}},
+				},
+			},
+			"securitySchemes": map[string]any{
+				"cookieAuth": map[string]string{"type": "apiKey", "in": "cookie", "name": "auth_token"},
+			},
+		},
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return &Document{patterns: patterns, data: data}, nil
+}
+
+func operation(c *schemas, op Operation, path string) (map[string]any, error) {
+	item := map[string]any{
+		"operationId": operationID(op.Pattern),
+		"tags":        []string{tag(path)},
+	}
+	if op.Summary != "" {
+		item["summary"] = op.Summary
+	}
+	description := op.Description
+	if len(op.Roles) > 0 {
+		if description != "" {
+			description += "\n\n"
+		}
+		description += "Roles: " + strings.Join(op.Roles, ", ") + "."
+		item["security"] = []map[string][]string{{"cookieAuth": {}}}
+	}
+	if description != "" {
+		item["description"] = description
+	}
+
+	params, err := parameters(op, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(params) > 0 {
+		item["parameters"] = params
+	}
+
+	switch {
+	case op.Body != nil:
+		item["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": map[string]any{"schema": c.of(op.Body)}},
+		}
+	case len(op.Media) > 0:
+		content := map[string]any{}
+		for _, m := range op.Media {
+			file := &Schema{Type: "string", Format: "binary"}
+			if m == "multipart/form-data" {
+				content[m] = map[string]any{"schema": &Schema{
+					Type: "object", Properties: map[string]*Schema{"file": file}, Required: []string{"file"},
+				}}
+				continue
+			}
+			content[m] = map[string]any{"schema": file}
+		}
+		item["requestBody"] = map[string]any{"required": true, "content": content}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	ok := map[string]any{"description": http.StatusText(status)}
+	if op.Response != nil {
+		mediaType := op.ResponseType
+		if mediaType == "" {
+			mediaType = "application/json"
+		}
+		ok["content"] = map[string]any{mediaType: map[string]any{"schema": c.of(op.Response)}}
+	}
+
+	problemRef := map[string]string{"$ref": "#/components/responses/Problem"}
+	responses := map[string]any{strconv.Itoa(status): ok, "default": problemRef}
+	if len(op.Roles) > 0 {
+		responses["401"] = problemRef
+		responses["403"] = problemRef
+	}
+	item["responses"] = responses
+
+	return item, nil
+}
+
+func parameters(op Operation, path string) ([]map[string]any, error) {
+	var params []map[string]any
+	declared := map[string]bool{}
+
+	for _, p := range op.Params {
+		in := p.In
+		if in == "" {
+			in = "query"
+		}
+		if in == "path" {
+			if !strings.Contains(path, "{"+p.Name+"}") {
+				return nil, fmt.Errorf("openapi: %q has no path parameter %s", op.Pattern, p.Name)
+			}
+			declared[p.Name] = true
+		}
+		params = append(params, param(p.Name, in, p.Type, p.Description, p.Required || in == "path"))
+	}
+
+	for rest := path; ; {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("openapi: %q has unclosed path parameter", op.Pattern)
+		}
+		name := rest[start+1 : start+end]
+		rest = rest[start+end+1:]
+		if declared[name] {
+			continue
+		}
+		typ := "string"
+		if name == "id" {
+			typ = "integer"
+		}
+		params = append(params, param(name, "path", typ, "", true))
+	}
+
+	return params, nil
+}
+
+func param(name, in, typ, description string, required bool) map[string]any {
+	if typ == "" {
+		typ = "string"
+	}
+	schema := &Schema{Type: typ}
+	if typ == "integer" {
+		schema.Format = "int64"
+	}
+	p := map[string]any{"name": name, "in": in, "required": required, "schema": schema}
+	if description != "" {
+		p["description"] = description
+	}
+	return p
+}
+
+// operationID из шаблона: "GET /users/{id}/follow" -> "get_users_id_follow"
+func operationID(pattern string) string {
+	id := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		case r == '{' || r == '}':
+			return -1
+		}
+		return '_'
+	}, strings.Replace(pattern, " /", "_", 1))
+	return strings.TrimRight(id, "_")
+}
+
+// tag группа операции по первому сегменту пути
+func tag(path string) string {
+	first, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	return first
+}
+
+func ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func problemSchema(c *schemas) *Schema {
+	s := Object("type", "title", "code", "detail", "instance", "request_id")
+	s.Properties["type"].Format = "uri"
+	s.Properties["status"] = &Schema{Type: "integer", Format: "int32"}
+	s.Properties["errors"] = c.of([]problem.FieldError{})
+	s.Required = []string{"type", "title", "status", "code"}
+	return s
+}
+
+// JSON документ целиком
+func (d *Document) JSON() []byte {
+	return d.data
+}
+
+// ServeHTTP GET /openapi.json
+func (d *Document) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	w.Write(d.data)
+}
+
+// Check сверяет описанные операции с зарегистрированными шаблонами маршрутов
+func (d *Document) Check(registered []string) error {
+	var errs []error
+	for _, p := range registered {
+		if !slices.Contains(d.patterns, p) {
+			errs = append(errs, fmt.Errorf("route %q is not described", p))
+		}
+	}
+	for _, p := range d.patterns {
+		if !slices.Contains(registered, p) {
+			errs = append(errs, fmt.Errorf("described operation %q is not registered", p))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("openapi document is out of sync with routes: %w", errors.Join(errs...))
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema объект схемы OpenAPI 3.1 (JSON Schema 2020-12), только нужные сервису поля
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+}
+
+// Object схема объекта из строковых полей, для ответов вида {"message": "..."}
+func Object(fields ...string) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for _, f := range fields {
+		s.Properties[f] = &Schema{Type: "string"}
+	}
+	return s
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// schemas собирает именованные схемы components.schemas по типам Go
+type schemas struct {
+	byType map[reflect.Type]string
+	byName map[string]*Schema
+}
+
+func newSchemas() *schemas {
+	return &schemas{byType: map[reflect.Type]string{}, byName: map[string]*Schema{}}
+}
+
+// of схема значения v: *Schema берётся как есть, остальное по типу через reflect
+func (c *schemas) of(v any) *Schema {
+	if s, ok := v.(*Schema); ok {
+		return s
+	}
+	return c.schema(reflect.TypeOf(v))
+}
+
+// schema повторяет правила encoding/json. Именованные структуры выносятся в
+// components и подставляются ссылкой.
+func (c *schemas) schema(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return c.schema(t.Elem())
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: c.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: c.schema(t.Elem())}
+	case reflect.Struct:
+		if t == timeType {
+			return &Schema{Type: "string", Format: "date-time"}
+		}
+		if t.Name() == "" {
+			return c.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + c.define(t)}
+	}
+	// interface{} и прочее: любое значение
+	return &Schema{}
+}
+
+// define регистрирует структуру под её именем. Одноимённые типы из разных
+// пакетов (handler.User и entity.User) различаются префиксом пакета.
+func (c *schemas) define(t reflect.Type) string {
+	if name, ok := c.byType[t]; ok {
+		return name
+	}
+
+	name := t.Name()
+	if _, taken := c.byName[name]; taken {
+		pkg := t.PkgPath()
+		name = pkg[strings.LastIndex(pkg, "/")+1:] + "." + name
+	}
+	c.byType[t] = name
+	// Место занимаем до обхода полей, чтобы рекурсивные типы не зациклились
+	c.byName[name] = nil
+	c.byName[name] = c.object(t)
+	return name
+}
+
+func (c *schemas) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	c.fields(s, t)
+	return s
+}
+
+func (c *schemas) fields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		// Встроенная структура без имени в теге раскрывается в поля родителя
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				c.fields(s, ft)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+
+		fs := c.schema(sf.Type)
+		if rules, ok := sf.Tag.Lookup("validate"); ok {
+			constrain(fs, t, rules)
+			if hasRule(rules, "required") {
+				s.Required = append(s.Required, name)
+			}
+		}
+		// Указатель без omitempty кодируется как null
+		if sf.Type.Kind() == reflect.Pointer && !strings.Contains(opts, "omitempty") {
+			fs = &Schema{AnyOf: []*Schema{fs, {Type: "null"}}}
+		}
+		s.Properties[name] = fs
+	}
+}
+
+// constrain переносит правила validate в ограничения схемы. Схема всегда
+// свежая, поэтому меняется на месте.
+func constrain(s *Schema, t reflect.Type, rules string) {
+	for _, part := range strings.Split(rules, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch name {
+		case "min", "max":
+			n, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				continue
+			}
+			if s.Type == "string" {
+				l := int(n)
+				if name == "min" {
+					s.MinLength = &l
+				} else {
+					s.MaxLength = &l
+				}
+			} else if name == "min" {
+				s.Minimum = &n
+			} else {
+				s.Maximum = &n
+			}
+		case "len":
+			if l, err := strconv.Atoi(arg); err == nil && s.Type == "string" {
+				s.MinLength, s.MaxLength = &l, &l
+			}
+		case "past":
+			s.Description = "not in the future"
+		case "oneof":
+			s.Enum = strings.Fields(arg)
+		case "email":
+			s.Format = "email"
+		case "date":
+			s.Format = "date"
+		case "datetime":
+			s.Format = "date-time"
+		case "gtefield":
+			if other, ok := t.FieldByName(arg); ok {
+				s.Description = "not less than " + jsonName(other)
+			}
+		}
+	}
+}
+
+func hasRule(rules, rule string) bool {
+	for _, part := range strings.Split(rules, ",") {
+		if strings.TrimSpace(part) == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return sf.Name
+	}
+	return name
+}
@@ -0,0 +1,237 @@
+package postgresdb
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/marcokz/movie-final/internal/entity"
+)
+
+type PgxAdminRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewAdminRepo(p *pgxpool.Pool) *PgxAdminRepo {
+	return &PgxAdminRepo{pool: p}
+}
+
+// ListAccounts все пользователи без учёта настроек приватности, только для администратора.
+func (p *PgxAdminRepo) ListAccounts(ctx context.Context, f entity.AccountFilter) ([]entity.Account, int, error) {
+	var q queryBuilder
+
+	if f.Query != "" {
+		pattern := likeContains(f.Query)
+		q.where("(u.email ILIKE ? or u.handle ILIKE ? or u.name ILIKE ? or u.surname ILIKE ?)",
+			pattern, pattern, pattern, pattern)
+	}
+	switch f.Status {
+	case "":
+	case entity.StatusActive:
+		q.where("(u.status = 'active' or u.status_until <= now())")
+	default:
+		q.where("u.status = ? and (u.status_until is null or u.status_until > now())", f.Status)
+	}
+
+	// Параметры без limit и offset, по ним же считается общее количество
+	countArgs := q.args[:len(q.args):len(q.args)]
+
+	rows, err := p.pool.Query(ctx, `
+	select u.id, u.email, coalesce(u.name, ''), coalesce(u.surname, ''), u.role,
+		u.status, coalesce(u.status_reason, ''), u.status_until, u.password_reset_required, u.totp_enabled,
+		u.deletion_requested_at,
+		(select max(last_seen_at) from sessions where userid = u.id),
+		count(*) over()
+	from users u`+q.whereSQL()+`
+	order by u.id`+q.bind(" limit ? offset ?", f.Limit, f.Offset), q.args...)
+	if err != nil {
+		return []entity.Account{}, 0, err
+	}
+	defer rows.Close()
+
+	var accounts []entity.Account
+	var total int
+
+	for rows.Next() {
+		var a entity.Account
+		err := rows.Scan(
+			&a.Users.ID,
+			&a.Users.Email,
+			&a.Users.Name,
+			&a.Users.Surname,
+			&a.Users.Role,
+			&a.Users.Status,
+			&a.Users.StatusReason,
+			&a.Users.StatusUntil,
+			&a.Users.PasswordResetRequired,
+			&a.Users.TOTPEnabled,
+			&a.DeletionRequestedAt,
+			&a.LastSeenAt,
+			&total,
+		)
+		if err != nil {
+			return []entity.Account{}, 0, err
+		}
+		accounts = append(accounts, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []entity.Account{}, 0, err
+	}
+
+	// За концом выдачи строк нет, и count(*) over() вернуть некому
+	if len(accounts) == 0 && f.Offset > 0 {
+		err := p.pool.QueryRow(ctx, "select count(*) from users u"+q.whereSQL(), countArgs...).Scan(&total)
+		if err != nil {
+			return []entity.Account{}, 0, err
+		}
+	}
+
+	return accounts, total, nil
+}
+
+// SetStatus блокирует или разблокирует пользователя. При блокировке все его сессии отзываются.
+func (p *PgxAdminRepo) SetStatus(ctx context.Context, userid int64, s entity.Sanction) error {
+	ctx, tx, err := begin(ctx, p.pool, "PgxAdminRepo.SetStatus")
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, "update users set status = $2, status_reason = nullif($3, ''), status_until = $4 where id = $1",
+		userid, s.Status, s.Reason, s.Until)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	if s.Status != entity.StatusActive {
+		_, err = tx.Exec(ctx, "update sessions set revoked_at = now() where userid = $1 and revoked_at is null", userid)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RequirePasswordReset запрещает вход по старому паролю и сохраняет токен для сброса.
+// Возвращает email пользователя, куда отправить ссылку для сброса.
+func (p *PgxAdminRepo) RequirePasswordReset(ctx context.Context, r entity.PasswordReset) (string, error) {
+	ctx, tx, err := begin(ctx, p.pool, "PgxAdminRepo.RequirePasswordReset")
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	var email string
+	err = tx.QueryRow(ctx, "update users set password_reset_required = true where id = $1 returning email", r.UserID).Scan(&email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", entity.ErrUserNotFound
+		}
+		return "", err
+	}
+
+	_, err = tx.Exec(ctx, `
+	insert into password_resets (userid, token_hash, expires_at) values ($1, $2, $3)
+	ON CONFLICT (userid) DO UPDATE SET token_hash = EXCLUDED.token_hash, expires_at = EXCLUDED.expires_at
+	`, r.UserID, r.TokenHash, r.ExpiresAt)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec(ctx, "update sessions set revoked_at = now() where userid = $1 and revoked_at is null", r.UserID)
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+
+	return email, nil
+}
+
+func (p *PgxAdminRepo) GetUserActivity(ctx context.Context, userid int64, limit int) (entity.UserActivity, error) {
+	var a entity.UserActivity
+
+	var exists bool
+	err := p.pool.QueryRow(ctx, "select exists(select 1 from users where id = $1)", userid).Scan(&exists)
+	if err != nil {
+		return entity.UserActivity{}, err
+	}
+	if !exists {
+		return entity.UserActivity{}, entity.ErrUserNotFound
+	}
+
+	rows, err := p.pool.Query(ctx, `
+	select id, user_agent, ip, created_at, last_seen_at, revoked_at
+	from sessions where userid = $1
+	order by last_seen_at desc limit $2
+	`, userid, limit)
+	if err != nil {
+		return entity.UserActivity{}, err
+	}
+	for rows.Next() {
+		s := entity.Session{UserID: userid}
+		if err := rows.Scan(&s.ID, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastSeenAt, &s.RevokedAt); err != nil {
+			rows.Close()
+			return entity.UserActivity{}, err
+		}
+		a.Sessions = append(a.Sessions, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return entity.UserActivity{}, err
+	}
+
+	rows, err = p.pool.Query(ctx, `
+	select id, coalesce(ip, ''), event, coalesce(details, ''), created_at
+	from audit_events where userid = $1
+	order by id desc limit $2
+	`, userid, limit)
+	if err != nil {
+		return entity.UserActivity{}, err
+	}
+	for rows.Next() {
+		e := entity.AuditEvent{UserID: userid}
+		if err := rows.Scan(&e.ID, &e.IP, &e.Event, &e.Details, &e.CreatedAt); err != nil {
+			rows.Close()
+			return entity.UserActivity{}, err
+		}
+		a.Events = append(a.Events, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return entity.UserActivity{}, err
+	}
+
+	rows, err = p.pool.Query(ctx, `
+	select m.id, m.title, m.year, r.rating
+	from ratings r
+	JOIN movies m ON m.id = r.movieid
+	where r.userid = $1
+	order by m.id desc limit $2
+	`, userid, limit)
+	if err != nil {
+		return entity.UserActivity{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m entity.MovieWithRating
+		if err := rows.Scan(&m.Movies.ID, &m.Movies.Name, &m.Movies.Year, &m.Rating); err != nil {
+			return entity.UserActivity{}, err
+		}
+		a.Ratings = append(a.Ratings, m)
+	}
+	if err := rows.Err(); err != nil {
+		return entity.UserActivity{}, err
+	}
+
+	return a, nil
+}
@@ -0,0 +1,22 @@
+package postgresdb
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/marcokz/movie-final/internal/entity"
+)
+
+type PgxAuditRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewAuditRepo(p *pgxpool.Pool) *PgxAuditRepo {
+	return &PgxAuditRepo{pool: p}
+}
+
+func (p *PgxAuditRepo) RecordEvent(ctx context.Context, e entity.AuditEvent) error {
+	_, err := p.pool.Exec(ctx, "insert into audit_events (userid, ip, event, details) values (nullif($1, 0), $2, $3, $4)",
+		e.UserID, e.IP, e.Event, e.Details)
+	return err
+}
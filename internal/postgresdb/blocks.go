@@ -0,0 +1,124 @@
+package postgresdb
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/marcokz/movie-final/internal/entity"
+	"github.com/marcokz/movie-final/internal/locale"
+)
+
+type PgxBlocksRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewBlocksRepo(p *pgxpool.Pool) *PgxBlocksRepo {
+	return &PgxBlocksRepo{pool: p}
+}
+
+// Block блокирует пользователя и удаляет подписки в обе стороны.
+func (p *PgxBlocksRepo) Block(ctx context.Context, blockerid, blockedid int64) error {
+	if blockerid == blockedid {
+		return entity.ErrCannotBlockSelf
+	}
+
+	ctx, tx, err := begin(ctx, p.pool, "PgxBlocksRepo.Block")
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, "insert into blocks (blockerid, blockedid) values ($1, $2) ON CONFLICT DO NOTHING", blockerid, blockedid)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return entity.ErrUserNotFound
+		}
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+	delete from follows
+	where (followerid = $1 and followeeid = $2) or (followerid = $2 and followeeid = $1)
+	`, blockerid, blockedid)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (p *PgxBlocksRepo) Unblock(ctx context.Context, blockerid, blockedid int64) error {
+	_, err := p.pool.Exec(ctx, "delete from blocks where blockerid = $1 and blockedid = $2", blockerid, blockedid)
+	return err
+}
+
+func (p *PgxBlocksRepo) Mute(ctx context.Context, muterid, mutedid int64) error {
+	if muterid == mutedid {
+		return entity.ErrCannotBlockSelf
+	}
+
+	_, err := p.pool.Exec(ctx, "insert into mutes (muterid, mutedid) values ($1, $2) ON CONFLICT DO NOTHING", muterid, mutedid)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return entity.ErrUserNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (p *PgxBlocksRepo) Unmute(ctx context.Context, muterid, mutedid int64) error {
+	_, err := p.pool.Exec(ctx, "delete from mutes where muterid = $1 and mutedid = $2", muterid, mutedid)
+	return err
+}
+
+// GetBlocked кого заблокировал пользователь, последние первыми.
+func (p *PgxBlocksRepo) GetBlocked(ctx context.Context, userid int64) ([]entity.User, error) {
+	return p.queryUsers(ctx, `
+	select `+userColumns("$1", "$2")+`
+	from blocks b
+	JOIN users u ON u.id = b.blockedid
+	where b.blockerid = $1
+	order by b.created_at desc
+	`, userid, locale.Language(ctx))
+}
+
+// GetMuted кого заглушил пользователь, последние первыми.
+func (p *PgxBlocksRepo) GetMuted(ctx context.Context, userid int64) ([]entity.User, error) {
+	return p.queryUsers(ctx, `
+	select `+userColumns("$1", "$2")+`
+	from mutes m
+	JOIN users u ON u.id = m.mutedid
+	where m.muterid = $1
+	order by m.created_at desc
+	`, userid, locale.Language(ctx))
+}
+
+func (p *PgxBlocksRepo) queryUsers(ctx context.Context, sql string, args ...any) ([]entity.User, error) {
+	rows, err := p.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return []entity.User{}, err
+	}
+	defer rows.Close()
+
+	var users []entity.User
+
+	for rows.Next() {
+		var u entity.User
+		if err := scanUser(rows, &u); err != nil {
+			return []entity.User{}, err
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []entity.User{}, err
+	}
+
+	return users, nil
+}
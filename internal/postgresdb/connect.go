@@ -7,17 +7,62 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func Connect(dsn string) (*pgxpool.Pool, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+// Коды ошибок postgres
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
+// PoolConfig нулевые значения оставляют настройки pgx по умолчанию
+type PoolConfig struct {
+	URL               string
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+	ConnectTimeout    time.Duration // на первое подключение и проверку, по умолчанию 3s
+}
+
+func Connect(c PoolConfig) (*pgxpool.Pool, error) {
+	cfg, err := pgxpool.ParseConfig(c.URL)
+	if err != nil {
+		return nil, err
+	}
+	if c.MaxConns > 0 {
+		cfg.MaxConns = c.MaxConns
+	}
+	if c.MinConns > 0 {
+		cfg.MinConns = c.MinConns
+	}
+	if c.MaxConnLifetime > 0 {
+		cfg.MaxConnLifetime = c.MaxConnLifetime
+	}
+	if c.MaxConnIdleTime > 0 {
+		cfg.MaxConnIdleTime = c.MaxConnIdleTime
+	}
+	if c.HealthCheckPeriod > 0 {
+		cfg.HealthCheckPeriod = c.HealthCheckPeriod
+	}
+
+	timeout := c.ConnectTimeout
+	if timeout <= 0 {
+		timeout = 3 * time.Second
+	}
+	cfg.ConnConfig.ConnectTimeout = timeout
+	cfg.ConnConfig.Tracer = queryTracer{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	pool, err := pgxpool.New(ctx, dsn)
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
 	if err != nil {
 		return nil, err
 	}
 
 	err = pool.Ping(ctx)
 	if err != nil {
+		pool.Close()
 		return nil, err
 	}
 
@@ -0,0 +1,254 @@
+package postgresdb
+
+import (
+	"context"
+	"time"
+
+	"github.com/marcokz/movie-final/internal/entity"
+)
+
+func (p *PgxUserRepo) RequestDeletion(ctx context.Context, id int64) error {
+	result, err := p.pool.Exec(ctx, "update users set deletion_requested_at = coalesce(deletion_requested_at, now()) where id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// CancelDeletion снимает запрос на удаление. Возвращает true, если запрос был.
+func (p *PgxUserRepo) CancelDeletion(ctx context.Context, id int64) (bool, error) {
+	result, err := p.pool.Exec(ctx, "update users set deletion_requested_at = null where id = $1 and deletion_requested_at is not null", id)
+	if err != nil {
+		return false, err
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// PurgeDeletedUsers окончательно удаляет пользователей, у которых истёк срок на отмену.
+// Оценки переносятся в anonymous_ratings без привязки к пользователю.
+func (p *PgxUserRepo) PurgeDeletedUsers(ctx context.Context, grace time.Duration) (int, error) {
+	rows, err := p.pool.Query(ctx, "select id from users where deletion_requested_at < $1", time.Now().Add(-grace))
+	if err != nil {
+		return 0, err
+	}
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, id := range ids {
+		if err := p.purgeUser(ctx, id); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+func (p *PgxUserRepo) purgeUser(ctx context.Context, id int64) error {
+	ctx, tx, err := begin(ctx, p.pool, "PgxUserRepo.purgeUser")
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, "insert into anonymous_ratings (movieid, rating) select movieid, rating from ratings where userid = $1", id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, "delete from ratings where userid = $1", id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, "delete from login_failures where key = (select 'email:' || lower(email) from users where id = $1)", id)
+	if err != nil {
+		return err
+	}
+
+	// Записи журнала остаются без userid, но раньше в details писался email
+	_, err = tx.Exec(ctx, `
+	update audit_events a set details = btrim(regexp_replace(a.details, 'email=\S*\s*', '', 'g'))
+	from users u
+	where u.id = $1 and (a.userid = u.id or strpos(lower(a.details), 'email=' || lower(u.email)) > 0)
+	`, id)
+	if err != nil {
+		return err
+	}
+
+	// Сессии, подписки, коды и прочее удаляются каскадом
+	_, err = tx.Exec(ctx, "delete from users where id = $1 and deletion_requested_at is not null", id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (p *PgxUserRepo) ExportUserData(ctx context.Context, id int64) (entity.UserExport, error) {
+	var e entity.UserExport
+	var err error
+
+	e.ExportedAt = time.Now()
+
+	e.Users, err = p.GetUserInfo(ctx, id)
+	if err != nil {
+		return entity.UserExport{}, err
+	}
+
+	e.Privacy, err = p.GetPrivacy(ctx, id)
+	if err != nil {
+		return entity.UserExport{}, err
+	}
+
+	rows, err := p.pool.Query(ctx, `
+	select m.id, m.title, m.year, r.rating
+	from ratings r
+	JOIN movies m ON m.id = r.movieid
+	where r.userid = $1
+	order by m.id
+	`, id)
+	if err != nil {
+		return entity.UserExport{}, err
+	}
+	for rows.Next() {
+		var m entity.MovieWithRating
+		if err := rows.Scan(&m.Movies.ID, &m.Movies.Name, &m.Movies.Year, &m.Rating); err != nil {
+			rows.Close()
+			return entity.UserExport{}, err
+		}
+		e.Ratings = append(e.Ratings, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return entity.UserExport{}, err
+	}
+
+	e.Following, err = p.queryIDs(ctx, "select followeeid from follows where followerid = $1 order by followeeid", id)
+	if err != nil {
+		return entity.UserExport{}, err
+	}
+
+	e.Followers, err = p.queryIDs(ctx, "select followerid from follows where followeeid = $1 order by followerid", id)
+	if err != nil {
+		return entity.UserExport{}, err
+	}
+
+	e.Blocked, err = p.queryIDs(ctx, "select blockedid from blocks where blockerid = $1 order by blockedid", id)
+	if err != nil {
+		return entity.UserExport{}, err
+	}
+
+	e.Muted, err = p.queryIDs(ctx, "select mutedid from mutes where muterid = $1 order by mutedid", id)
+	if err != nil {
+		return entity.UserExport{}, err
+	}
+
+	rows, err = p.pool.Query(ctx, "select id, user_agent, ip, created_at, last_seen_at, revoked_at from sessions where userid = $1 order by id", id)
+	if err != nil {
+		return entity.UserExport{}, err
+	}
+	for rows.Next() {
+		s := entity.Session{UserID: id}
+		if err := rows.Scan(&s.ID, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastSeenAt, &s.RevokedAt); err != nil {
+			rows.Close()
+			return entity.UserExport{}, err
+		}
+		e.Sessions = append(e.Sessions, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return entity.UserExport{}, err
+	}
+
+	rows, err = p.pool.Query(ctx, "select provider from user_identities where userid = $1 order by provider", id)
+	if err != nil {
+		return entity.UserExport{}, err
+	}
+	for rows.Next() {
+		var provider string
+		if err := rows.Scan(&provider); err != nil {
+			rows.Close()
+			return entity.UserExport{}, err
+		}
+		e.Identities = append(e.Identities, provider)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return entity.UserExport{}, err
+	}
+
+	rows, err = p.pool.Query(ctx, "select handle from handle_history where userid = $1 order by changed_at", id)
+	if err != nil {
+		return entity.UserExport{}, err
+	}
+	for rows.Next() {
+		var handle string
+		if err := rows.Scan(&handle); err != nil {
+			rows.Close()
+			return entity.UserExport{}, err
+		}
+		e.Handles = append(e.Handles, handle)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return entity.UserExport{}, err
+	}
+
+	rows, err = p.pool.Query(ctx, "select id, coalesce(ip, ''), event, coalesce(details, ''), created_at from audit_events where userid = $1 order by id", id)
+	if err != nil {
+		return entity.UserExport{}, err
+	}
+	for rows.Next() {
+		a := entity.AuditEvent{UserID: id}
+		if err := rows.Scan(&a.ID, &a.IP, &a.Event, &a.Details, &a.CreatedAt); err != nil {
+			rows.Close()
+			return entity.UserExport{}, err
+		}
+		e.Audit = append(e.Audit, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return entity.UserExport{}, err
+	}
+
+	return e, nil
+}
+
+func (p *PgxUserRepo) queryIDs(ctx context.Context, sql string, args ...any) ([]int64, error) {
+	rows, err := p.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
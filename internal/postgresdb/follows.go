@@ -0,0 +1,49 @@
+package postgresdb
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/marcokz/movie-final/internal/entity"
+)
+
+type PgxFollowsRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewFollowsRepo(p *pgxpool.Pool) *PgxFollowsRepo {
+	return &PgxFollowsRepo{pool: p}
+}
+
+func (p *PgxFollowsRepo) Follow(ctx context.Context, followerid, followeeid int64) error {
+	if followerid == followeeid {
+		return entity.ErrCannotFollowSelf
+	}
+
+	var blocked bool
+	err := p.pool.QueryRow(ctx, "select blocked_between($1, $2)", followerid, followeeid).Scan(&blocked)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return entity.ErrBlocked
+	}
+
+	_, err = p.pool.Exec(ctx, "insert into follows (followerid, followeeid) values ($1, $2) ON CONFLICT DO NOTHING", followerid, followeeid)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return entity.ErrUserNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (p *PgxFollowsRepo) Unfollow(ctx context.Context, followerid, followeeid int64) error {
+	_, err := p.pool.Exec(ctx, "delete from follows where followerid = $1 and followeeid = $2", followerid, followeeid)
+	return err
+}
@@ -0,0 +1,44 @@
+package postgresdb
+
+import (
+	"math"
+
+	"github.com/marcokz/movie-final/internal/entity"
+)
+
+const (
+	earthRadiusKm = 6371.0
+	kmPerDegree   = earthRadiusKm * math.Pi / 180
+)
+
+// distanceKm расстояние в км по большому кругу (haversine, радиус Земли earthRadiusKm)
+// от точки до города c. Параметры: широта точки, ещё раз широта, долгота.
+const distanceKm = `(2 * 6371.0 * asin(sqrt(least(1,
+	power(sin(radians(c.latitude - ?) / 2), 2)
+	+ cos(radians(?)) * cos(radians(c.latitude)) * power(sin(radians(c.longitude - ?) / 2), 2)))))`
+
+// withinRadius добавляет условия на город c: грубый отбор прямоугольником по индексу
+// широты, затем точное расстояние.
+func withinRadius(q *queryBuilder, p entity.GeoPoint, radiusKm float64) string {
+	dLat := radiusKm / kmPerDegree
+	q.where("c.latitude BETWEEN ? AND ?", p.Latitude-dLat, p.Latitude+dLat)
+
+	// У полюсов и на больших радиусах прямоугольник по долготе теряет смысл
+	if math.Abs(p.Latitude)+dLat < 90 {
+		dLon := dLat / math.Cos(p.Latitude*math.Pi/180)
+		minLon, maxLon := p.Longitude-dLon, p.Longitude+dLon
+		switch {
+		case dLon >= 180:
+		case minLon < -180:
+			q.where("(c.longitude >= ? or c.longitude <= ?)", minLon+360, maxLon)
+		case maxLon > 180:
+			q.where("(c.longitude >= ? or c.longitude <= ?)", minLon, maxLon-360)
+		default:
+			q.where("c.longitude BETWEEN ? AND ?", minLon, maxLon)
+		}
+	}
+
+	distance := q.bind(distanceKm, p.Latitude, p.Latitude, p.Longitude)
+	q.where(distance+" <= ?", radiusKm)
+	return distance
+}
@@ -0,0 +1,101 @@
+package postgresdb
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/marcokz/movie-final/internal/entity"
+)
+
+func isHandleConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && pgErr.ConstraintName == "users_handle_key"
+}
+
+// handleHeld ник раньше принадлежал другому пользователю (не userid) и пока ведёт на него.
+func handleHeld(ctx context.Context, tx pgx.Tx, handle string, userid int64) (bool, error) {
+	var held bool
+	err := tx.QueryRow(ctx, "select exists(select 1 from handle_history where lower(handle) = lower($1) and userid <> $2)", handle, userid).
+		Scan(&held)
+	return held, err
+}
+
+// SetHandle меняет ник. Прежний ник попадает в историю и дальше ведёт на этого
+// пользователя, вернуть его себе можно в любой момент.
+func (p *PgxUserRepo) SetHandle(ctx context.Context, id int64, handle string) error {
+	ctx, tx, err := begin(ctx, p.pool, "PgxUserRepo.SetHandle")
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var current string
+	err = tx.QueryRow(ctx, "select coalesce(handle, '') from users where id = $1 for update", id).Scan(&current)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.ErrUserNotFound
+		}
+		return err
+	}
+	if current == handle {
+		return nil
+	}
+
+	held, err := handleHeld(ctx, tx, handle, id)
+	if err != nil {
+		return err
+	}
+	if held {
+		return entity.ErrHandleTaken
+	}
+
+	_, err = tx.Exec(ctx, "update users set handle = $2 where id = $1", id, handle)
+	if err != nil {
+		if isHandleConflict(err) {
+			return entity.ErrHandleTaken
+		}
+		return err
+	}
+
+	_, err = tx.Exec(ctx, "delete from handle_history where userid = $1 and lower(handle) = lower($2)", id, handle)
+	if err != nil {
+		return err
+	}
+
+	// Смена только регистра историю не пополняет
+	if current != "" && !strings.EqualFold(current, handle) {
+		_, err = tx.Exec(ctx, "insert into handle_history (handle, userid) values ($1, $2) ON CONFLICT ((lower(handle))) DO NOTHING", current, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ResolveHandle id пользователя и его текущий ник по нику, в том числе прежнему.
+func (p *PgxUserRepo) ResolveHandle(ctx context.Context, handle string) (int64, string, error) {
+	var id int64
+	var current string
+
+	err := p.pool.QueryRow(ctx, `
+	select id, handle from users where lower(handle) = lower($1)
+	UNION ALL
+	select u.id, u.handle
+	from handle_history h
+	JOIN users u ON u.id = h.userid
+	where lower(h.handle) = lower($1) and u.handle is not null
+	limit 1
+	`, handle).Scan(&id, &current)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, "", entity.ErrUserNotFound
+		}
+		return 0, "", err
+	}
+
+	return id, current, nil
+}
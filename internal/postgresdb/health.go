@@ -0,0 +1,35 @@
+package postgresdb
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PgxHealthRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewHealthRepo(p *pgxpool.Pool) *PgxHealthRepo {
+	return &PgxHealthRepo{pool: p}
+}
+
+func (p *PgxHealthRepo) Ping(ctx context.Context) error {
+	return p.pool.Ping(ctx)
+}
+
+// SchemaVersion последняя применённая миграция goose. Откат старым goose оставляет
+// у версии прежнюю строку с is_applied, поэтому смотрим только на последнюю запись
+// по каждой версии, как migrate.Migrator.
+func (p *PgxHealthRepo) SchemaVersion(ctx context.Context) (int64, error) {
+	var version int64
+	err := p.pool.QueryRow(ctx, `
+	select coalesce(max(version_id), 0) from (
+		select distinct on (version_id) version_id, is_applied
+		from goose_db_version
+		order by version_id, id desc
+	) latest
+	where is_applied
+	`).Scan(&version)
+	return version, err
+}
@@ -0,0 +1,93 @@
+package postgresdb
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/marcokz/movie-final/internal/entity"
+)
+
+type PgxIdentitiesRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewIdentitiesRepo(p *pgxpool.Pool) *PgxIdentitiesRepo {
+	return &PgxIdentitiesRepo{pool: p}
+}
+
+func (p *PgxIdentitiesRepo) CreateLogin(ctx context.Context, l entity.OIDCLogin) error {
+	_, err := p.pool.Exec(ctx, "insert into oidc_logins (state, provider, nonce, verifier, expires_at) values ($1, $2, $3, $4, $5)",
+		l.State, l.Provider, l.Nonce, l.Verifier, l.ExpiresAt)
+	return err
+}
+
+// TakeLogin достаёт и сразу удаляет начатый вход, чтобы state нельзя было использовать повторно.
+func (p *PgxIdentitiesRepo) TakeLogin(ctx context.Context, state string) (entity.OIDCLogin, error) {
+	var l entity.OIDCLogin
+
+	err := p.pool.QueryRow(ctx, "delete from oidc_logins where state = $1 returning state, provider, nonce, verifier, expires_at", state).
+		Scan(&l.State, &l.Provider, &l.Nonce, &l.Verifier, &l.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.OIDCLogin{}, entity.ErrInvalidToken
+		}
+		return entity.OIDCLogin{}, err
+	}
+
+	return l, nil
+}
+
+// PurgeExpiredLogins удаляет брошенные входы, до callback которых пользователь так и не дошёл.
+func (p *PgxIdentitiesRepo) PurgeExpiredLogins(ctx context.Context) (int, error) {
+	tag, err := p.pool.Exec(ctx, "delete from oidc_logins where expires_at < now()")
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// LinkIdentity находит пользователя по внешней учётной записи. Если связи ещё нет,
+// привязывает к пользователю с тем же подтверждённым email или создаёт нового.
+func (p *PgxIdentitiesRepo) LinkIdentity(ctx context.Context, provider, subject, email string, emailVerified bool) (int64, error) {
+	ctx, tx, err := begin(ctx, p.pool, "PgxIdentitiesRepo.LinkIdentity")
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var userid int64
+	err = tx.QueryRow(ctx, "select userid from user_identities where provider = $1 and subject = $2", provider, subject).Scan(&userid)
+	if err == nil {
+		return userid, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, err
+	}
+
+	// Без подтверждённого email привязка позволила бы захватить чужой аккаунт
+	if email == "" || !emailVerified {
+		return 0, entity.ErrEmailNotVerified
+	}
+
+	err = tx.QueryRow(ctx, "select id from users where lower(email) = lower($1)", email).Scan(&userid)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Пароля нет: такой пользователь входит только через провайдера
+		err = tx.QueryRow(ctx, "insert into users (email, password) values ($1, '') returning id", email).Scan(&userid)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, "insert into user_identities (provider, subject, userid) values ($1, $2, $3)", provider, subject, userid)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return userid, nil
+}
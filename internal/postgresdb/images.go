@@ -0,0 +1,53 @@
+package postgresdb
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/marcokz/movie-final/internal/entity"
+)
+
+type PgxImagesRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewImagesRepo(p *pgxpool.Pool) *PgxImagesRepo {
+	return &PgxImagesRepo{pool: p}
+}
+
+// SaveImage запоминает картинку. Одинаковые файлы имеют один hash, повтор не ошибка.
+func (p *PgxImagesRepo) SaveImage(ctx context.Context, img entity.Image) error {
+	_, err := p.pool.Exec(ctx, `
+	insert into images (hash, content_type, width, height, size) values ($1, $2, $3, $4, $5)
+	ON CONFLICT (hash) DO NOTHING
+	`, img.Hash, img.ContentType, img.Width, img.Height, img.Size)
+	return err
+}
+
+// SetAvatar hash == "" убирает аватар.
+func (p *PgxUserRepo) SetAvatar(ctx context.Context, id int64, hash string) error {
+	result, err := p.pool.Exec(ctx, "update users set avatar = nullif($2, '') where id = $1", id, hash)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// SetPoster hash == "" убирает постер.
+func (p *PgxMoviesRepo) SetPoster(ctx context.Context, id int64, hash string) error {
+	result, err := p.pool.Exec(ctx, "update movies set poster = nullif($2, '') where id = $1", id, hash)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return entity.ErrMovieNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,265 @@
+package postgresdb
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/marcokz/movie-final/internal/entity"
+	"github.com/marcokz/movie-final/internal/locale"
+)
+
+// Сколько строк справочника отправляем в базу за один batch при импорте
+const importBatchSize = 1000
+
+type PgxLocationsRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewLocationsRepo(p *pgxpool.Pool) *PgxLocationsRepo {
+	return &PgxLocationsRepo{pool: p}
+}
+
+// locationError переводит нарушение внешнего ключа на страну или город в ошибку для клиента.
+func locationError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		switch pgErr.ConstraintName {
+		case "users_country_fkey":
+			return entity.ErrUnknownCountry
+		case "users_cityid_fkey":
+			return entity.ErrCityNotFound
+		}
+	}
+	return err
+}
+
+// likePrefix шаблон LIKE для поиска по началу строки без учёта регистра.
+func likePrefix(s string) string {
+	return likeEscape(strings.ToLower(strings.TrimSpace(s))) + "%"
+}
+
+// likeContains шаблон LIKE для поиска подстроки.
+func likeContains(s string) string {
+	return "%" + likeEscape(strings.TrimSpace(s)) + "%"
+}
+
+// likeEscape экранирует спецсимволы LIKE, чтобы ввод искался буквально.
+func likeEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// SearchCountries подсказки для ввода страны: по коду, английскому названию или любому переводу.
+func (p *PgxLocationsRepo) SearchCountries(ctx context.Context, prefix string, limit int) ([]entity.Country, error) {
+	rows, err := p.pool.Query(ctx, `
+	select c.code, country_name(c.code, $3)
+	from countries c
+	where lower(c.code) = lower($1)
+		or lower(c.name) like $2
+		or exists(select 1 from country_names n where n.code = c.code and lower(n.name) like $2)
+	order by lower(c.code) = lower($1) desc, country_name(c.code, $3)
+	limit $4
+	`, strings.TrimSpace(prefix), likePrefix(prefix), locale.Language(ctx), limit)
+	if err != nil {
+		return []entity.Country{}, err
+	}
+	defer rows.Close()
+
+	var countries []entity.Country
+
+	for rows.Next() {
+		var c entity.Country
+		if err := rows.Scan(&c.Code, &c.Name); err != nil {
+			return []entity.Country{}, err
+		}
+		countries = append(countries, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []entity.Country{}, err
+	}
+
+	return countries, nil
+}
+
+// SearchCities подсказки для ввода города, крупные города первыми. country необязателен.
+func (p *PgxLocationsRepo) SearchCities(ctx context.Context, prefix, country string, limit int) ([]entity.City, error) {
+	var q queryBuilder
+	lang := q.bind("?", locale.Language(ctx))
+
+	q.where(`(lower(c.name) like ? or lower(c.ascii_name) like ?
+		or exists(select 1 from city_names n where n.cityid = c.id and lower(n.name) like ?))`,
+		likePrefix(prefix), likePrefix(prefix), likePrefix(prefix))
+	if country != "" {
+		q.where("c.country = ?", country)
+	}
+
+	rows, err := p.pool.Query(ctx, `
+	select c.id, city_name(c.id, `+lang+`), c.country, country_name(c.country, `+lang+`),
+		c.latitude, c.longitude, c.population
+	from cities c`+q.whereSQL()+`
+	order by c.population desc, c.id`+q.bind(" limit ?", limit), q.args...)
+	if err != nil {
+		return []entity.City{}, err
+	}
+	defer rows.Close()
+
+	var cities []entity.City
+
+	for rows.Next() {
+		var c entity.City
+		if err := rows.Scan(&c.ID, &c.Name, &c.Country, &c.CountryName, &c.Latitude, &c.Longitude, &c.Population); err != nil {
+			return []entity.City{}, err
+		}
+		cities = append(cities, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []entity.City{}, err
+	}
+
+	return cities, nil
+}
+
+// ResolveCountry код страны по коду или названию на любом языке: "KZ", "kazakhstan", "Казахстан".
+func (p *PgxLocationsRepo) ResolveCountry(ctx context.Context, text string) (string, error) {
+	var code string
+
+	err := p.pool.QueryRow(ctx, `
+	select c.code
+	from countries c
+	where lower(c.code) = $1
+		or lower(c.name) = $1
+		or exists(select 1 from country_names n where n.code = c.code and lower(n.name) = $1)
+	order by lower(c.code) = $1 desc
+	limit 1
+	`, strings.ToLower(strings.TrimSpace(text))).Scan(&code)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", entity.ErrUnknownCountry
+		}
+		return "", err
+	}
+
+	return code, nil
+}
+
+func (p *PgxLocationsRepo) GetCity(ctx context.Context, id int64) (entity.City, error) {
+	var c entity.City
+
+	err := p.pool.QueryRow(ctx, `
+	select id, city_name(id, $2), country, country_name(country, $2), latitude, longitude, population
+	from cities where id = $1
+	`, id, locale.Language(ctx)).Scan(&c.ID, &c.Name, &c.Country, &c.CountryName, &c.Latitude, &c.Longitude, &c.Population)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.City{}, entity.ErrCityNotFound
+		}
+		return entity.City{}, err
+	}
+
+	return c, nil
+}
+
+// ImportCountries добавляет страны из countryInfo.txt или обновляет их названия.
+func (p *PgxLocationsRepo) ImportCountries(ctx context.Context, countries []entity.Country) error {
+	batch := &pgx.Batch{}
+	for _, c := range countries {
+		batch.Queue(`
+		insert into countries (code, name) values ($1, $2)
+		ON CONFLICT (code) DO UPDATE SET name = EXCLUDED.name
+		`, c.Code, c.Name)
+	}
+	return p.sendBatches(ctx, batch)
+}
+
+// ImportCities добавляет города или обновляет их. Города неизвестных стран пропускаются.
+func (p *PgxLocationsRepo) ImportCities(ctx context.Context, cities []entity.City) error {
+	batch := &pgx.Batch{}
+	for _, c := range cities {
+		batch.Queue(`
+		insert into cities (id, country, name, ascii_name, latitude, longitude, population)
+		select $1, $2, $3, $4, $5, $6, $7
+		where exists(select 1 from countries where code = $2)
+		ON CONFLICT (id) DO UPDATE SET country = EXCLUDED.country, name = EXCLUDED.name,
+			ascii_name = EXCLUDED.ascii_name, latitude = EXCLUDED.latitude,
+			longitude = EXCLUDED.longitude, population = EXCLUDED.population
+		`, c.ID, c.Country, c.Name, c.ASCIIName, c.Latitude, c.Longitude, c.Population)
+	}
+	return p.sendBatches(ctx, batch)
+}
+
+// ImportCountryNames переводы названий стран, PlaceName.Code код страны.
+func (p *PgxLocationsRepo) ImportCountryNames(ctx context.Context, names []entity.PlaceName) error {
+	batch := &pgx.Batch{}
+	for _, n := range names {
+		batch.Queue(`
+		insert into country_names (code, lang, name)
+		select $1, $2, $3
+		where exists(select 1 from countries where code = $1)
+		ON CONFLICT (code, lang) DO UPDATE SET name = EXCLUDED.name
+		`, n.Code, n.Lang, n.Name)
+	}
+	return p.sendBatches(ctx, batch)
+}
+
+// ImportCityNames переводы названий городов, PlaceName.ID geonameid города.
+func (p *PgxLocationsRepo) ImportCityNames(ctx context.Context, names []entity.PlaceName) error {
+	batch := &pgx.Batch{}
+	for _, n := range names {
+		batch.Queue(`
+		insert into city_names (cityid, lang, name)
+		select $1, $2, $3
+		where exists(select 1 from cities where id = $1)
+		ON CONFLICT (cityid, lang) DO UPDATE SET name = EXCLUDED.name
+		`, n.ID, n.Lang, n.Name)
+	}
+	return p.sendBatches(ctx, batch)
+}
+
+// MatchLegacyCities сопоставляет города, введённые текстом до появления справочника.
+// Ищем в стране пользователя по названию на любом языке, при совпадении берём самый крупный город.
+func (p *PgxLocationsRepo) MatchLegacyCities(ctx context.Context) (int64, error) {
+	result, err := p.pool.Exec(ctx, `
+	with matches as (
+		select u.id as userid, (
+			select c.id
+			from cities c
+			where (u.country is null or c.country = u.country)
+				and (lower(c.name) = lower(u.legacy_city)
+					or lower(c.ascii_name) = lower(u.legacy_city)
+					or exists(select 1 from city_names n where n.cityid = c.id and lower(n.name) = lower(u.legacy_city)))
+			order by c.population desc, c.id
+			limit 1
+		) as cityid
+		from users u
+		where u.legacy_city is not null and u.cityid is null
+	)
+	update users u
+	set cityid = m.cityid,
+		country = coalesce(u.country, (select country from cities where id = m.cityid)),
+		legacy_city = null
+	from matches m
+	where m.userid = u.id and m.cityid is not null
+	`)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
+
+func (p *PgxLocationsRepo) sendBatches(ctx context.Context, batch *pgx.Batch) error {
+	for start := 0; start < len(batch.QueuedQueries); start += importBatchSize {
+		end := min(start+importBatchSize, len(batch.QueuedQueries))
+
+		part := &pgx.Batch{QueuedQueries: batch.QueuedQueries[start:end]}
+		if err := p.pool.SendBatch(ctx, part).Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package postgresdb
+
+import "testing"
+
+func TestLikePatterns(t *testing.T) {
+	tests := []struct {
+		in       string
+		prefix   string
+		contains string
+	}{
+		{"Mos", "mos%", "%Mos%"},
+		{"  spb ", "spb%", "%spb%"},
+		{"100%", `100\%%`, `%100\%%`},
+		{"a_b", `a\_b%`, `%a\_b%`},
+		{`back\slash`, `back\\slash%`, `%back\\slash%`},
+		{"%", `\%%`, `%\%%`},
+	}
+	for _, tt := range tests {
+		if got := likePrefix(tt.in); got != tt.prefix {
+			t.Errorf("likePrefix(%q) = %q, want %q", tt.in, got, tt.prefix)
+		}
+		if got := likeContains(tt.in); got != tt.contains {
+			t.Errorf("likeContains(%q) = %q, want %q", tt.in, got, tt.contains)
+		}
+	}
+}
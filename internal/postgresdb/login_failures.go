@@ -0,0 +1,64 @@
+package postgresdb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PgxLoginFailuresRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewLoginFailuresRepo(p *pgxpool.Pool) *PgxLoginFailuresRepo {
+	return &PgxLoginFailuresRepo{pool: p}
+}
+
+func (p *PgxLoginFailuresRepo) LockedUntil(ctx context.Context, key string) (time.Time, error) {
+	var until *time.Time
+
+	err := p.pool.QueryRow(ctx, "select locked_until from login_failures where key = $1", key).Scan(&until)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+
+	if until == nil {
+		return time.Time{}, nil
+	}
+	return *until, nil
+}
+
+// RegisterFailure увеличивает счётчик неудачных попыток и возвращает его новое значение.
+// Если последняя неудача была раньше чем window назад, счёт начинается заново.
+func (p *PgxLoginFailuresRepo) RegisterFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	var failures int
+
+	err := p.pool.QueryRow(ctx, `
+	insert into login_failures (key, failures, last_failure_at) values ($1, 1, now())
+	ON CONFLICT (key) DO UPDATE SET
+		failures = case when login_failures.last_failure_at < now() - make_interval(secs => $2) then 1 else login_failures.failures + 1 end,
+		last_failure_at = now()
+	returning failures
+	`, key, window.Seconds()).Scan(&failures)
+	if err != nil {
+		return 0, err
+	}
+
+	return failures, nil
+}
+
+func (p *PgxLoginFailuresRepo) Lock(ctx context.Context, key string, until time.Time) error {
+	_, err := p.pool.Exec(ctx, "update login_failures set locked_until = $2 where key = $1", key, until)
+	return err
+}
+
+func (p *PgxLoginFailuresRepo) ResetFailures(ctx context.Context, key string) error {
+	_, err := p.pool.Exec(ctx, "delete from login_failures where key = $1", key)
+	return err
+}
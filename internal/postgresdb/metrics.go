@@ -0,0 +1,30 @@
+package postgresdb
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/marcokz/movie-final/internal/metrics"
+)
+
+// RegisterPoolMetrics статистика пула, снимается при каждом чтении метрик
+func RegisterPoolMetrics(r *metrics.Registry, pool *pgxpool.Pool) {
+	stat := func(fn func(s *pgxpool.Stat) float64) func() float64 {
+		return func() float64 { return fn(pool.Stat()) }
+	}
+
+	r.GaugeFunc("db_pool_acquired_conns", "Connections currently in use.",
+		stat(func(s *pgxpool.Stat) float64 { return float64(s.AcquiredConns()) }))
+	r.GaugeFunc("db_pool_idle_conns", "Idle connections in the pool.",
+		stat(func(s *pgxpool.Stat) float64 { return float64(s.IdleConns()) }))
+	r.GaugeFunc("db_pool_total_conns", "All open connections in the pool.",
+		stat(func(s *pgxpool.Stat) float64 { return float64(s.TotalConns()) }))
+	r.GaugeFunc("db_pool_max_conns", "Maximum pool size.",
+		stat(func(s *pgxpool.Stat) float64 { return float64(s.MaxConns()) }))
+	r.CounterFunc("db_pool_acquires_total", "Successful connection acquires.",
+		stat(func(s *pgxpool.Stat) float64 { return float64(s.AcquireCount()) }))
+	r.CounterFunc("db_pool_empty_acquires_total", "Acquires that had to wait for a free connection.",
+		stat(func(s *pgxpool.Stat) float64 { return float64(s.EmptyAcquireCount()) }))
+	r.CounterFunc("db_pool_canceled_acquires_total", "Acquires canceled while waiting.",
+		stat(func(s *pgxpool.Stat) float64 { return float64(s.CanceledAcquireCount()) }))
+	r.CounterFunc("db_pool_acquire_wait_seconds_total", "Total time spent acquiring connections.",
+		stat(func(s *pgxpool.Stat) float64 { return s.AcquireDuration().Seconds() }))
+}
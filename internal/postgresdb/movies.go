@@ -5,10 +5,27 @@ import (
 	"errors"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/marcokz/movie-final/internal/entity"
 )
 
+// Оценки удалённых пользователей лежат в anonymous_ratings и учитываются наравне с остальными
+const movieRatingsJoin = `
+	left join lateral (
+		select count(*) as count, coalesce(avg(rating), 0)::float8 as average
+		from (
+			select rating from ratings where movieid = m.id
+			union all
+			select rating from anonymous_ratings where movieid = m.id
+		) all_ratings
+	) agg on true`
+
+func isMovieConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && pgErr.ConstraintName == "movies_title_year_key"
+}
+
 type PgxMoviesRepo struct {
 	pool *pgxpool.Pool
 }
@@ -18,16 +35,19 @@ func NewMoviesRepo(p *pgxpool.Pool) *PgxMoviesRepo {
 }
 
 func (p *PgxMoviesRepo) CreateMovie(ctx context.Context, m entity.Movie) error {
-	_, err := p.pool.Exec(ctx, "insert into movie (name, year, description) values ($1, $2, $3)", m.Name, m.Year, m.Description)
+	_, err := p.pool.Exec(ctx, "insert into movies (title, year, description) values ($1, $2, $3)", m.Name, m.Year, m.Description)
 	if err != nil {
-		return errors.New("the movie already exists")
+		if isMovieConflict(err) {
+			return entity.ErrMovieExists
+		}
+		return err
 	}
 
 	return nil
 }
 
 func (p *PgxMoviesRepo) GetMovies(ctx context.Context) ([]entity.Movie, error) {
-	rows, err := p.pool.Query(ctx, "select id, name, year from movie")
+	rows, err := p.pool.Query(ctx, "select m.id, m.title, m.year, coalesce(m.poster, ''), agg.count, agg.average from movies m"+movieRatingsJoin+" order by m.id")
 	if err != nil {
 		return []entity.Movie{}, err
 	}
@@ -41,6 +61,9 @@ func (p *PgxMoviesRepo) GetMovies(ctx context.Context) ([]entity.Movie, error) {
 			&m.ID,
 			&m.Name,
 			&m.Year,
+			&m.Poster,
+			&m.RatingsCount,
+			&m.AverageRating,
 		)
 		if err != nil {
 			return []entity.Movie{}, err
@@ -58,8 +81,8 @@ func (p *PgxMoviesRepo) GetMovies(ctx context.Context) ([]entity.Movie, error) {
 func (p *PgxMoviesRepo) GetMoviesByID(ctx context.Context, id int64) (entity.Movie, error) {
 	var e entity.Movie
 
-	err := p.pool.QueryRow(ctx, "select id, name, year from movie where id = $1", id).
-		Scan(&e.ID, &e.Name, &e.Year)
+	err := p.pool.QueryRow(ctx, "select m.id, m.title, m.year, coalesce(m.poster, ''), agg.count, agg.average from movies m"+movieRatingsJoin+" where m.id = $1", id).
+		Scan(&e.ID, &e.Name, &e.Year, &e.Poster, &e.RatingsCount, &e.AverageRating)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return entity.Movie{}, entity.ErrMovieNotFound
@@ -71,8 +94,11 @@ func (p *PgxMoviesRepo) GetMoviesByID(ctx context.Context, id int64) (entity.Mov
 }
 
 func (p *PgxMoviesRepo) UpdateMovieByID(ctx context.Context, m entity.Movie) error {
-	result, err := p.pool.Exec(ctx, "update movie set name = $2, year = $3 where id = $1", m.ID, m.Name, m.Year)
+	result, err := p.pool.Exec(ctx, "update movies set title = $2, year = $3 where id = $1", m.ID, m.Name, m.Year)
 	if err != nil {
+		if isMovieConflict(err) {
+			return entity.ErrMovieExists
+		}
 		return err
 	}
 
@@ -84,7 +110,7 @@ func (p *PgxMoviesRepo) UpdateMovieByID(ctx context.Context, m entity.Movie) err
 }
 
 func (p *PgxMoviesRepo) DeleteMovieByID(ctx context.Context, id int64) error {
-	result, err := p.pool.Exec(ctx, "delete from movie where id = $1", id)
+	result, err := p.pool.Exec(ctx, "delete from movies where id = $1", id)
 	if err != nil {
 		return err
 	}
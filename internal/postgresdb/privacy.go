@@ -0,0 +1,108 @@
+package postgresdb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/marcokz/movie-final/internal/entity"
+)
+
+// userColumns колонки пользователя u с учётом его настроек приватности для смотрящего viewer
+// (номер параметра, например "$1"). Точную дату рождения видит только сам пользователь,
+// остальным достаётся возрастной диапазон. Названия страны и города переводятся на язык lang
+// (тоже номер параметра). Читать результат нужно через scanUser.
+func userColumns(viewer, lang string) string {
+	return `u.id, coalesce(u.handle, ''), coalesce(u.name, ''), coalesce(u.surname, ''), coalesce(u.avatar, ''),
+		case when can_see(u.sex_visibility, u.id, ` + viewer + `) then coalesce(u.sex, '') else '' end,
+		case when u.id = ` + viewer + ` then u.dateofbirth end,
+		case when can_see(u.dateofbirth_visibility, u.id, ` + viewer + `) then coalesce(age_bucket(u.dateofbirth), '') else '' end,
+		case when can_see(u.country_visibility, u.id, ` + viewer + `) then coalesce(u.country, '') else '' end,
+		case when can_see(u.country_visibility, u.id, ` + viewer + `) then coalesce(country_name(u.country, ` + lang + `), '') else '' end,
+		case when can_see(u.city_visibility, u.id, ` + viewer + `) then coalesce(u.cityid, 0) else 0 end,
+		case when can_see(u.city_visibility, u.id, ` + viewer + `) then coalesce(city_name(u.cityid, ` + lang + `), '') else '' end`
+}
+
+// userScanDest адреса для колонок userColumns; extra добавляются после них.
+func userScanDest(u *entity.User, dateOfBirth **time.Time, extra ...any) []any {
+	return append([]any{
+		&u.ID,
+		&u.Handle,
+		&u.Name,
+		&u.Surname,
+		&u.Avatar,
+		&u.Sex,
+		dateOfBirth,
+		&u.AgeRange,
+		&u.Country,
+		&u.CountryName,
+		&u.CityID,
+		&u.City,
+	}, extra...)
+}
+
+func scanUser(row pgx.Row, u *entity.User, extra ...any) error {
+	var dateOfBirth *time.Time
+	if err := row.Scan(userScanDest(u, &dateOfBirth, extra...)...); err != nil {
+		return err
+	}
+	if dateOfBirth != nil {
+		u.DateOfBirth = *dateOfBirth
+	}
+	return nil
+}
+
+// Условия where: поле участвует в фильтре, только если смотрящий может его видеть,
+// иначе сам факт попадания в выдачу раскрыл бы скрытое значение.
+func visibleTo(column, viewer string) string {
+	return "can_see(u." + column + "_visibility, u.id, " + viewer + ")"
+}
+
+// notHidden пользователь u не заблокирован смотрящим (или наоборот), не заглушён им
+// и не ждёт удаления аккаунта.
+func notHidden(viewer string) string {
+	return notDeleted + " and not hidden_from(u.id, " + viewer + ")"
+}
+
+// notDeleted аккаунт, запросивший удаление, пропадает из выдачи на весь срок ожидания
+const notDeleted = "u.deletion_requested_at is null"
+
+// ageOverlaps фильтр по возрасту работает по диапазонам, а не по точному возрасту,
+// чтобы узким фильтром нельзя было вычислить дату рождения. NULL вместо границы
+// оставляет диапазон открытым с этой стороны.
+const ageOverlaps = "age_bucket_range(u.dateofbirth) && int4range(?, ?, '[]')"
+
+func (p *PgxUserRepo) GetPrivacy(ctx context.Context, id int64) (entity.Privacy, error) {
+	var pr entity.Privacy
+
+	err := p.pool.QueryRow(ctx, `
+	select sex_visibility, dateofbirth_visibility, country_visibility, city_visibility, ratings_visibility
+	from users where id = $1
+	`, id).Scan(&pr.Sex, &pr.DateOfBirth, &pr.Country, &pr.City, &pr.Ratings)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.Privacy{}, entity.ErrUserNotFound
+		}
+		return entity.Privacy{}, err
+	}
+
+	return pr, nil
+}
+
+func (p *PgxUserRepo) UpdatePrivacy(ctx context.Context, id int64, pr entity.Privacy) error {
+	result, err := p.pool.Exec(ctx, `
+	update users set sex_visibility = $2, dateofbirth_visibility = $3, country_visibility = $4,
+		city_visibility = $5, ratings_visibility = $6
+	where id = $1
+	`, id, pr.Sex, pr.DateOfBirth, pr.Country, pr.City, pr.Ratings)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package postgresdb
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/marcokz/movie-final/internal/entity"
+	"github.com/marcokz/movie-final/internal/locale"
+)
+
+// Сколько лучших фильмов показываем в публичном профиле
+const profileTopMovies = 5
+
+func (p *PgxUserRepo) GetUserInfo(ctx context.Context, id int64) (entity.User, error) {
+	var u entity.User
+	var dateOfBirth *time.Time
+
+	err := p.pool.QueryRow(ctx, `
+	select id, coalesce(handle, ''), email, coalesce(name, ''), coalesce(surname, ''), coalesce(avatar, ''), coalesce(sex, ''), dateofbirth,
+		coalesce(country, ''), coalesce(country_name(country, $2), ''),
+		coalesce(cityid, 0), coalesce(city_name(cityid, $2), ''), role, totp_enabled
+	from users where id = $1
+	`, id, locale.Language(ctx)).Scan(&u.ID, &u.Handle, &u.Email, &u.Name, &u.Surname, &u.Avatar, &u.Sex, &dateOfBirth,
+		&u.Country, &u.CountryName, &u.CityID, &u.City, &u.Role, &u.TOTPEnabled)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.User{}, entity.ErrUserNotFound
+		}
+		return entity.User{}, err
+	}
+
+	if dateOfBirth != nil {
+		u.DateOfBirth = *dateOfBirth
+	}
+
+	return u, nil
+}
+
+// GetPublicProfile профиль пользователя id глазами viewerid. Статистика и лучшие фильмы
+// отдаются, только если смотрящему видны оценки. При блокировке в любую сторону
+// профиль для смотрящего не существует, как и у аккаунта, ждущего удаления (кроме его владельца).
+func (p *PgxUserRepo) GetPublicProfile(ctx context.Context, viewerid, id int64) (entity.Profile, error) {
+	var pr entity.Profile
+	var ratingsVisible bool
+
+	err := scanUser(p.pool.QueryRow(ctx, `
+	select `+userColumns("$1", "$3")+`,
+		can_see(u.ratings_visibility, u.id, $1),
+		(select count(*) from ratings where userid = u.id),
+		(select coalesce(avg(rating), 0)::float8 from ratings where userid = u.id),
+		(select count(*) from follows where followeeid = u.id),
+		(select count(*) from follows where followerid = u.id)
+	from users u where u.id = $2 and not blocked_between(u.id, $1) and (`+notDeleted+` or u.id = $1)
+	`, viewerid, id, locale.Language(ctx)), &pr.Users,
+		&ratingsVisible,
+		&pr.RatingsCount,
+		&pr.AverageRating,
+		&pr.Followers,
+		&pr.Following,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.Profile{}, entity.ErrUserNotFound
+		}
+		return entity.Profile{}, err
+	}
+
+	if !ratingsVisible {
+		pr.RatingsHidden, pr.RatingsCount, pr.AverageRating = true, 0, 0
+		return pr, nil
+	}
+
+	rows, err := p.pool.Query(ctx, `
+	select m.id, m.title, m.year, coalesce(m.poster, ''), r.rating
+	from ratings r
+	JOIN movies m ON m.id = r.movieid
+	where r.userid = $1
+	order by r.rating desc, m.id
+	limit $2
+	`, id, profileTopMovies)
+	if err != nil {
+		return entity.Profile{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m entity.MovieWithRating
+		err := rows.Scan(
+			&m.Movies.ID,
+			&m.Movies.Name,
+			&m.Movies.Year,
+			&m.Movies.Poster,
+			&m.Rating,
+		)
+		if err != nil {
+			return entity.Profile{}, err
+		}
+		pr.TopMovies = append(pr.TopMovies, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return entity.Profile{}, err
+	}
+
+	return pr, nil
+}
+
+// PatchUserInfo обновляет только переданные поля профиля.
+func (p *PgxUserRepo) PatchUserInfo(ctx context.Context, id int64, patch entity.UserPatch) error {
+	var q queryBuilder
+
+	var sets []string
+	set := func(column string, value any) {
+		sets = append(sets, q.bind(column+" = ?", value))
+	}
+	if patch.Name != nil {
+		set("name", *patch.Name)
+	}
+	if patch.Surname != nil {
+		set("surname", *patch.Surname)
+	}
+	if patch.Sex != nil {
+		set("sex", *patch.Sex)
+	}
+	if patch.DateOfBirth != nil {
+		set("dateofbirth", *patch.DateOfBirth)
+	}
+	if patch.Country != nil {
+		sets = append(sets, q.bind("country = nullif(?, '')", *patch.Country))
+		// Город из другой страны сбрасываем
+		if patch.CityID == nil {
+			sets = append(sets, q.bind("cityid = (select c.id from cities c where c.id = users.cityid and c.country = ?)", *patch.Country))
+		}
+	}
+	if patch.CityID != nil {
+		sets = append(sets, q.bind("cityid = nullif(?, 0::bigint)", *patch.CityID))
+	}
+
+	// Нечего обновлять, но пользователь должен существовать
+	if len(sets) == 0 {
+		_, err := p.GetUserInfo(ctx, id)
+		return err
+	}
+
+	q.where("id = ?", id)
+
+	result, err := p.pool.Exec(ctx, "update users set "+strings.Join(sets, ", ")+q.whereSQL(), q.args...)
+	if err != nil {
+		return locationError(err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
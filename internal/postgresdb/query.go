@@ -0,0 +1,42 @@
+package postgresdb
+
+import (
+	"strconv"
+	"strings"
+)
+
+// queryBuilder собирает условия where для динамических запросов. Значения всегда
+// уходят параметрами, в текст запроса попадают только фрагменты из кода.
+type queryBuilder struct {
+	conds []string
+	args  []any
+}
+
+// where добавляет условие, в котором каждый ? заменяется на следующий $n.
+func (q *queryBuilder) where(cond string, args ...any) {
+	q.conds = append(q.conds, q.bind(cond, args...))
+}
+
+// bind нумерует параметры во фрагменте без добавления его в where (для join, order by и т.п.).
+func (q *queryBuilder) bind(fragment string, args ...any) string {
+	var b strings.Builder
+	for _, arg := range args {
+		i := strings.IndexByte(fragment, '?')
+		if i < 0 {
+			break
+		}
+		q.args = append(q.args, arg)
+		b.WriteString(fragment[:i])
+		b.WriteString("$" + strconv.Itoa(len(q.args)))
+		fragment = fragment[i+1:]
+	}
+	b.WriteString(fragment)
+	return b.String()
+}
+
+func (q *queryBuilder) whereSQL() string {
+	if len(q.conds) == 0 {
+		return ""
+	}
+	return " where " + strings.Join(q.conds, " and ")
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/marcokz/movie-final/internal/entity"
+	"github.com/marcokz/movie-final/internal/locale"
 )
 
 type PgxRatingsRepo struct {
@@ -24,8 +25,16 @@ func NewRatingsRepo(p *pgxpool.Pool) *PgxRatingsRepo {
 
 // }
 
-func (p *PgxRatingsRepo) GetMoviesWithRatingFromUser(ctx context.Context, userid, minrating, maxrating int64) ([]entity.MovieWithRating, error) {
-	rows, err := p.pool.Query(ctx, "select movieid, rating from ratings where userid = $1 and rating BETWEEN $2 AND $3", userid, minrating, maxrating)
+// GetMoviesWithRatingFromUser оценки пользователя userid, если его оценки видны viewerid.
+func (p *PgxRatingsRepo) GetMoviesWithRatingFromUser(ctx context.Context, viewerid, userid, minrating, maxrating int64) ([]entity.MovieWithRating, error) {
+	rows, err := p.pool.Query(ctx, `
+	select r.rating, m.id, m.title, m.year
+	from ratings r
+	JOIN movies m ON m.id = r.movieid
+	JOIN users u ON u.id = r.userid
+	where r.userid = $2 AND r.rating BETWEEN $3 AND $4 AND can_see(u.ratings_visibility, u.id, $1)
+		AND `+notHidden("$1")+`
+	`, viewerid, userid, minrating, maxrating)
 	if err != nil {
 		return []entity.MovieWithRating{}, err
 	}
@@ -35,9 +44,8 @@ func (p *PgxRatingsRepo) GetMoviesWithRatingFromUser(ctx context.Context, userid
 
 	for rows.Next() {
 		var m entity.MovieWithRating
-		var rating int64
 		err := rows.Scan(
-			&rating,
+			&m.Rating,
 			&m.Movies.ID,
 			&m.Movies.Name,
 			&m.Movies.Year,
@@ -55,13 +63,14 @@ func (p *PgxRatingsRepo) GetMoviesWithRatingFromUser(ctx context.Context, userid
 	return movies, nil
 }
 
-func (p *PgxRatingsRepo) GetUsersByRatingOfMovie(ctx context.Context, movieid, minrating, maxrating int64) ([]entity.UserWithRating, error) {
+func (p *PgxRatingsRepo) GetUsersByRatingOfMovie(ctx context.Context, viewerid, movieid, minrating, maxrating int64) ([]entity.UserWithRating, error) {
 	rows, err := p.pool.Query(ctx, `
-	select u.id, u.name, u.surname, u.sex, u.dateofbirth, u.country, u.city, r.rating  
+	select `+userColumns("$1", "$5")+`, r.rating
 	from ratings r
 	JOIN users u ON r.userid = u.id
-	where r.movieid = $1 AND r.rating BETWEEN $2 AND $3
-	`, movieid, minrating, maxrating)
+	where r.movieid = $2 AND r.rating BETWEEN $3 AND $4 AND can_see(u.ratings_visibility, u.id, $1)
+		AND `+notHidden("$1")+`
+	`, viewerid, movieid, minrating, maxrating, locale.Language(ctx))
 	if err != nil {
 		return []entity.UserWithRating{}, err
 	}
@@ -72,17 +81,7 @@ func (p *PgxRatingsRepo) GetUsersByRatingOfMovie(ctx context.Context, movieid, m
 	for rows.Next() {
 		var u entity.User
 		var rating int64
-		err := rows.Scan(
-			&u.ID,
-			&u.Name,
-			&u.Surname,
-			&u.Sex,
-			&u.DateOfBirth,
-			&u.Country,
-			&u.City,
-			&rating,
-		)
-		if err != nil {
+		if err := scanUser(rows, &u, &rating); err != nil {
 			return []entity.UserWithRating{}, err
 		}
 		if u.ID != 0 {
@@ -0,0 +1,136 @@
+package postgresdb
+
+import (
+	"context"
+
+	"github.com/marcokz/movie-final/internal/entity"
+	"github.com/marcokz/movie-final/internal/locale"
+)
+
+// Схожесть вкусов: 1 - средняя разница оценок общих фильмов, нормированная на шкалу 1..10.
+// Считается только по пользователям, чьи оценки видны смотрящему.
+const similarityJoin = `
+	left join lateral (
+		select 1 - avg(abs(a.rating - b.rating)) / 9.0 as similarity
+		from ratings a
+		join ratings b on b.movieid = a.movieid
+		where a.userid = ? and b.userid = u.id and can_see(u.ratings_visibility, u.id, ?)
+	) s on true`
+
+func (p *PgxUserRepo) SearchUsers(ctx context.Context, f entity.UserFilter) ([]entity.UserSearchResult, int, error) {
+	var q queryBuilder
+	viewer := q.bind("?", f.Viewer)
+	lang := q.bind("?", locale.Language(ctx))
+	q.where(notHidden(viewer))
+
+	ratingSelect, ratingJoin := "null::int", ""
+	if f.MovieID != 0 {
+		ratingSelect = "r.rating"
+		ratingJoin = q.bind(" join ratings r on r.userid = u.id and r.movieid = ?", f.MovieID)
+		q.where(visibleTo("ratings", viewer))
+	}
+
+	similaritySelect, similarityJoinSQL := "null::float8", ""
+	if f.WithSimilarity {
+		similaritySelect = "s.similarity::float8"
+		similarityJoinSQL = q.bind(similarityJoin, f.Viewer, f.Viewer)
+		q.where("u.id <> ?", f.Viewer)
+	}
+
+	if f.MinAge != nil || f.MaxAge != nil {
+		q.where(visibleTo("dateofbirth", viewer))
+		q.where(ageOverlaps, f.MinAge, f.MaxAge)
+	}
+	if f.Sex != "" {
+		q.where(visibleTo("sex", viewer))
+		q.where("u.sex = ?", f.Sex)
+	}
+	if f.Country != "" {
+		q.where(visibleTo("country", viewer))
+		q.where("u.country = ?", f.Country)
+	}
+	if f.CityID != 0 {
+		q.where(visibleTo("city", viewer))
+		q.where("u.cityid = ?", f.CityID)
+	}
+	if f.MovieID != 0 {
+		q.where("r.rating BETWEEN ? AND ?", f.MinRating, f.MaxRating)
+	}
+	if f.MinSimilarity != nil {
+		q.where("s.similarity >= ?", *f.MinSimilarity)
+	}
+
+	// Расстояние считаем между городами, поэтому город должен быть виден смотрящему
+	distanceSelect, distanceJoin := "null::float8", ""
+	if f.Near != nil {
+		distanceJoin = " join cities c on c.id = u.cityid"
+		q.where(visibleTo("city", viewer))
+		q.where("u.id <> ?", f.Viewer)
+		distanceSelect = withinRadius(&q, *f.Near, f.RadiusKm)
+	}
+
+	// Допустимые поля сортировки. Пользовательский ввод в запрос напрямую не попадает.
+	// По возрасту сортируем по диапазонам, иначе порядок выдал бы точный возраст.
+	var order string
+	switch {
+	case f.Sort == "name":
+		order = "u.name"
+	case f.Sort == "age":
+		order = "case when " + visibleTo("dateofbirth", viewer) + " then lower(age_bucket_range(u.dateofbirth)) end"
+	case f.Sort == "rating" && f.MovieID != 0:
+		order = "r.rating"
+	case f.Sort == "similarity" && f.WithSimilarity:
+		order = "s.similarity"
+	case f.Sort == "distance" && f.Near != nil:
+		order = distanceSelect
+	default:
+		order = "u.id"
+	}
+	if f.Desc {
+		order += " desc nulls last"
+	} else {
+		order += " asc nulls last"
+	}
+
+	columns := userColumns(viewer, lang) + `, ` + ratingSelect + `, ` + similaritySelect + `, ` + distanceSelect
+	from := `
+	from users u` + ratingJoin + similarityJoinSQL + distanceJoin + q.whereSQL()
+	// Параметры без limit и offset, по ним же считается общее количество
+	countArgs := q.args[:len(q.args):len(q.args)]
+
+	sql := `
+	select ` + columns + `, count(*) over()` + from + `
+	order by ` + order + `, u.id` + q.bind(" limit ? offset ?", f.Limit, f.Offset)
+
+	rows, err := p.pool.Query(ctx, sql, q.args...)
+	if err != nil {
+		return []entity.UserSearchResult{}, 0, err
+	}
+	defer rows.Close()
+
+	var results []entity.UserSearchResult
+	var total int
+
+	for rows.Next() {
+		var res entity.UserSearchResult
+		err := scanUser(rows, &res.Users, &res.Rating, &res.Similarity, &res.DistanceKm, &total)
+		if err != nil {
+			return []entity.UserSearchResult{}, 0, err
+		}
+		results = append(results, res)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []entity.UserSearchResult{}, 0, err
+	}
+
+	// За концом выдачи строк нет, и count(*) over() вернуть некому
+	if len(results) == 0 && f.Offset > 0 {
+		err := p.pool.QueryRow(ctx, `select count(*) from (select `+columns+from+`) t`, countArgs...).Scan(&total)
+		if err != nil {
+			return []entity.UserSearchResult{}, 0, err
+		}
+	}
+
+	return results, total, nil
+}
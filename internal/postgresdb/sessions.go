@@ -0,0 +1,130 @@
+package postgresdb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/marcokz/movie-final/internal/entity"
+)
+
+// Как часто обновляем last_seen_at, чтобы не писать в базу на каждый запрос
+const lastSeenPrecision = time.Minute
+
+type PgxSessionsRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewSessionsRepo(p *pgxpool.Pool) *PgxSessionsRepo {
+	return &PgxSessionsRepo{pool: p}
+}
+
+func (p *PgxSessionsRepo) CreateSession(ctx context.Context, s entity.Session) (int64, error) {
+	var id int64
+
+	err := p.pool.QueryRow(ctx, "insert into sessions (userid, user_agent, ip) values ($1, $2, $3) returning id",
+		s.UserID, s.UserAgent, s.IP).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// TouchSession проверяет, что сессия не отозвана и пользователь не заблокирован,
+// и отмечает время последней активности.
+func (p *PgxSessionsRepo) TouchSession(ctx context.Context, id, userid int64) (bool, error) {
+	var lastSeen time.Time
+
+	err := p.pool.QueryRow(ctx, `
+	select s.last_seen_at
+	from sessions s
+	JOIN users u ON u.id = s.userid
+	where s.id = $1 and s.userid = $2 and s.revoked_at is null
+		and (u.status = 'active' or u.status_until <= now())
+	`, id, userid).Scan(&lastSeen)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if time.Since(lastSeen) > lastSeenPrecision {
+		_, err = p.pool.Exec(ctx, "update sessions set last_seen_at = now() where id = $1", id)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func (p *PgxSessionsRepo) GetActiveSessions(ctx context.Context, userid int64) ([]entity.Session, error) {
+	rows, err := p.pool.Query(ctx, `
+	select id, userid, user_agent, ip, created_at, last_seen_at
+	from sessions
+	where userid = $1 and revoked_at is null
+	order by last_seen_at desc
+	`, userid)
+	if err != nil {
+		return []entity.Session{}, err
+	}
+	defer rows.Close()
+
+	var sessions []entity.Session
+
+	for rows.Next() {
+		var s entity.Session
+		err := rows.Scan(
+			&s.ID,
+			&s.UserID,
+			&s.UserAgent,
+			&s.IP,
+			&s.CreatedAt,
+			&s.LastSeenAt,
+		)
+		if err != nil {
+			return []entity.Session{}, err
+		}
+		sessions = append(sessions, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []entity.Session{}, err
+	}
+
+	return sessions, nil
+}
+
+func (p *PgxSessionsRepo) RevokeSession(ctx context.Context, id int64) error {
+	_, err := p.pool.Exec(ctx, "update sessions set revoked_at = now() where id = $1 and revoked_at is null", id)
+	return err
+}
+
+// RevokeUserSession отзывает сессию, только если она принадлежит пользователю.
+func (p *PgxSessionsRepo) RevokeUserSession(ctx context.Context, id, userid int64) error {
+	result, err := p.pool.Exec(ctx, "update sessions set revoked_at = now() where id = $1 and userid = $2 and revoked_at is null", id, userid)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return entity.ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// RevokeOtherSessions отзывает все сессии пользователя, кроме текущей.
+func (p *PgxSessionsRepo) RevokeOtherSessions(ctx context.Context, userid, keepID int64) error {
+	_, err := p.pool.Exec(ctx, "update sessions set revoked_at = now() where userid = $1 and id <> $2 and revoked_at is null", userid, keepID)
+	return err
+}
+
+func (p *PgxSessionsRepo) RevokeAllSessions(ctx context.Context, userid int64) error {
+	_, err := p.pool.Exec(ctx, "update sessions set revoked_at = now() where userid = $1 and revoked_at is null", userid)
+	return err
+}
@@ -0,0 +1,175 @@
+package postgresdb
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/marcokz/movie-final/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// queryTracer спан на каждый запрос и каждую пачку (pgx.Batch) к базе. Имя спана
+// по соглашениям OpenTelemetry: операция и таблица из SQL, например "SELECT users".
+type queryTracer struct{}
+
+var dbSystem = attribute.String("db.system", "postgresql")
+
+func (queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	name, operation := spanName(data.SQL)
+	ctx, span := tracing.Tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+	if span.IsRecording() {
+		span.SetAttributes(
+			dbSystem,
+			attribute.String("db.operation.name", operation),
+			attribute.String("db.query.text", strings.Join(strings.Fields(data.SQL), " ")),
+		)
+	}
+	return ctx
+}
+
+func (queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.Int64("db.response.rows_affected", data.CommandTag.RowsAffected()))
+	endSpan(span, data.Err)
+}
+
+func (queryTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	ctx, span := tracing.Tracer().Start(ctx, "BATCH", trace.WithSpanKind(trace.SpanKindClient))
+	if span.IsRecording() {
+		span.SetAttributes(dbSystem, attribute.Int("db.operation.batch.size", data.Batch.Len()))
+	}
+	return ctx
+}
+
+// TraceBatchQuery запросы пачки уходят одним обменом, поэтому они события спана пачки, а не спаны
+func (queryTracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	name, _ := spanName(data.SQL)
+	attrs := []attribute.KeyValue{attribute.Int64("db.response.rows_affected", data.CommandTag.RowsAffected())}
+	if data.Err != nil {
+		attrs = append(attrs, attribute.String("error", data.Err.Error()))
+	}
+	span.AddEvent(name, trace.WithAttributes(attrs...))
+}
+
+func (queryTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	endSpan(trace.SpanFromContext(ctx), data.Err)
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// begin открывает транзакцию внутри спана name. Запросы транзакции нужно делать с
+// возвращённым ctx, тогда их спаны вложены в спан транзакции. Спан закрывается
+// на Commit или Rollback.
+func begin(ctx context.Context, pool *pgxpool.Pool, name string) (context.Context, pgx.Tx, error) {
+	ctx, span := tracing.Tracer().Start(ctx, name, trace.WithAttributes(dbSystem))
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		endSpan(span, err)
+		return ctx, nil, err
+	}
+
+	return ctx, &tracedTx{Tx: tx, span: span}, nil
+}
+
+type tracedTx struct {
+	pgx.Tx
+	span trace.Span
+}
+
+func (t *tracedTx) Commit(ctx context.Context) error {
+	err := t.Tx.Commit(ctx)
+	endSpan(t.span, err)
+	return err
+}
+
+// Rollback после Commit ничего не делает, спан к этому времени уже закрыт
+func (t *tracedTx) Rollback(ctx context.Context) error {
+	err := t.Tx.Rollback(ctx)
+	if errors.Is(err, pgx.ErrTxClosed) {
+		return err
+	}
+	t.span.SetAttributes(attribute.Bool("db.transaction.rolled_back", true))
+	endSpan(t.span, err)
+	return err
+}
+
+// spanName имя спана запроса и операция: первое слово SQL и таблица, к которой
+// относится запрос верхнего уровня. Без таблицы (WITH, select 1) только операция.
+func spanName(sql string) (name, operation string) {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "db", ""
+	}
+	operation = strings.ToUpper(fields[0])
+
+	if table := sqlTable(sql, operation); table != "" {
+		return operation + " " + table, operation
+	}
+	return operation, operation
+}
+
+// Слово, за которым в запросе верхнего уровня идёт таблица
+var tableKeyword = map[string]string{
+	"SELECT": "from",
+	"DELETE": "from",
+	"INSERT": "into",
+	"UPDATE": "update",
+}
+
+func sqlTable(sql, operation string) string {
+	keyword, ok := tableKeyword[operation]
+	if !ok {
+		return ""
+	}
+
+	depth := 0
+	found := false
+	var word strings.Builder
+	for i := 0; i <= len(sql); i++ {
+		var c byte = ' '
+		if i < len(sql) {
+			c = sql[i]
+		}
+		if c == '_' || c == '.' || c == '"' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' {
+			word.WriteByte(c)
+			continue
+		}
+
+		if word.Len() > 0 {
+			w := word.String()
+			word.Reset()
+			if found {
+				return w
+			}
+			found = depth == 0 && strings.EqualFold(w, keyword)
+		}
+
+		switch c {
+		case '(':
+			// Подзапрос вместо таблицы
+			if found {
+				return ""
+			}
+			depth++
+		case ')':
+			depth--
+		}
+	}
+	return ""
+}
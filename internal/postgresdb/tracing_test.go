@@ -0,0 +1,29 @@
+package postgresdb
+
+import "testing"
+
+func TestSpanName(t *testing.T) {
+	tests := []struct {
+		sql       string
+		name      string
+		operation string
+	}{
+		{"select id from users where id = $1", "SELECT users", "SELECT"},
+		{"\n\tselect u.id, (select count(*) from follows where followeeid = u.id)\n\tfrom users u", "SELECT users", "SELECT"},
+		{"SELECT m.id FROM movies m JOIN ratings r ON r.movieid = m.id", "SELECT movies", "SELECT"},
+		{"insert into sessions (userid, user_agent, ip) values ($1, $2, $3) returning id", "INSERT sessions", "INSERT"},
+		{"update users set handle = $2 where id = $1", "UPDATE users", "UPDATE"},
+		{"delete from oidc_logins where expires_at < now()", "DELETE oidc_logins", "DELETE"},
+		{"select count(*) from (select 1 from users) t", "SELECT", "SELECT"},
+		{"select blocked_between($1, $2)", "SELECT", "SELECT"},
+		{"with t as (select 1) select * from t", "WITH", "WITH"},
+		{"begin", "BEGIN", "BEGIN"},
+		{"  ", "db", ""},
+	}
+	for _, tt := range tests {
+		name, operation := spanName(tt.sql)
+		if name != tt.name || operation != tt.operation {
+			t.Errorf("spanName(%q) = %q, %q, want %q, %q", tt.sql, name, operation, tt.name, tt.operation)
+		}
+	}
+}
@@ -5,12 +5,19 @@ import (
 	"errors"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/marcokz/movie-final/internal/entity"
+	"github.com/marcokz/movie-final/internal/locale"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+func isEmailConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && pgErr.ConstraintName == "users_email_key"
+}
+
 type PgxUserRepo struct {
 	pool *pgxpool.Pool
 }
@@ -19,15 +26,42 @@ func NewUserRepo(p *pgxpool.Pool) *PgxUserRepo {
 	return &PgxUserRepo{pool: p}
 }
 
-func (p *PgxUserRepo) CreateUser(ctx context.Context, email, password string) error {
+// CreateUser handle уже проверен entity.NormalizeHandle, пустой означает без ника.
+func (p *PgxUserRepo) CreateUser(ctx context.Context, email, password, handle string) error {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return err
 	}
 
-	_, err = p.pool.Exec(ctx, "insert into users (email, password) values ($1, $2)", email, hashedPassword)
+	ctx, tx, err := begin(ctx, p.pool, "PgxUserRepo.CreateUser")
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if handle != "" {
+		held, err := handleHeld(ctx, tx, handle, 0)
+		if err != nil {
+			return err
+		}
+		if held {
+			return entity.ErrHandleTaken
+		}
+	}
+
+	_, err = tx.Exec(ctx, "insert into users (email, password, handle) values ($1, $2, nullif($3, ''))", email, hashedPassword, handle)
 	if err != nil {
-		return errors.New("the user already exists")
+		if isHandleConflict(err) {
+			return entity.ErrHandleTaken
+		}
+		if isEmailConflict(err) {
+			return entity.ErrEmailTaken
+		}
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
 	}
 
 	return nil
@@ -36,8 +70,13 @@ func (p *PgxUserRepo) CreateUser(ctx context.Context, email, password string) er
 func (p *PgxUserRepo) GetUserByEmail(ctx context.Context, email string) (entity.User, error) {
 	var u entity.User
 
-	err := p.pool.QueryRow(ctx, "select id, email, password from users where email = $1", email).
-		Scan(&u.ID, &u.Email, &u.Password)
+	err := p.pool.QueryRow(ctx, `
+	select id, email, password, role, coalesce(totp_secret, ''), totp_enabled,
+		status, coalesce(status_reason, ''), status_until, password_reset_required
+	from users where email = $1
+	`, email).
+		Scan(&u.ID, &u.Email, &u.Password, &u.Role, &u.TOTPSecret, &u.TOTPEnabled,
+			&u.Status, &u.StatusReason, &u.StatusUntil, &u.PasswordResetRequired)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return entity.User{}, entity.ErrUserNotFound
@@ -48,9 +87,53 @@ func (p *PgxUserRepo) GetUserByEmail(ctx context.Context, email string) (entity.
 	return u, nil
 }
 
-func (p *PgxUserRepo) GetUserByAge(ctx context.Context, minAge, maxAge int64) ([]entity.User, error) {
-	rows, err := p.pool.Query(ctx, "select id, name, surname, sex, dateofbirth, country, city from users where EXTRACT(YEAR FROM AGE(dateofbirth)) BETWEEN $1 AND $2",
-		minAge, maxAge)
+func (p *PgxUserRepo) GetUserByAge(ctx context.Context, viewerid, minAge, maxAge int64) ([]entity.User, error) {
+	var q queryBuilder
+	viewer := q.bind("?", viewerid)
+	lang := q.bind("?", locale.Language(ctx))
+	q.where(notHidden(viewer))
+	q.where(visibleTo("dateofbirth", viewer))
+	q.where(ageOverlaps, minAge, maxAge)
+
+	return p.queryUsers(ctx, "select "+userColumns(viewer, lang)+" from users u"+q.whereSQL(), q.args...)
+}
+
+// GetUserByCountry country код ISO 3166-1 alpha-2.
+func (p *PgxUserRepo) GetUserByCountry(ctx context.Context, viewerid int64, country string) ([]entity.User, error) {
+	var q queryBuilder
+	viewer := q.bind("?", viewerid)
+	lang := q.bind("?", locale.Language(ctx))
+	q.where(notHidden(viewer))
+	q.where(visibleTo("country", viewer))
+	q.where("u.country = ?", country)
+
+	return p.queryUsers(ctx, "select "+userColumns(viewer, lang)+" from users u"+q.whereSQL(), q.args...)
+}
+
+func (p *PgxUserRepo) GetUserByCity(ctx context.Context, viewerid, cityid int64) ([]entity.User, error) {
+	var q queryBuilder
+	viewer := q.bind("?", viewerid)
+	lang := q.bind("?", locale.Language(ctx))
+	q.where(notHidden(viewer))
+	q.where(visibleTo("city", viewer))
+	q.where("u.cityid = ?", cityid)
+
+	return p.queryUsers(ctx, "select "+userColumns(viewer, lang)+" from users u"+q.whereSQL(), q.args...)
+}
+
+func (p *PgxUserRepo) GetUserBySex(ctx context.Context, viewerid int64, sex string) ([]entity.User, error) {
+	var q queryBuilder
+	viewer := q.bind("?", viewerid)
+	lang := q.bind("?", locale.Language(ctx))
+	q.where(notHidden(viewer))
+	q.where(visibleTo("sex", viewer))
+	q.where("u.sex = ?", sex)
+
+	return p.queryUsers(ctx, "select "+userColumns(viewer, lang)+" from users u"+q.whereSQL(), q.args...)
+}
+
+func (p *PgxUserRepo) queryUsers(ctx context.Context, sql string, args ...any) ([]entity.User, error) {
+	rows, err := p.pool.Query(ctx, sql, args...)
 	if err != nil {
 		return []entity.User{}, err
 	}
@@ -60,16 +143,7 @@ func (p *PgxUserRepo) GetUserByAge(ctx context.Context, minAge, maxAge int64) ([
 
 	for rows.Next() {
 		var u entity.User
-		err := rows.Scan(
-			&u.ID,
-			&u.Name,
-			&u.Surname,
-			&u.Sex,
-			&u.DateOfBirth,
-			&u.Country,
-			&u.City,
-		)
-		if err != nil {
+		if err := scanUser(rows, &u); err != nil {
 			return []entity.User{}, err
 		}
 		users = append(users, u)
@@ -78,118 +152,248 @@ func (p *PgxUserRepo) GetUserByAge(ctx context.Context, minAge, maxAge int64) ([
 	if err := rows.Err(); err != nil {
 		return []entity.User{}, err
 	}
+
 	return users, nil
 }
 
-func (p *PgxUserRepo) GetUserByCountry(ctx context.Context, country string) ([]entity.User, error) {
-	rows, err := p.pool.Query(ctx, "select id, name, surname, sex, dateofbirth, country, city from users where country = $1", country)
+func (p *PgxUserRepo) UpdateUserInfo(ctx context.Context, u entity.User) error {
+	result, err := p.pool.Exec(ctx, `
+	update users set name = $2, surname = $3, sex = $4, dateofbirth = $5,
+		country = nullif($6, ''), cityid = nullif($7, 0::bigint)
+	where id = $1
+	`, u.ID, u.Name, u.Surname, u.Sex, u.DateOfBirth, u.Country, u.CityID)
 	if err != nil {
-		return []entity.User{}, err
+		return locationError(err)
 	}
-	defer rows.Close()
 
-	var users []entity.User
+	if result.RowsAffected() == 0 {
+		return entity.ErrUserNotFound
+	}
 
-	for rows.Next() {
-		var u entity.User
-		err := rows.Scan(
-			&u.ID,
-			&u.Name,
-			&u.Surname,
-			&u.Sex,
-			&u.DateOfBirth,
-			&u.Country,
-			&u.City,
-		)
-		if err != nil {
-			return []entity.User{}, err
+	return nil
+}
+
+func (p *PgxUserRepo) GetUserByID(ctx context.Context, id int64) (entity.User, error) {
+	var u entity.User
+
+	err := p.pool.QueryRow(ctx, `
+	select id, email, password, role, coalesce(totp_secret, ''), totp_enabled,
+		status, coalesce(status_reason, ''), status_until, password_reset_required
+	from users where id = $1
+	`, id).
+		Scan(&u.ID, &u.Email, &u.Password, &u.Role, &u.TOTPSecret, &u.TOTPEnabled,
+			&u.Status, &u.StatusReason, &u.StatusUntil, &u.PasswordResetRequired)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.User{}, entity.ErrUserNotFound
 		}
-		users = append(users, u)
+		return entity.User{}, err
 	}
 
-	if err := rows.Err(); err != nil {
-		return []entity.User{}, err
+	return u, nil
+}
+
+func (p *PgxUserRepo) UpdatePassword(ctx context.Context, id int64, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
 	}
 
-	return users, nil
+	result, err := p.pool.Exec(ctx, "update users set password = $2 where id = $1", id, hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
 }
 
-func (p *PgxUserRepo) GetUserByCity(ctx context.Context, city string) ([]entity.User, error) {
-	rows, err := p.pool.Query(ctx, "select id, name, surname, sex, dateofbirth, country, city from users where city = $1", city)
+// CreateEmailChange сохраняет запрос на смену email. Предыдущий неподтверждённый запрос перезаписывается.
+func (p *PgxUserRepo) CreateEmailChange(ctx context.Context, c entity.EmailChange) error {
+	var exists bool
+	err := p.pool.QueryRow(ctx, "select exists(select 1 from users where email = $1)", c.NewEmail).Scan(&exists)
 	if err != nil {
-		return []entity.User{}, err
+		return err
+	}
+	if exists {
+		return entity.ErrEmailTaken
 	}
-	defer rows.Close()
 
-	var users []entity.User
+	_, err = p.pool.Exec(ctx, `
+	insert into email_changes (userid, new_email, token_hash, expires_at) values ($1, $2, $3, $4)
+	ON CONFLICT (userid) DO UPDATE SET new_email = EXCLUDED.new_email, token_hash = EXCLUDED.token_hash, expires_at = EXCLUDED.expires_at
+	`, c.UserID, c.NewEmail, c.TokenHash, c.ExpiresAt)
+	if err != nil {
+		return err
+	}
 
-	for rows.Next() {
-		var u entity.User
-		err := rows.Scan(
-			&u.ID,
-			&u.Name,
-			&u.Surname,
-			&u.Sex,
-			&u.DateOfBirth,
-			&u.Country,
-			&u.City,
-		)
-		if err != nil {
-			return []entity.User{}, err
+	return nil
+}
+
+func (p *PgxUserRepo) ConfirmEmailChange(ctx context.Context, userid int64, tokenHash string) (string, error) {
+	ctx, tx, err := begin(ctx, p.pool, "PgxUserRepo.ConfirmEmailChange")
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	var email string
+	err = tx.QueryRow(ctx, "delete from email_changes where userid = $1 and token_hash = $2 and expires_at > now() returning new_email", userid, tokenHash).
+		Scan(&email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", entity.ErrInvalidToken
 		}
-		users = append(users, u)
+		return "", err
 	}
 
-	if err := rows.Err(); err != nil {
-		return []entity.User{}, err
+	_, err = tx.Exec(ctx, "update users set email = $2 where id = $1", userid, email)
+	if err != nil {
+		if isEmailConflict(err) {
+			return "", entity.ErrEmailTaken
+		}
+		return "", err
 	}
 
-	return users, nil
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+
+	return email, nil
 }
 
-func (p *PgxUserRepo) GetUserBySex(ctx context.Context, sex string) ([]entity.User, error) {
-	rows, err := p.pool.Query(ctx, "select id, name, surname, sex, dateofbirth, country, city from users where sex = $1", sex)
+// SetTOTPSecret сохраняет секрет до подтверждения кодом, сам 2FA пока не включён.
+func (p *PgxUserRepo) SetTOTPSecret(ctx context.Context, id int64, secret string) error {
+	result, err := p.pool.Exec(ctx, "update users set totp_secret = $2 where id = $1 and totp_enabled = false", id, secret)
 	if err != nil {
-		return []entity.User{}, err
+		return err
 	}
-	defer rows.Close()
 
-	var users []entity.User
+	if result.RowsAffected() == 0 {
+		return entity.ErrTOTPAlreadyEnabled
+	}
 
-	for rows.Next() {
-		var u entity.User
-		err := rows.Scan(
-			&u.ID,
-			&u.Name,
-			&u.Surname,
-			&u.Sex,
-			&u.DateOfBirth,
-			&u.Country,
-			&u.City,
-		)
+	return nil
+}
+
+func (p *PgxUserRepo) EnableTOTP(ctx context.Context, id int64, recoveryHashes []string) error {
+	ctx, tx, err := begin(ctx, p.pool, "PgxUserRepo.EnableTOTP")
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, "update users set totp_enabled = true where id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, "delete from recovery_codes where userid = $1", id)
+	if err != nil {
+		return err
+	}
+
+	for _, h := range recoveryHashes {
+		_, err = tx.Exec(ctx, "insert into recovery_codes (userid, code_hash) values ($1, $2)", id, h)
 		if err != nil {
-			return []entity.User{}, err
+			return err
 		}
-		users = append(users, u)
 	}
 
-	if err := rows.Err(); err != nil {
-		return []entity.User{}, err
+	return tx.Commit(ctx)
+}
+
+func (p *PgxUserRepo) DisableTOTP(ctx context.Context, id int64) error {
+	ctx, tx, err := begin(ctx, p.pool, "PgxUserRepo.DisableTOTP")
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback(ctx)
 
-	return users, nil
-}
+	_, err = tx.Exec(ctx, "update users set totp_enabled = false, totp_secret = null, totp_last_step = 0 where id = $1", id)
+	if err != nil {
+		return err
+	}
 
-func (p *PgxUserRepo) UpdateUserInfo(ctx context.Context, u entity.User) error {
-	result, err := p.pool.Exec(ctx, "update users set name = $2, surname = $3, sex = $4, dateofbirth = $5, country = $6, city = $7 where id = $1",
-		u.ID, u.Name, u.Surname, u.Sex, u.DateOfBirth, u.Country, u.City)
+	_, err = tx.Exec(ctx, "delete from recovery_codes where userid = $1", id)
 	if err != nil {
 		return err
 	}
 
-	if result.RowsAffected() == 0 {
-		return entity.ErrUserNotFound
+	return tx.Commit(ctx)
+}
+
+// UseTOTPStep запоминает интервал использованного кода. Возвращает false, если код
+// из этого интервала (или более позднего) уже использовался.
+func (p *PgxUserRepo) UseTOTPStep(ctx context.Context, id, step int64) (bool, error) {
+	result, err := p.pool.Exec(ctx, "update users set totp_last_step = $2 where id = $1 and totp_last_step < $2", id, step)
+	if err != nil {
+		return false, err
 	}
 
-	return nil
+	return result.RowsAffected() == 1, nil
+}
+
+func (p *PgxUserRepo) UseRecoveryCode(ctx context.Context, id int64, codeHash string) (bool, error) {
+	result, err := p.pool.Exec(ctx, "update recovery_codes set used_at = now() where userid = $1 and code_hash = $2 and used_at is null", id, codeHash)
+	if err != nil {
+		return false, err
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// ResetPassword меняет пароль по одноразовому токену сброса и возвращает id пользователя.
+func (p *PgxUserRepo) ResetPassword(ctx context.Context, tokenHash, password string) (int64, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, tx, err := begin(ctx, p.pool, "PgxUserRepo.ResetPassword")
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var userid int64
+	err = tx.QueryRow(ctx, "delete from password_resets where token_hash = $1 and expires_at > now() returning userid", tokenHash).
+		Scan(&userid)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, entity.ErrInvalidToken
+		}
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, "update users set password = $2, password_reset_required = false where id = $1", userid, hashedPassword)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return userid, nil
+}
+
+// RenewPasswordReset выдаёт новый токен пользователю, от которого требуется сменить пароль.
+// Пока прежний токен действует, он не заменяется, чтобы запросами нельзя было засыпать
+// почту письмами. Возвращает true, если токен сохранён.
+func (p *PgxUserRepo) RenewPasswordReset(ctx context.Context, r entity.PasswordReset) (bool, error) {
+	result, err := p.pool.Exec(ctx, `
+	insert into password_resets (userid, token_hash, expires_at)
+	select id, $2, $3 from users where id = $1 and password_reset_required
+	ON CONFLICT (userid) DO UPDATE SET token_hash = EXCLUDED.token_hash, expires_at = EXCLUDED.expires_at
+	where password_resets.expires_at <= now()
+	`, r.UserID, r.TokenHash, r.ExpiresAt)
+	if err != nil {
+		return false, err
+	}
+
+	return result.RowsAffected() > 0, nil
 }
@@ -0,0 +1,35 @@
+package postgresdb
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestUniqueConflicts(t *testing.T) {
+	unique := func(constraint string) error {
+		return fmt.Errorf("insert: %w", &pgconn.PgError{Code: uniqueViolation, ConstraintName: constraint})
+	}
+	tests := []struct {
+		name   string
+		err    error
+		email  bool
+		handle bool
+	}{
+		{"email", unique("users_email_key"), true, false},
+		{"handle", unique("users_handle_key"), false, true},
+		{"other constraint", unique("movies_title_year_key"), false, false},
+		{"not null", &pgconn.PgError{Code: "23502", ConstraintName: "users_email_key"}, false, false},
+		{"not a pg error", errors.New("connection reset"), false, false},
+	}
+	for _, tt := range tests {
+		if got := isEmailConflict(tt.err); got != tt.email {
+			t.Errorf("%s: isEmailConflict = %v, want %v", tt.name, got, tt.email)
+		}
+		if got := isHandleConflict(tt.err); got != tt.handle {
+			t.Errorf("%s: isHandleConflict = %v, want %v", tt.name, got, tt.handle)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package problem
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/marcokz/movie-final/internal/auth"
+	"github.com/marcokz/movie-final/internal/entity"
+)
+
+// domainErrors ответы на доменные ошибки. Текст ошибки уходит в detail,
+// поэтому здесь только ошибки, текст которых можно показывать клиенту.
+var domainErrors = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{entity.ErrMovieNotFound, http.StatusNotFound, "movie_not_found"},
+	{entity.ErrUserNotFound, http.StatusNotFound, "user_not_found"},
+	{entity.ErrSessionNotFound, http.StatusNotFound, "session_not_found"},
+
+	{entity.ErrMovieExists, http.StatusConflict, "movie_exists"},
+	{entity.ErrEmailTaken, http.StatusConflict, "email_taken"},
+	{entity.ErrHandleTaken, http.StatusConflict, "handle_taken"},
+	{entity.ErrTOTPAlreadyEnabled, http.StatusConflict, "totp_already_enabled"},
+
+	{entity.ErrInvalidHandle, http.StatusBadRequest, "invalid_handle"},
+	{entity.ErrHandleReserved, http.StatusBadRequest, "handle_reserved"},
+	{entity.ErrUnknownCountry, http.StatusBadRequest, "unknown_country"},
+	{entity.ErrCityNotFound, http.StatusBadRequest, "unknown_city"},
+	{entity.ErrCityCountryMismatch, http.StatusBadRequest, "city_country_mismatch"},
+	{entity.ErrCannotFollowSelf, http.StatusBadRequest, "cannot_follow_self"},
+	{entity.ErrCannotBlockSelf, http.StatusBadRequest, "cannot_block_self"},
+	{entity.ErrInvalidToken, http.StatusBadRequest, "invalid_token"},
+	{auth.ErrPasswordTooShort, http.StatusBadRequest, "password_too_short"},
+	{auth.ErrPasswordTooLong, http.StatusBadRequest, "password_too_long"},
+	{auth.ErrPasswordBreached, http.StatusBadRequest, "password_breached"},
+
+	{entity.ErrBlocked, http.StatusForbidden, "blocked"},
+	{entity.ErrEmailNotVerified, http.StatusForbidden, "email_not_verified"},
+	{entity.ErrAccountSuspended, http.StatusForbidden, "account_suspended"},
+	{entity.ErrPasswordResetRequired, http.StatusForbidden, "password_reset_required"},
+
+	{entity.ErrImageTooLarge, http.StatusRequestEntityTooLarge, "image_too_large"},
+	{entity.ErrUnsupportedImage, http.StatusUnsupportedMediaType, "unsupported_image"},
+}
+
+func fromDomain(err error) *Problem {
+	for _, d := range domainErrors {
+		if errors.Is(err, d.err) {
+			return New(d.status, d.code, d.err.Error())
+		}
+	}
+	return nil
+}
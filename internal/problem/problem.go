@@ -0,0 +1,221 @@
+// Package problem ошибки API в формате RFC 7807 (application/problem+json).
+//
+// Каждая ошибка несёт стабильный машинный код (поле code), по которому клиент
+// решает, что делать, и необязательный список ошибок по полям запроса.
+// Доменные ошибки entity и auth переводятся в ответы по таблице в domain.go,
+// всё неизвестное пишется в лог и отдаётся как 500 без подробностей.
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"maps"
+	"net/http"
+
+	"github.com/marcokz/movie-final/internal/logging"
+	"github.com/marcokz/movie-final/internal/validate"
+)
+
+const ContentType = "application/problem+json"
+
+// Общие коды. Доменные коды (movie_not_found, handle_taken...) в domain.go.
+const (
+	CodeBadRequest       = "bad_request"
+	CodeInvalidBody      = "invalid_body"
+	CodeValidation       = "validation_failed"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeConflict         = "conflict"
+	CodeTooLarge         = "payload_too_large"
+	CodeTooManyRequests  = "too_many_requests"
+	CodeInternal         = "internal_error"
+	CodeUnavailable      = "service_unavailable"
+	CodeMethodNotAllowed = "method_not_allowed"
+)
+
+// typePrefix префикс поля type. URN ни на что не ссылается, но стабилен.
+const typePrefix = "urn:movie-final:problem:"
+
+// FieldError ошибка одного поля запроса
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Problem ответ с ошибкой. Реализует error, поэтому его можно возвращать
+// из вспомогательных функций и отдавать через Write.
+type Problem struct {
+	Status int
+	Code   string
+	Detail string
+	Errors []FieldError
+
+	// Дополнительные поля ответа, например retry_after
+	Extensions map[string]any
+
+	cause error
+}
+
+func New(status int, code, detail string) *Problem {
+	return &Problem{Status: status, Code: code, Detail: detail}
+}
+
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Code + ": " + p.Detail
+	}
+	return p.Code
+}
+
+func (p *Problem) Unwrap() error { return p.cause }
+
+// With добавляет поле в ответ
+func (p *Problem) With(key string, value any) *Problem {
+	c := *p
+	c.Extensions = maps.Clone(p.Extensions)
+	if c.Extensions == nil {
+		c.Extensions = make(map[string]any)
+	}
+	c.Extensions[key] = value
+	return &c
+}
+
+func BadRequest(detail string) *Problem {
+	return New(http.StatusBadRequest, CodeBadRequest, detail)
+}
+
+// InvalidBody тело запроса не разобралось как JSON нужной формы
+func InvalidBody(err error) *Problem {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return New(http.StatusRequestEntityTooLarge, CodeTooLarge, "request body is too large")
+	}
+	p := New(http.StatusBadRequest, CodeInvalidBody, "request body is not valid JSON")
+	p.cause = err
+	return p
+}
+
+// Validation ошибки в отдельных полях запроса
+func Validation(errs ...FieldError) *Problem {
+	p := New(http.StatusBadRequest, CodeValidation, "request has invalid fields")
+	p.Errors = errs
+	return p
+}
+
+// Invalid ошибка поля с кодом invalid
+func Invalid(field, message string) FieldError {
+	return FieldError{Field: field, Code: "invalid", Message: message}
+}
+
+// Required отсутствует обязательное поле
+func Required(field string) FieldError {
+	return FieldError{Field: field, Code: "required", Message: field + " is required"}
+}
+
+// InvalidField доменная ошибка проверки значения поля (пароль, ник...) как
+// ошибка этого поля. Неизвестные ошибки остаются внутренними.
+func InvalidField(field string, err error) *Problem {
+	d := fromDomain(err)
+	if d == nil || d.Status != http.StatusBadRequest {
+		return From(err)
+	}
+	return Validation(FieldError{Field: field, Code: d.Code, Message: d.Detail})
+}
+
+func Unauthorized(detail string) *Problem {
+	return New(http.StatusUnauthorized, CodeUnauthorized, detail)
+}
+
+func Forbidden(detail string) *Problem {
+	return New(http.StatusForbidden, CodeForbidden, detail)
+}
+
+func NotFound(detail string) *Problem {
+	return New(http.StatusNotFound, CodeNotFound, detail)
+}
+
+func Conflict(detail string) *Problem {
+	return New(http.StatusConflict, CodeConflict, detail)
+}
+
+// Unavailable 503: сервис останавливается или его зависимость недоступна
+func Unavailable(detail string) *Problem {
+	return New(http.StatusServiceUnavailable, CodeUnavailable, detail)
+}
+
+// Internal 500 с причиной для лога; клиенту причина не уходит
+func Internal(err error) *Problem {
+	p := New(http.StatusInternalServerError, CodeInternal, "")
+	p.cause = err
+	return p
+}
+
+// Unauthenticated 401 без пользователя в контексте запроса
+var Unauthenticated = Unauthorized("authentication required")
+
+// From переводит ошибку в Problem: сама Problem, доменная ошибка из таблицы
+// или внутренняя ошибка сервера
+func From(err error) *Problem {
+	var p *Problem
+	if errors.As(err, &p) {
+		return p
+	}
+	if p := fromDomain(err); p != nil {
+		return p
+	}
+	var invalid validate.Errors
+	if errors.As(err, &invalid) {
+		fields := make([]FieldError, len(invalid))
+		for i, fe := range invalid {
+			fields[i] = FieldError{Field: fe.Field, Code: fe.Rule, Message: fe.Message}
+		}
+		return Validation(fields...)
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return InvalidBody(err)
+	}
+	return Internal(err)
+}
+
+// Write отвечает ошибкой err. Ошибки 5xx пишутся в лог вместе с request_id, кроме
+// 503 без причины: это ожидаемое состояние (остановка, проба готовности), а не сбой.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	p := From(err)
+
+	if p.Status >= 500 && (p.Status != http.StatusServiceUnavailable || p.cause != nil) {
+		logErr := p.cause
+		if logErr == nil {
+			logErr = err
+		}
+		slog.ErrorContext(r.Context(), "request failed", "method", r.Method, "path", r.URL.Path, "err", logErr)
+	}
+
+	// Стандартные поля важнее дополнительных с тем же именем
+	body := maps.Clone(p.Extensions)
+	if body == nil {
+		body = make(map[string]any)
+	}
+	body["type"] = typePrefix + p.Code
+	body["title"] = http.StatusText(p.Status)
+	body["status"] = p.Status
+	body["code"] = p.Code
+	if p.Detail != "" {
+		body["detail"] = p.Detail
+	}
+	if len(p.Errors) > 0 {
+		body["errors"] = p.Errors
+	}
+	body["instance"] = r.URL.Path
+	if id := logging.RequestID(r.Context()); id != "" {
+		body["request_id"] = id
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Del("Content-Length")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(body)
+}
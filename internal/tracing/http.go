@@ -0,0 +1,53 @@
+package tracing
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/marcokz/movie-final/internal/logging"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Instrument открывает серверный спан на каждый запрос к обработчику h,
+// зарегистрированному на шаблон pattern. Родитель берётся из traceparent.
+func Instrument(pattern string, h http.HandlerFunc) http.HandlerFunc {
+	_, route, ok := strings.Cut(pattern, " ")
+	if !ok {
+		route = pattern
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := Tracer().Start(ctx, pattern, trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+
+		if !span.IsRecording() {
+			h(w, r.WithContext(ctx))
+			return
+		}
+
+		span.SetAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("http.route", route),
+			attribute.String("url.path", r.URL.Path),
+		)
+
+		h(w, r.WithContext(ctx))
+
+		if sw, ok := w.(interface{ Status() int }); ok {
+			status := sw.Status()
+			span.SetAttributes(attribute.Int("http.response.status_code", status))
+			if status >= 500 {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		}
+		// Пользователь известен только после авторизации внутри h
+		if id := logging.UserID(ctx); id != 0 {
+			span.SetAttributes(attribute.Int64("enduser.id", id))
+		}
+	}
+}
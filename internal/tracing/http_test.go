@@ -0,0 +1,123 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// statusWriter как у access log: обработчик узнаёт код ответа через Status()
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Status() int { return w.status }
+
+// record ставит провайдер, который не записывает новые трассы, но продолжает
+// трассы с флагом sampled из traceparent
+func record(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(recorder),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(0))),
+	)
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	return recorder
+}
+
+func serve(h http.HandlerFunc, traceparent string) {
+	r := httptest.NewRequest(http.MethodGet, "/movies/7", nil)
+	if traceparent != "" {
+		r.Header.Set("traceparent", traceparent)
+	}
+	h(&statusWriter{ResponseWriter: httptest.NewRecorder()}, r)
+}
+
+func TestInstrumentContinuesSampledTrace(t *testing.T) {
+	recorder := record(t)
+	h := Instrument("GET /movies/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	serve(h, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("recorded %d spans, want 1", len(spans))
+	}
+	s := spans[0]
+	if s.Name() != "GET /movies/{id}" {
+		t.Errorf("name = %q", s.Name())
+	}
+	if got := s.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace id = %s, want the one from traceparent", got)
+	}
+	if got := s.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("parent span id = %s", got)
+	}
+	if s.Status().Code != codes.Error {
+		t.Errorf("status = %v, want error for 500", s.Status().Code)
+	}
+
+	want := map[attribute.Key]attribute.Value{
+		"http.route":                attribute.StringValue("/movies/{id}"),
+		"url.path":                  attribute.StringValue("/movies/7"),
+		"http.response.status_code": attribute.IntValue(500),
+	}
+	for _, kv := range s.Attributes() {
+		if v, ok := want[kv.Key]; ok {
+			if v != kv.Value {
+				t.Errorf("%s = %v, want %v", kv.Key, kv.Value.Emit(), v.Emit())
+			}
+			delete(want, kv.Key)
+		}
+	}
+	for k := range want {
+		t.Errorf("attribute %s is missing", k)
+	}
+}
+
+func TestInstrumentSampling(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+	}{
+		{"new trace outside the sample", ""},
+		{"parent not sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"},
+		{"malformed traceparent", "00-zz-00f067aa0ba902b7-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record(t)
+			called := false
+			serve(Instrument("GET /movies/{id}", func(w http.ResponseWriter, r *http.Request) { called = true }), tt.traceparent)
+
+			if !called {
+				t.Fatal("handler was not called")
+			}
+			if n := len(recorder.Ended()); n != 0 {
+				t.Errorf("recorded %d spans, want none", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+// Package tracing трассировка на OpenTelemetry: провайдер с выгрузкой в stdout или
+// по OTLP/HTTP, контекст трассы по W3C Trace Context (заголовок traceparent) и спаны
+// HTTP-обработчиков. Пока Setup не вызван, глобальный провайдер otel ничего не пишет.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/marcokz/movie-final/internal/buildinfo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentation = "github.com/marcokz/movie-final"
+
+type Config struct {
+	Exporter    string // stdout или otlp
+	Endpoint    string // OTLP/HTTP коллектора
+	ServiceName string
+	// Доля новых трасс, которые записываются. Если родитель пришёл в traceparent,
+	// решение берётся у него, чтобы трасса не рвалась между сервисами.
+	SampleRatio float64
+}
+
+// Setup включает трассировку и возвращает функцию, которая выгружает оставшиеся
+// спаны и останавливает провайдер.
+func Setup(ctx context.Context, c Config, stdout io.Writer) (func(context.Context) error, error) {
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch c.Exporter {
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(stdout))
+	case "otlp":
+		exporter, err = otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(c.Endpoint))
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", c.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(c.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(
+			semconv.ServiceName(c.ServiceName),
+			semconv.ServiceVersion(buildinfo.Get().Version),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return provider.Shutdown, nil
+}
+
+// Tracer спаны сервиса. Берётся из глобального провайдера при каждом вызове,
+// поэтому работает и для кода, созданного до Setup.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
@@ -0,0 +1,319 @@
+// Package validate проверка входных структур по тегам validate:
+//
+//	type UpdateRating struct {
+//		MovieID int64 `json:"movieid" validate:"required,min=1"`
+//		Rating  int64 `json:"rating" validate:"min=1,max=10"`
+//	}
+//
+// Правила через запятую:
+//
+//	required      значение не нулевое (строка не пустая и не из одних пробелов, указатель не nil)
+//	omitempty     остальные правила не проверяются для нулевого значения
+//	min=N, max=N  для чисел значение, для строк длина в символах
+//	len=N         строка ровно из N символов, срез или map ровно из N элементов
+//	oneof=a b c   строка из перечисленных
+//	email         адрес вида user@example.com
+//	date          дата YYYY-MM-DD
+//	past          дата YYYY-MM-DD не позже сегодняшней (дата рождения)
+//	datetime      время RFC 3339
+//	gtefield=F    не меньше поля F той же структуры (например MaxRating и MinRating)
+//
+// Указатели проверяются по значению; nil пропускается всеми правилами, кроме required.
+// Имя поля в ошибке берётся из тега json.
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// FieldError нарушенное правило одного поля
+type FieldError struct {
+	Field   string
+	Rule    string
+	Message string
+}
+
+// Errors все ошибки структуры
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Struct проверяет структуру (или указатель на неё) и возвращает Errors
+// или nil. Неверные теги ошибка программиста, на них Struct паникует.
+func Struct(v any) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		panic("validate: not a struct: " + rv.Type().String())
+	}
+
+	var errs Errors
+	for _, f := range fieldsOf(rv.Type()) {
+		fv := rv.Field(f.index)
+		for fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				break
+			}
+			fv = fv.Elem()
+		}
+
+		for _, rule := range f.rules {
+			if msg := rule.check(rv, fv); msg != "" {
+				errs = append(errs, FieldError{Field: f.name, Rule: rule.name, Message: f.name + " " + msg})
+				break
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+type field struct {
+	index int
+	name  string
+	rules []rule
+}
+
+type rule struct {
+	name  string
+	check func(parent, v reflect.Value) string
+}
+
+var cache sync.Map // reflect.Type -> []field
+
+func fieldsOf(t reflect.Type) []field {
+	if fs, ok := cache.Load(t); ok {
+		return fs.([]field)
+	}
+
+	var fs []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("validate")
+		if !ok || tag == "-" {
+			continue
+		}
+		fs = append(fs, field{index: i, name: jsonName(sf), rules: parseRules(t, sf, tag)})
+	}
+
+	cache.Store(t, fs)
+	return fs
+}
+
+func jsonName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return sf.Name
+	}
+	return name
+}
+
+func parseRules(t reflect.Type, sf reflect.StructField, tag string) []rule {
+	var rules []rule
+	omitempty := false
+
+	for _, part := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+		var check func(parent, v reflect.Value) string
+
+		switch name {
+		case "required":
+			check = func(_, v reflect.Value) string {
+				if isZero(v) {
+					return "is required"
+				}
+				return ""
+			}
+		case "omitempty":
+			omitempty = true
+			continue
+		case "min", "max":
+			check = boundCheck(sf, name, arg)
+		case "len":
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 0 {
+				panic(fmt.Sprintf("validate: %s.%s: bad len=%s", t.Name(), sf.Name, arg))
+			}
+			check = func(_, v reflect.Value) string {
+				switch v.Kind() {
+				case reflect.String:
+					if utf8.RuneCountInString(v.String()) != n {
+						return "must be exactly " + arg + " characters"
+					}
+				case reflect.Slice, reflect.Array, reflect.Map:
+					if v.Len() != n {
+						return "must have exactly " + arg + " items"
+					}
+				}
+				return ""
+			}
+		case "oneof":
+			allowed := strings.Fields(arg)
+			msg := "must be one of " + strings.Join(allowed, ", ")
+			check = func(_, v reflect.Value) string {
+				if v.Kind() != reflect.String {
+					return ""
+				}
+				for _, a := range allowed {
+					if v.String() == a {
+						return ""
+					}
+				}
+				return msg
+			}
+		case "email":
+			check = stringCheck(func(s string) string {
+				if a, err := mail.ParseAddress(s); err != nil || a.Address != s {
+					return "must be a valid email address"
+				}
+				return ""
+			})
+		case "date":
+			check = stringCheck(func(s string) string {
+				if _, err := time.Parse(time.DateOnly, s); err != nil {
+					return "must be a date in YYYY-MM-DD format"
+				}
+				return ""
+			})
+		case "past":
+			// Неверный формат сообщает правило date
+			check = stringCheck(func(s string) string {
+				d, err := time.Parse(time.DateOnly, s)
+				if err != nil {
+					return ""
+				}
+				if d.After(today()) {
+					return "must not be in the future"
+				}
+				return ""
+			})
+		case "datetime":
+			check = stringCheck(func(s string) string {
+				if _, err := time.Parse(time.RFC3339, s); err != nil {
+					return "must be an RFC 3339 time"
+				}
+				return ""
+			})
+		case "gtefield":
+			other, ok := t.FieldByName(arg)
+			if !ok {
+				panic(fmt.Sprintf("validate: %s.%s: no field %s", t.Name(), sf.Name, arg))
+			}
+			msg := "must not be less than " + jsonName(other)
+			check = func(parent, v reflect.Value) string {
+				o := reflect.Indirect(parent.FieldByIndex(other.Index))
+				if !o.IsValid() || !v.CanInt() || !o.CanInt() {
+					return ""
+				}
+				if v.Int() < o.Int() {
+					return msg
+				}
+				return ""
+			}
+		default:
+			panic(fmt.Sprintf("validate: %s.%s: unknown rule %q", t.Name(), sf.Name, name))
+		}
+
+		rules = append(rules, rule{name: name, check: check})
+	}
+
+	// omitempty действует на все правила поля, где бы ни стоял в теге
+	if omitempty {
+		for i, r := range rules {
+			check := r.check
+			rules[i].check = func(parent, v reflect.Value) string {
+				if isZero(v) {
+					return ""
+				}
+				return check(parent, v)
+			}
+		}
+	}
+
+	return rules
+}
+
+func boundCheck(sf reflect.StructField, name, arg string) func(parent, v reflect.Value) string {
+	n, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		panic(fmt.Sprintf("validate: %s: bad %s=%s", sf.Name, name, arg))
+	}
+
+	return func(_, v reflect.Value) string {
+		if v.Kind() == reflect.Pointer {
+			return ""
+		}
+
+		var got float64
+		unit := ""
+		switch {
+		case v.Kind() == reflect.String:
+			got = float64(utf8.RuneCountInString(v.String()))
+			unit = " characters"
+		case v.CanInt():
+			got = float64(v.Int())
+		case v.CanUint():
+			got = float64(v.Uint())
+		case v.CanFloat():
+			got = v.Float()
+		default:
+			return ""
+		}
+
+		if name == "min" && got < n {
+			if unit != "" {
+				return "must be at least " + arg + unit
+			}
+			return "must be at least " + arg
+		}
+		if name == "max" && got > n {
+			if unit != "" {
+				return "must be at most " + arg + unit
+			}
+			return "must be at most " + arg
+		}
+		return ""
+	}
+}
+
+// stringCheck правило только для непустых строк: пустоту проверяет required
+func stringCheck(fn func(s string) string) func(parent, v reflect.Value) string {
+	return func(_, v reflect.Value) string {
+		if v.Kind() != reflect.String || v.String() == "" {
+			return ""
+		}
+		return fn(v.String())
+	}
+}
+
+// today начало сегодняшнего дня в UTC, как и даты, разобранные из YYYY-MM-DD.
+// Переменная, чтобы тесты могли подменить.
+var today = func() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	case reflect.String:
+		return strings.TrimSpace(v.String()) == ""
+	default:
+		return v.IsZero()
+	}
+}
@@ -0,0 +1,172 @@
+package validate
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// rules одна ошибка на поле: достаточно сравнить поле и правило
+func rules(t *testing.T, err error) map[string]string {
+	t.Helper()
+	if err == nil {
+		return map[string]string{}
+	}
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Struct() error %T, want Errors", err)
+	}
+	got := map[string]string{}
+	for _, fe := range errs {
+		got[fe.Field] = fe.Rule
+	}
+	return got
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func TestRules(t *testing.T) {
+	defer func(old func() time.Time) { today = old }(today)
+	today = func() time.Time { return time.Date(2025, 1, 25, 0, 0, 0, 0, time.UTC) }
+
+	type required struct {
+		S string  `json:"s" validate:"required"`
+		N int64   `json:"n" validate:"required"`
+		P *string `json:"p" validate:"required"`
+	}
+	type bounds struct {
+		N int64   `json:"n" validate:"min=1,max=10"`
+		F float64 `json:"f" validate:"min=0,max=1"`
+		S string  `json:"s" validate:"min=2,max=4"`
+		P *int64  `json:"p" validate:"min=1"`
+	}
+	type length struct {
+		S  string   `json:"s" validate:"len=2"`
+		L  []string `json:"l" validate:"len=1"`
+		OE string   `json:"oe" validate:"omitempty,len=2"`
+	}
+	type formats struct {
+		Email string `json:"email" validate:"email"`
+		Date  string `json:"date" validate:"date"`
+		Birth string `json:"birth" validate:"date,past"`
+		Time  string `json:"time" validate:"datetime"`
+		Enum  string `json:"enum" validate:"oneof=a b"`
+		Opt   string `json:"opt" validate:"omitempty,oneof=a b"`
+	}
+	type fields struct {
+		Min *int64 `json:"min"`
+		Max *int64 `json:"max" validate:"gtefield=Min"`
+	}
+	type untagged struct {
+		Name string
+		Skip string `validate:"-"`
+	}
+
+	tests := []struct {
+		name string
+		v    any
+		want map[string]string
+	}{
+		{"required ok", required{S: "x", N: 1, P: ptr("x")}, map[string]string{}},
+		{"required missing", required{S: "  "}, map[string]string{"s": "required", "n": "required", "p": "required"}},
+
+		{"bounds ok", bounds{N: 10, F: 1, S: "жжжж"}, map[string]string{}},
+		{"bounds below", bounds{N: 0, F: -0.1, S: "a", P: ptr(int64(0))}, map[string]string{"n": "min", "f": "min", "s": "min", "p": "min"}},
+		{"bounds above", bounds{N: 11, F: 1.5, S: "abcde"}, map[string]string{"n": "max", "f": "max", "s": "max"}},
+
+		{"len ok", length{S: "ru", L: []string{"x"}}, map[string]string{}},
+		{"len counts runes", length{S: "жж", L: []string{"x"}}, map[string]string{}},
+		{"len wrong", length{S: "rus", L: nil, OE: "x"}, map[string]string{"s": "len", "l": "len", "oe": "len"}},
+
+		{"formats ok", formats{Email: "a@example.com", Date: "2024-02-29", Birth: "2025-01-25", Time: "2025-01-25T10:00:00Z", Enum: "b"}, map[string]string{}},
+		{"formats empty skipped", formats{Enum: "a"}, map[string]string{}},
+		{"email", formats{Email: "Bob <bob@example.com>", Enum: "a"}, map[string]string{"email": "email"}},
+		{"email no at", formats{Email: "bob", Enum: "a"}, map[string]string{"email": "email"}},
+		{"date", formats{Date: "2023-02-29", Enum: "a"}, map[string]string{"date": "date"}},
+		{"date format", formats{Date: "25.01.2025", Enum: "a"}, map[string]string{"date": "date"}},
+		{"past future", formats{Birth: "2025-01-26", Enum: "a"}, map[string]string{"birth": "past"}},
+		{"past bad date reported by date", formats{Birth: "tomorrow", Enum: "a"}, map[string]string{"birth": "date"}},
+		{"datetime", formats{Time: "2025-01-25 10:00", Enum: "a"}, map[string]string{"time": "datetime"}},
+		{"oneof", formats{Enum: "c", Opt: "c"}, map[string]string{"enum": "oneof", "opt": "oneof"}},
+		{"oneof empty", formats{}, map[string]string{"enum": "oneof"}},
+
+		{"gtefield ok", fields{Min: ptr(int64(1)), Max: ptr(int64(1))}, map[string]string{}},
+		{"gtefield less", fields{Min: ptr(int64(2)), Max: ptr(int64(1))}, map[string]string{"max": "gtefield"}},
+		{"gtefield other nil", fields{Max: ptr(int64(1))}, map[string]string{}},
+
+		{"untagged", untagged{}, map[string]string{}},
+		{"pointer to struct", &required{S: "x", N: 1, P: ptr("x")}, map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rules(t, Struct(tt.v))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Struct() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldName(t *testing.T) {
+	type request struct {
+		Named  string `json:"named,omitempty" validate:"required"`
+		Plain  string `validate:"required"`
+		Hidden string `json:"-" validate:"required"`
+	}
+
+	err := Struct(request{})
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("Struct() = %v, want 3 errors", err)
+	}
+	for i, want := range []string{"named", "Plain", "Hidden"} {
+		if errs[i].Field != want {
+			t.Errorf("errs[%d].Field = %q, want %q", i, errs[i].Field, want)
+		}
+	}
+	if errs[0].Message != "named is required" {
+		t.Errorf("message = %q", errs[0].Message)
+	}
+}
+
+func TestFirstFailedRuleOnly(t *testing.T) {
+	type request struct {
+		Email string `json:"email" validate:"required,email,max=5"`
+	}
+	got := rules(t, Struct(request{Email: "not an email at all"}))
+	if want := map[string]string{"email": "email"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Struct() = %v, want %v", got, want)
+	}
+}
+
+func TestBadTagsPanic(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+	}{
+		{"unknown rule", struct {
+			S string `validate:"uppercase"`
+		}{}},
+		{"bad min", struct {
+			N int `validate:"min=x"`
+		}{}},
+		{"bad len", struct {
+			S string `validate:"len=-1"`
+		}{}},
+		{"no gtefield", struct {
+			N int `validate:"gtefield=Missing"`
+		}{}},
+		{"not a struct", 42},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Struct() did not panic")
+				}
+			}()
+			Struct(tt.v)
+		})
+	}
+}
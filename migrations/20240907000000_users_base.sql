@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Исходную таблицу пользователей создавали вручную до появления миграций,
+-- на таких базах миграция ничего не меняет
+CREATE TABLE IF NOT EXISTS users(
+    id SERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    password VARCHAR(255) NOT NULL,
+    login VARCHAR(50)
+);
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE users;
+-- +goose StatementEnd
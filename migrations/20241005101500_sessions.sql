@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE sessions(
+    id BIGSERIAL PRIMARY KEY,
+    userID INT NOT NULL references users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_at TIMESTAMPTZ
+);
+CREATE INDEX sessions_userid_idx ON sessions(userID);
+CREATE TABLE email_changes(
+    userID INT PRIMARY KEY references users(id),
+    new_email VARCHAR(255) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE email_changes;
+DROP TABLE sessions;
+-- +goose StatementEnd
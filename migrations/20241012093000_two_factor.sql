@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'user',
+    ADD COLUMN totp_secret VARCHAR(64),
+    ADD COLUMN totp_enabled BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN totp_last_step BIGINT NOT NULL DEFAULT 0;
+CREATE TABLE recovery_codes(
+    id SERIAL PRIMARY KEY,
+    userID INT NOT NULL references users(id),
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMPTZ
+);
+CREATE INDEX recovery_codes_userid_idx ON recovery_codes(userID);
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE recovery_codes;
+ALTER TABLE users DROP COLUMN role,
+    DROP COLUMN totp_secret,
+    DROP COLUMN totp_enabled,
+    DROP COLUMN totp_last_step;
+-- +goose StatementEnd
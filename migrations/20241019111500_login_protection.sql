@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE login_failures(
+    key VARCHAR(320) PRIMARY KEY,
+    failures INT NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    locked_until TIMESTAMPTZ
+);
+CREATE TABLE audit_events(
+    id BIGSERIAL PRIMARY KEY,
+    userID INT references users(id),
+    ip VARCHAR(45),
+    event VARCHAR(50) NOT NULL,
+    details TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX audit_events_userid_idx ON audit_events(userID);
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE audit_events;
+DROP TABLE login_failures;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE sessions
+ADD COLUMN user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    ADD COLUMN ip VARCHAR(45) NOT NULL DEFAULT '',
+    ADD COLUMN last_seen_at TIMESTAMPTZ NOT NULL DEFAULT now();
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE sessions DROP COLUMN user_agent,
+    DROP COLUMN ip,
+    DROP COLUMN last_seen_at;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE follows(
+    followerID INT NOT NULL references users(id),
+    followeeID INT NOT NULL references users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (followerID, followeeID),
+    CHECK (followerID <> followeeID)
+);
+CREATE INDEX follows_followeeid_idx ON follows(followeeID);
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE follows;
+-- +goose StatementEnd
@@ -0,0 +1,73 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+ADD COLUMN sex_visibility VARCHAR(10) NOT NULL DEFAULT 'public',
+    ADD COLUMN dateofbirth_visibility VARCHAR(10) NOT NULL DEFAULT 'public',
+    ADD COLUMN country_visibility VARCHAR(10) NOT NULL DEFAULT 'public',
+    ADD COLUMN city_visibility VARCHAR(10) NOT NULL DEFAULT 'public',
+    ADD COLUMN ratings_visibility VARCHAR(10) NOT NULL DEFAULT 'public',
+    ADD CONSTRAINT users_visibility_check CHECK (
+        sex_visibility IN ('public', 'followers', 'private')
+        AND dateofbirth_visibility IN ('public', 'followers', 'private')
+        AND country_visibility IN ('public', 'followers', 'private')
+        AND city_visibility IN ('public', 'followers', 'private')
+        AND ratings_visibility IN ('public', 'followers', 'private')
+    );
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE FUNCTION can_see(visibility VARCHAR, owner INT, viewer INT) RETURNS BOOLEAN AS $$
+SELECT owner = viewer
+    OR visibility = 'public'
+    OR (
+        visibility = 'followers'
+        AND EXISTS (
+            SELECT 1
+            FROM follows
+            WHERE followerID = viewer
+                AND followeeID = owner
+        )
+    );
+$$ LANGUAGE SQL STABLE;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE FUNCTION age_bucket_range(dateofbirth DATE) RETURNS int4range AS $$
+SELECT CASE
+        WHEN dateofbirth IS NULL THEN NULL
+        WHEN age < 18 THEN int4range(0, 18)
+        WHEN age < 25 THEN int4range(18, 25)
+        WHEN age < 35 THEN int4range(25, 35)
+        WHEN age < 45 THEN int4range(35, 45)
+        WHEN age < 55 THEN int4range(45, 55)
+        WHEN age < 65 THEN int4range(55, 65)
+        ELSE int4range(65, NULL)
+    END
+FROM (
+        SELECT EXTRACT(YEAR FROM AGE(dateofbirth))::INT AS age
+    ) a;
+$$ LANGUAGE SQL STABLE;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE FUNCTION age_bucket(dateofbirth DATE) RETURNS TEXT AS $$
+SELECT CASE
+        WHEN r IS NULL THEN NULL
+        WHEN upper(r) IS NULL THEN lower(r) || '+'
+        WHEN lower(r) = 0 THEN '<' || upper(r)
+        ELSE lower(r) || '-' || (upper(r) - 1)
+    END
+FROM (
+        SELECT age_bucket_range(dateofbirth) AS r
+    ) b;
+$$ LANGUAGE SQL STABLE;
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP FUNCTION age_bucket(DATE);
+DROP FUNCTION age_bucket_range(DATE);
+DROP FUNCTION can_see(VARCHAR, INT, INT);
+ALTER TABLE users DROP CONSTRAINT users_visibility_check,
+    DROP COLUMN sex_visibility,
+    DROP COLUMN dateofbirth_visibility,
+    DROP COLUMN country_visibility,
+    DROP COLUMN city_visibility,
+    DROP COLUMN ratings_visibility;
+-- +goose StatementEnd
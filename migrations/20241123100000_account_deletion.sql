@@ -0,0 +1,48 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+ADD COLUMN deletion_requested_at TIMESTAMPTZ;
+-- Оценки удалённых пользователей без привязки к личности, чтобы не терять агрегаты по фильмам
+CREATE TABLE anonymous_ratings(
+    id BIGSERIAL PRIMARY KEY,
+    movieID INT NOT NULL references movies(id),
+    rating INT check (
+        rating >= 1
+        and rating <= 10
+    )
+);
+CREATE INDEX anonymous_ratings_movieid_idx ON anonymous_ratings(movieID);
+ALTER TABLE sessions DROP CONSTRAINT sessions_userid_fkey,
+    ADD CONSTRAINT sessions_userid_fkey FOREIGN KEY (userID) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE email_changes DROP CONSTRAINT email_changes_userid_fkey,
+    ADD CONSTRAINT email_changes_userid_fkey FOREIGN KEY (userID) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE recovery_codes DROP CONSTRAINT recovery_codes_userid_fkey,
+    ADD CONSTRAINT recovery_codes_userid_fkey FOREIGN KEY (userID) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE user_identities DROP CONSTRAINT user_identities_userid_fkey,
+    ADD CONSTRAINT user_identities_userid_fkey FOREIGN KEY (userID) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE follows DROP CONSTRAINT follows_followerid_fkey,
+    ADD CONSTRAINT follows_followerid_fkey FOREIGN KEY (followerID) REFERENCES users(id) ON DELETE CASCADE,
+    DROP CONSTRAINT follows_followeeid_fkey,
+    ADD CONSTRAINT follows_followeeid_fkey FOREIGN KEY (followeeID) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE audit_events DROP CONSTRAINT audit_events_userid_fkey,
+    ADD CONSTRAINT audit_events_userid_fkey FOREIGN KEY (userID) REFERENCES users(id) ON DELETE SET NULL;
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE audit_events DROP CONSTRAINT audit_events_userid_fkey,
+    ADD CONSTRAINT audit_events_userid_fkey FOREIGN KEY (userID) REFERENCES users(id);
+ALTER TABLE follows DROP CONSTRAINT follows_followerid_fkey,
+    ADD CONSTRAINT follows_followerid_fkey FOREIGN KEY (followerID) REFERENCES users(id),
+    DROP CONSTRAINT follows_followeeid_fkey,
+    ADD CONSTRAINT follows_followeeid_fkey FOREIGN KEY (followeeID) REFERENCES users(id);
+ALTER TABLE user_identities DROP CONSTRAINT user_identities_userid_fkey,
+    ADD CONSTRAINT user_identities_userid_fkey FOREIGN KEY (userID) REFERENCES users(id);
+ALTER TABLE recovery_codes DROP CONSTRAINT recovery_codes_userid_fkey,
+    ADD CONSTRAINT recovery_codes_userid_fkey FOREIGN KEY (userID) REFERENCES users(id);
+ALTER TABLE email_changes DROP CONSTRAINT email_changes_userid_fkey,
+    ADD CONSTRAINT email_changes_userid_fkey FOREIGN KEY (userID) REFERENCES users(id);
+ALTER TABLE sessions DROP CONSTRAINT sessions_userid_fkey,
+    ADD CONSTRAINT sessions_userid_fkey FOREIGN KEY (userID) REFERENCES users(id);
+DROP TABLE anonymous_ratings;
+ALTER TABLE users DROP COLUMN deletion_requested_at;
+-- +goose StatementEnd
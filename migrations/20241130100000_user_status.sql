@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+ADD COLUMN status VARCHAR(10) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'suspended', 'banned')),
+    ADD COLUMN status_reason TEXT,
+    ADD COLUMN status_until TIMESTAMPTZ,
+    ADD COLUMN password_reset_required BOOLEAN NOT NULL DEFAULT false;
+CREATE TABLE password_resets(
+    userID INT PRIMARY KEY references users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE password_resets;
+ALTER TABLE users DROP COLUMN status,
+    DROP COLUMN status_reason,
+    DROP COLUMN status_until,
+    DROP COLUMN password_reset_required;
+-- +goose StatementEnd
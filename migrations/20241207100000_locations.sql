@@ -0,0 +1,467 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Страны по ISO 3166-1 alpha-2. Названия на английском, переводы в country_names.
+-- Справочник городов пустой, его заполняет cmd/gazetteer из выгрузки GeoNames.
+CREATE TABLE countries(
+    code CHAR(2) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL
+);
+CREATE TABLE country_names(
+    code CHAR(2) NOT NULL references countries(code) ON DELETE CASCADE,
+    lang VARCHAR(7) NOT NULL,
+    name VARCHAR(200) NOT NULL,
+    PRIMARY KEY (code, lang)
+);
+CREATE INDEX country_names_name_idx ON country_names(lower(name) text_pattern_ops);
+CREATE TABLE cities(
+    id BIGINT PRIMARY KEY,
+    -- geonameid
+    country CHAR(2) NOT NULL references countries(code),
+    name VARCHAR(200) NOT NULL,
+    ascii_name VARCHAR(200) NOT NULL,
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    population BIGINT NOT NULL DEFAULT 0
+);
+CREATE INDEX cities_country_idx ON cities(country);
+CREATE INDEX cities_name_idx ON cities(lower(name) text_pattern_ops);
+CREATE INDEX cities_ascii_name_idx ON cities(lower(ascii_name) text_pattern_ops);
+CREATE TABLE city_names(
+    cityID BIGINT NOT NULL references cities(id) ON DELETE CASCADE,
+    lang VARCHAR(7) NOT NULL,
+    name VARCHAR(200) NOT NULL,
+    PRIMARY KEY (cityID, lang)
+);
+CREATE INDEX city_names_name_idx ON city_names(lower(name) text_pattern_ops);
+INSERT INTO countries (code, name)
+VALUES ('AD', 'Andorra'),
+    ('AE', 'United Arab Emirates'),
+    ('AF', 'Afghanistan'),
+    ('AG', 'Antigua and Barbuda'),
+    ('AI', 'Anguilla'),
+    ('AL', 'Albania'),
+    ('AM', 'Armenia'),
+    ('AO', 'Angola'),
+    ('AQ', 'Antarctica'),
+    ('AR', 'Argentina'),
+    ('AS', 'American Samoa'),
+    ('AT', 'Austria'),
+    ('AU', 'Australia'),
+    ('AW', 'Aruba'),
+    ('AX', 'Aland Islands'),
+    ('AZ', 'Azerbaijan'),
+    ('BA', 'Bosnia and Herzegovina'),
+    ('BB', 'Barbados'),
+    ('BD', 'Bangladesh'),
+    ('BE', 'Belgium'),
+    ('BF', 'Burkina Faso'),
+    ('BG', 'Bulgaria'),
+    ('BH', 'Bahrain'),
+    ('BI', 'Burundi'),
+    ('BJ', 'Benin'),
+    ('BL', 'Saint Barthelemy'),
+    ('BM', 'Bermuda'),
+    ('BN', 'Brunei'),
+    ('BO', 'Bolivia'),
+    ('BQ', 'Bonaire, Saint Eustatius and Saba'),
+    ('BR', 'Brazil'),
+    ('BS', 'Bahamas'),
+    ('BT', 'Bhutan'),
+    ('BV', 'Bouvet Island'),
+    ('BW', 'Botswana'),
+    ('BY', 'Belarus'),
+    ('BZ', 'Belize'),
+    ('CA', 'Canada'),
+    ('CC', 'Cocos Islands'),
+    ('CD', 'Democratic Republic of the Congo'),
+    ('CF', 'Central African Republic'),
+    ('CG', 'Republic of the Congo'),
+    ('CH', 'Switzerland'),
+    ('CI', 'Ivory Coast'),
+    ('CK', 'Cook Islands'),
+    ('CL', 'Chile'),
+    ('CM', 'Cameroon'),
+    ('CN', 'China'),
+    ('CO', 'Colombia'),
+    ('CR', 'Costa Rica'),
+    ('CU', 'Cuba'),
+    ('CV', 'Cabo Verde'),
+    ('CW', 'Curacao'),
+    ('CX', 'Christmas Island'),
+    ('CY', 'Cyprus'),
+    ('CZ', 'Czechia'),
+    ('DE', 'Germany'),
+    ('DJ', 'Djibouti'),
+    ('DK', 'Denmark'),
+    ('DM', 'Dominica'),
+    ('DO', 'Dominican Republic'),
+    ('DZ', 'Algeria'),
+    ('EC', 'Ecuador'),
+    ('EE', 'Estonia'),
+    ('EG', 'Egypt'),
+    ('EH', 'Western Sahara'),
+    ('ER', 'Eritrea'),
+    ('ES', 'Spain'),
+    ('ET', 'Ethiopia'),
+    ('FI', 'Finland'),
+    ('FJ', 'Fiji'),
+    ('FK', 'Falkland Islands'),
+    ('FM', 'Micronesia'),
+    ('FO', 'Faroe Islands'),
+    ('FR', 'France'),
+    ('GA', 'Gabon'),
+    ('GB', 'United Kingdom'),
+    ('GD', 'Grenada'),
+    ('GE', 'Georgia'),
+    ('GF', 'French Guiana'),
+    ('GG', 'Guernsey'),
+    ('GH', 'Ghana'),
+    ('GI', 'Gibraltar'),
+    ('GL', 'Greenland'),
+    ('GM', 'Gambia'),
+    ('GN', 'Guinea'),
+    ('GP', 'Guadeloupe'),
+    ('GQ', 'Equatorial Guinea'),
+    ('GR', 'Greece'),
+    ('GS', 'South Georgia and the South Sandwich Islands'),
+    ('GT', 'Guatemala'),
+    ('GU', 'Guam'),
+    ('GW', 'Guinea-Bissau'),
+    ('GY', 'Guyana'),
+    ('HK', 'Hong Kong'),
+    ('HM', 'Heard Island and McDonald Islands'),
+    ('HN', 'Honduras'),
+    ('HR', 'Croatia'),
+    ('HT', 'Haiti'),
+    ('HU', 'Hungary'),
+    ('ID', 'Indonesia'),
+    ('IE', 'Ireland'),
+    ('IL', 'Israel'),
+    ('IM', 'Isle of Man'),
+    ('IN', 'India'),
+    ('IO', 'British Indian Ocean Territory'),
+    ('IQ', 'Iraq'),
+    ('IR', 'Iran'),
+    ('IS', 'Iceland'),
+    ('IT', 'Italy'),
+    ('JE', 'Jersey'),
+    ('JM', 'Jamaica'),
+    ('JO', 'Jordan'),
+    ('JP', 'Japan'),
+    ('KE', 'Kenya'),
+    ('KG', 'Kyrgyzstan'),
+    ('KH', 'Cambodia'),
+    ('KI', 'Kiribati'),
+    ('KM', 'Comoros'),
+    ('KN', 'Saint Kitts and Nevis'),
+    ('KP', 'North Korea'),
+    ('KR', 'South Korea'),
+    ('KW', 'Kuwait'),
+    ('KY', 'Cayman Islands'),
+    ('KZ', 'Kazakhstan'),
+    ('LA', 'Laos'),
+    ('LB', 'Lebanon'),
+    ('LC', 'Saint Lucia'),
+    ('LI', 'Liechtenstein'),
+    ('LK', 'Sri Lanka'),
+    ('LR', 'Liberia'),
+    ('LS', 'Lesotho'),
+    ('LT', 'Lithuania'),
+    ('LU', 'Luxembourg'),
+    ('LV', 'Latvia'),
+    ('LY', 'Libya'),
+    ('MA', 'Morocco'),
+    ('MC', 'Monaco'),
+    ('MD', 'Moldova'),
+    ('ME', 'Montenegro'),
+    ('MF', 'Saint Martin'),
+    ('MG', 'Madagascar'),
+    ('MH', 'Marshall Islands'),
+    ('MK', 'North Macedonia'),
+    ('ML', 'Mali'),
+    ('MM', 'Myanmar'),
+    ('MN', 'Mongolia'),
+    ('MO', 'Macao'),
+    ('MP', 'Northern Mariana Islands'),
+    ('MQ', 'Martinique'),
+    ('MR', 'Mauritania'),
+    ('MS', 'Montserrat'),
+    ('MT', 'Malta'),
+    ('MU', 'Mauritius'),
+    ('MV', 'Maldives'),
+    ('MW', 'Malawi'),
+    ('MX', 'Mexico'),
+    ('MY', 'Malaysia'),
+    ('MZ', 'Mozambique'),
+    ('NA', 'Namibia'),
+    ('NC', 'New Caledonia'),
+    ('NE', 'Niger'),
+    ('NF', 'Norfolk Island'),
+    ('NG', 'Nigeria'),
+    ('NI', 'Nicaragua'),
+    ('NL', 'The Netherlands'),
+    ('NO', 'Norway'),
+    ('NP', 'Nepal'),
+    ('NR', 'Nauru'),
+    ('NU', 'Niue'),
+    ('NZ', 'New Zealand'),
+    ('OM', 'Oman'),
+    ('PA', 'Panama'),
+    ('PE', 'Peru'),
+    ('PF', 'French Polynesia'),
+    ('PG', 'Papua New Guinea'),
+    ('PH', 'Philippines'),
+    ('PK', 'Pakistan'),
+    ('PL', 'Poland'),
+    ('PM', 'Saint Pierre and Miquelon'),
+    ('PN', 'Pitcairn'),
+    ('PR', 'Puerto Rico'),
+    ('PS', 'Palestinian Territory'),
+    ('PT', 'Portugal'),
+    ('PW', 'Palau'),
+    ('PY', 'Paraguay'),
+    ('QA', 'Qatar'),
+    ('RE', 'Reunion'),
+    ('RO', 'Romania'),
+    ('RS', 'Serbia'),
+    ('RU', 'Russia'),
+    ('RW', 'Rwanda'),
+    ('SA', 'Saudi Arabia'),
+    ('SB', 'Solomon Islands'),
+    ('SC', 'Seychelles'),
+    ('SD', 'Sudan'),
+    ('SE', 'Sweden'),
+    ('SG', 'Singapore'),
+    ('SH', 'Saint Helena'),
+    ('SI', 'Slovenia'),
+    ('SJ', 'Svalbard and Jan Mayen'),
+    ('SK', 'Slovakia'),
+    ('SL', 'Sierra Leone'),
+    ('SM', 'San Marino'),
+    ('SN', 'Senegal'),
+    ('SO', 'Somalia'),
+    ('SR', 'Suriname'),
+    ('SS', 'South Sudan'),
+    ('ST', 'Sao Tome and Principe'),
+    ('SV', 'El Salvador'),
+    ('SX', 'Sint Maarten'),
+    ('SY', 'Syria'),
+    ('SZ', 'Eswatini'),
+    ('TC', 'Turks and Caicos Islands'),
+    ('TD', 'Chad'),
+    ('TF', 'French Southern Territories'),
+    ('TG', 'Togo'),
+    ('TH', 'Thailand'),
+    ('TJ', 'Tajikistan'),
+    ('TK', 'Tokelau'),
+    ('TL', 'Timor Leste'),
+    ('TM', 'Turkmenistan'),
+    ('TN', 'Tunisia'),
+    ('TO', 'Tonga'),
+    ('TR', 'Turkey'),
+    ('TT', 'Trinidad and Tobago'),
+    ('TV', 'Tuvalu'),
+    ('TW', 'Taiwan'),
+    ('TZ', 'Tanzania'),
+    ('UA', 'Ukraine'),
+    ('UG', 'Uganda'),
+    ('UM', 'United States Minor Outlying Islands'),
+    ('US', 'United States'),
+    ('UY', 'Uruguay'),
+    ('UZ', 'Uzbekistan'),
+    ('VA', 'Vatican'),
+    ('VC', 'Saint Vincent and the Grenadines'),
+    ('VE', 'Venezuela'),
+    ('VG', 'British Virgin Islands'),
+    ('VI', 'U.S. Virgin Islands'),
+    ('VN', 'Vietnam'),
+    ('VU', 'Vanuatu'),
+    ('WF', 'Wallis and Futuna'),
+    ('WS', 'Samoa'),
+    ('XK', 'Kosovo'),
+    ('YE', 'Yemen'),
+    ('YT', 'Mayotte'),
+    ('ZA', 'South Africa'),
+    ('ZM', 'Zambia'),
+    ('ZW', 'Zimbabwe');
+-- Русские названия для стран, которые чаще всего встречаются у наших пользователей.
+-- Остальные переводы подгружает cmd/gazetteer из alternateNames.
+INSERT INTO country_names (code, lang, name)
+VALUES ('AM', 'ru', 'Армения'),
+    ('AT', 'ru', 'Австрия'),
+    ('AZ', 'ru', 'Азербайджан'),
+    ('BE', 'ru', 'Бельгия'),
+    ('BG', 'ru', 'Болгария'),
+    ('BY', 'ru', 'Беларусь'),
+    ('CA', 'ru', 'Канада'),
+    ('CH', 'ru', 'Швейцария'),
+    ('CN', 'ru', 'Китай'),
+    ('CY', 'ru', 'Кипр'),
+    ('CZ', 'ru', 'Чехия'),
+    ('DE', 'ru', 'Германия'),
+    ('DK', 'ru', 'Дания'),
+    ('EE', 'ru', 'Эстония'),
+    ('EG', 'ru', 'Египет'),
+    ('ES', 'ru', 'Испания'),
+    ('FI', 'ru', 'Финляндия'),
+    ('FR', 'ru', 'Франция'),
+    ('GB', 'ru', 'Великобритания'),
+    ('GE', 'ru', 'Грузия'),
+    ('GR', 'ru', 'Греция'),
+    ('HU', 'ru', 'Венгрия'),
+    ('IE', 'ru', 'Ирландия'),
+    ('IL', 'ru', 'Израиль'),
+    ('IN', 'ru', 'Индия'),
+    ('IT', 'ru', 'Италия'),
+    ('JP', 'ru', 'Япония'),
+    ('KG', 'ru', 'Киргизия'),
+    ('KR', 'ru', 'Южная Корея'),
+    ('KZ', 'ru', 'Казахстан'),
+    ('LT', 'ru', 'Литва'),
+    ('LV', 'ru', 'Латвия'),
+    ('MD', 'ru', 'Молдова'),
+    ('MN', 'ru', 'Монголия'),
+    ('NL', 'ru', 'Нидерланды'),
+    ('NO', 'ru', 'Норвегия'),
+    ('PL', 'ru', 'Польша'),
+    ('PT', 'ru', 'Португалия'),
+    ('RO', 'ru', 'Румыния'),
+    ('RS', 'ru', 'Сербия'),
+    ('RU', 'ru', 'Россия'),
+    ('SE', 'ru', 'Швеция'),
+    ('TJ', 'ru', 'Таджикистан'),
+    ('TM', 'ru', 'Туркменистан'),
+    ('TR', 'ru', 'Турция'),
+    ('UA', 'ru', 'Украина'),
+    ('AE', 'ru', 'Объединённые Арабские Эмираты'),
+    ('US', 'ru', 'США'),
+    ('UZ', 'ru', 'Узбекистан'),
+    ('KZ', 'kk', 'Қазақстан'),
+    ('RU', 'kk', 'Ресей'),
+    ('UZ', 'kk', 'Өзбекстан'),
+    ('KG', 'kk', 'Қырғызстан');
+-- +goose StatementEnd
+-- +goose StatementBegin
+-- Старые значения сохраняем как есть: страну сопоставляем сразу, город сопоставит
+-- cmd/gazetteer после загрузки справочника. Что не удалось сопоставить, остаётся в legacy_*.
+ALTER TABLE users
+ADD COLUMN legacy_country VARCHAR(50),
+    ADD COLUMN legacy_city VARCHAR(50),
+    ADD COLUMN country_code CHAR(2) CONSTRAINT users_country_fkey references countries(code),
+    ADD COLUMN cityID BIGINT CONSTRAINT users_cityid_fkey references cities(id) ON DELETE SET NULL;
+UPDATE users
+SET legacy_country = nullif(trim(country), ''),
+    legacy_city = nullif(trim(city), '');
+UPDATE users u
+SET country_code = m.code
+FROM (
+        SELECT code, lower(name) AS name FROM countries
+        UNION
+        SELECT code, lower(code) FROM countries
+        UNION
+        SELECT code, lower(name) FROM country_names
+        UNION
+        SELECT code, alias
+        FROM (
+                VALUES ('US', 'usa'),
+                    ('US', 'united states of america'),
+                    ('US', 'америка'),
+                    ('GB', 'uk'),
+                    ('GB', 'england'),
+                    ('GB', 'great britain'),
+                    ('GB', 'англия'),
+                    ('RU', 'russian federation'),
+                    ('RU', 'рф'),
+                    ('RU', 'российская федерация'),
+                    ('NL', 'netherlands'),
+                    ('NL', 'holland'),
+                    ('NL', 'голландия'),
+                    ('CZ', 'czech republic'),
+                    ('KG', 'kyrgyz republic'),
+                    ('KG', 'кыргызстан'),
+                    ('BY', 'белоруссия'),
+                    ('MD', 'молдавия'),
+                    ('AE', 'оаэ'),
+                    ('AE', 'uae')
+            ) a(code, alias)
+    ) m
+WHERE lower(trim(u.legacy_country)) = m.name;
+UPDATE users
+SET legacy_country = NULL
+WHERE country_code IS NOT NULL;
+ALTER TABLE users DROP COLUMN country,
+    DROP COLUMN city;
+ALTER TABLE users
+    RENAME COLUMN country_code TO country;
+CREATE INDEX users_country_idx ON users(country);
+CREATE INDEX users_cityid_idx ON users(cityID);
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE FUNCTION country_name(code CHAR(2), lang VARCHAR) RETURNS TEXT AS $$
+SELECT coalesce(
+        (
+            SELECT n.name
+            FROM country_names n
+            WHERE n.code = country_name.code
+                AND n.lang = country_name.lang
+        ),
+        (
+            SELECT c.name
+            FROM countries c
+            WHERE c.code = country_name.code
+        )
+    );
+$$ LANGUAGE SQL STABLE;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE FUNCTION city_name(id BIGINT, lang VARCHAR) RETURNS TEXT AS $$
+SELECT coalesce(
+        (
+            SELECT n.name
+            FROM city_names n
+            WHERE n.cityID = city_name.id
+                AND n.lang = city_name.lang
+        ),
+        (
+            SELECT c.name
+            FROM cities c
+            WHERE c.id = city_name.id
+        )
+    );
+$$ LANGUAGE SQL STABLE;
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP FUNCTION city_name(BIGINT, VARCHAR);
+DROP FUNCTION country_name(CHAR(2), VARCHAR);
+ALTER TABLE users
+    RENAME COLUMN country TO country_code;
+ALTER TABLE users
+ADD COLUMN country varchar(50),
+    ADD COLUMN city varchar(50);
+UPDATE users u
+SET country = coalesce(
+        u.legacy_country,
+        (
+            SELECT name
+            FROM countries
+            WHERE code = u.country_code
+        )
+    ),
+    city = coalesce(
+        u.legacy_city,
+        (
+            SELECT name
+            FROM cities
+            WHERE id = u.cityID
+        )
+    );
+ALTER TABLE users DROP COLUMN cityID,
+    DROP COLUMN country_code,
+    DROP COLUMN legacy_city,
+    DROP COLUMN legacy_country;
+DROP TABLE city_names;
+DROP TABLE cities;
+DROP TABLE country_names;
+DROP TABLE countries;
+-- +goose StatementEnd
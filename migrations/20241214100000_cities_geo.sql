@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Поиск пользователей рядом сначала отбирает города прямоугольником по координатам
+CREATE INDEX cities_latitude_idx ON cities(latitude, longitude);
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX cities_latitude_idx;
+-- +goose StatementEnd
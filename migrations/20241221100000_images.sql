@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Файлы лежат в blob хранилище по hash, здесь только сведения о них
+CREATE TABLE images(
+    hash CHAR(64) PRIMARY KEY,
+    content_type VARCHAR(50) NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    size BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+ALTER TABLE users
+ADD COLUMN avatar CHAR(64) references images(hash) ON DELETE SET NULL;
+ALTER TABLE movies
+ADD COLUMN poster CHAR(64) references images(hash) ON DELETE SET NULL;
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE movies DROP COLUMN poster;
+ALTER TABLE users DROP COLUMN avatar;
+DROP TABLE images;
+-- +goose StatementEnd
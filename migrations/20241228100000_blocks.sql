@@ -0,0 +1,48 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Блокировка взаимная: пользователи не видят друг друга и не могут подписаться.
+-- Заглушить можно только для себя: заглушённый пропадает из выдачи того, кто заглушил.
+CREATE TABLE blocks(
+    blockerID INT NOT NULL references users(id) ON DELETE CASCADE,
+    blockedID INT NOT NULL references users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (blockerID, blockedID),
+    CHECK (blockerID <> blockedID)
+);
+CREATE INDEX blocks_blockedid_idx ON blocks(blockedID);
+CREATE TABLE mutes(
+    muterID INT NOT NULL references users(id) ON DELETE CASCADE,
+    mutedID INT NOT NULL references users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (muterID, mutedID),
+    CHECK (muterID <> mutedID)
+);
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE FUNCTION blocked_between(a INT, b INT) RETURNS BOOLEAN AS $$
+SELECT EXISTS (
+        SELECT 1
+        FROM blocks
+        WHERE (blockerID = a AND blockedID = b)
+            OR (blockerID = b AND blockedID = a)
+    );
+$$ LANGUAGE SQL STABLE;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE FUNCTION hidden_from(owner INT, viewer INT) RETURNS BOOLEAN AS $$
+SELECT blocked_between(owner, viewer)
+    OR EXISTS (
+        SELECT 1
+        FROM mutes
+        WHERE muterID = viewer
+            AND mutedID = owner
+    );
+$$ LANGUAGE SQL STABLE;
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP FUNCTION hidden_from(INT, INT);
+DROP FUNCTION blocked_between(INT, INT);
+DROP TABLE mutes;
+DROP TABLE blocks;
+-- +goose StatementEnd
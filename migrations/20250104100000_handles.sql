@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Ник уникален без учёта регистра, хранится в том виде, в каком его выбрал пользователь
+ALTER TABLE users
+ADD COLUMN handle VARCHAR(30);
+CREATE UNIQUE INDEX users_handle_key ON users(lower(handle));
+-- Прежние ники остаются за пользователем: по ним работает редирект на текущий
+CREATE TABLE handle_history(
+    handle VARCHAR(30) NOT NULL,
+    userID INT NOT NULL references users(id) ON DELETE CASCADE,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX handle_history_handle_key ON handle_history(lower(handle));
+CREATE INDEX handle_history_userid_idx ON handle_history(userID);
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE handle_history;
+DROP INDEX users_handle_key;
+ALTER TABLE users DROP COLUMN handle;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+-- API хранит у фильма только год выпуска, полная дата необязательна
+ALTER TABLE movies
+ADD COLUMN year INT;
+UPDATE movies
+SET year = extract(
+        year
+        FROM release_date
+    );
+ALTER TABLE movies
+ALTER COLUMN year
+SET NOT NULL,
+    ALTER COLUMN release_date DROP NOT NULL;
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+UPDATE movies
+SET release_date = make_date(year, 1, 1)
+WHERE release_date IS NULL;
+ALTER TABLE movies
+ALTER COLUMN release_date
+SET NOT NULL,
+    DROP COLUMN year;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Те же правила, что у API. NOT VALID: старые строки не проверяются, пока их не
+-- почистят и не выполнят VALIDATE CONSTRAINT, новые и изменённые проверяются сразу.
+ALTER TABLE movies
+ADD CONSTRAINT movies_title_check CHECK (btrim(title) <> '') NOT VALID,
+    ADD CONSTRAINT movies_year_check CHECK (
+        year BETWEEN 1888 AND 2100
+    ) NOT VALID;
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE movies DROP CONSTRAINT movies_title_check,
+    DROP CONSTRAINT movies_year_check;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Фильм определяется названием и годом. Если в базе уже есть дубликаты, миграция
+-- упадёт: их нужно свести вручную и повторить.
+CREATE UNIQUE INDEX movies_title_year_key ON movies(lower(title), year);
+-- +goose StatementEnd
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX movies_title_year_key;
+-- +goose StatementEnd
@@ -0,0 +1,8 @@
+// Package migrations SQL миграции goose, встроенные в бинарник.
+// Применяются командой migrate или при старте с db.auto_migrate.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS